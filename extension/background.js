@@ -1,3 +1,5 @@
+const jobPollInterval = 2000;
+
 chrome.contextMenus.create({
     title: "Download YouTube video to server",
     contexts: ["video"],
@@ -22,24 +24,84 @@ chrome.contextMenus.onClicked.addListener((info, tab) => {
 });
 
 function downloadVideo(videoUrl, type) {
-    chrome.storage.sync.get(['bestQuality', 'selectedQuality'], function(data) {
-        let quality = 'best';
-
-        if (!data.bestQuality && data.selectedQuality) {
-            quality = data.selectedQuality;
-        }
-
-        fetch('http://localhost:8080/extension/download-to-server', {
-            method: 'POST',
-            mode: 'no-cors',
-            headers: { 'Content-Type': 'application/json' },
-            body: JSON.stringify({ video_url: videoUrl, type: type, quality: quality }),
-        })
-            .then(response => {
-                console.log('Request was sent');
+    chrome.storage.sync.get(['bestQuality', 'selectedQuality', 'serverUrl', 'folderId'], function(data) {
+        chrome.storage.local.get(['apiToken'], function(local) {
+            let quality = 'best';
+
+            if (!data.bestQuality && data.selectedQuality) {
+                quality = data.selectedQuality;
+            }
+
+            if (!data.serverUrl || !data.folderId || !local.apiToken) {
+                setStatus('error', 'Set the server URL, API token and folder ID in the extension settings');
+                return;
+            }
+
+            apiRequest(data.serverUrl, local.apiToken, '/videos/download-to-server', {
+                method: 'POST',
+                body: JSON.stringify({ video_url: videoUrl, type: type, quality: quality, folder_id: data.folderId }),
             })
-            .catch(error => {
-                console.error('Error saving video:', error);
-            });
+                .then(job => {
+                    setStatus('queued', 'Download queued', job.id);
+                    pollJob(data.serverUrl, local.apiToken, job.id);
+                })
+                .catch(error => {
+                    console.error('Error saving video:', error);
+                    setStatus('error', error.message);
+                });
+        });
     });
+}
+
+function pollJob(serverUrl, apiToken, jobId) {
+    apiRequest(serverUrl, apiToken, '/jobs/?job_id=' + encodeURIComponent(jobId), { method: 'GET' })
+        .then(job => {
+            switch (job.status) {
+                case 'done':
+                    setStatus('done', 'Video saved', job.id);
+                    break;
+                case 'failed':
+                case 'canceled':
+                    setStatus('error', job.error || 'Download ' + job.status, job.id);
+                    break;
+                default:
+                    setStatus(job.status, 'Download ' + job.status, job.id);
+                    setTimeout(() => pollJob(serverUrl, apiToken, jobId), jobPollInterval);
+            }
+        })
+        .catch(error => {
+            console.error('Error getting job:', error);
+            setStatus('error', error.message, jobId);
+        });
+}
+
+// apiRequest calls the server with the API token and returns the parsed body,
+// or rejects with the message of the error body.
+function apiRequest(serverUrl, apiToken, path, options) {
+    return fetch(serverUrl.replace(/\/+$/, '') + path, {
+        ...options,
+        headers: {
+            'Content-Type': 'application/json',
+            'Authorization': 'Bearer ' + apiToken,
+        },
+    })
+        .then(response => response.json()
+            .catch(() => null)
+            .then(body => {
+                if (!response.ok) {
+                    const message = body && (body.message || body.error);
+                    throw new Error(message || 'Server responded with ' + response.status);
+                }
+
+                return body;
+            }));
+}
+
+// setStatus remembers the last download for the popup and shows it on the badge.
+function setStatus(status, message, jobId) {
+    chrome.storage.local.set({ lastDownload: { status: status, message: message, jobId: jobId || null } });
+
+    const badges = { queued: '...', running: '...', done: 'OK', error: '!' };
+    chrome.action.setBadgeText({ text: badges[status] || '' });
+    chrome.action.setBadgeBackgroundColor({ color: status === 'error' ? '#d93025' : '#1a73e8' });
 }
\ No newline at end of file
@@ -2,6 +2,10 @@ document.addEventListener('DOMContentLoaded', function() {
     const bestQualityCheckbox = document.getElementById('bestQualityCheckbox');
     const qualitySelect = document.getElementById('qualitySelect');
     const saveButton = document.getElementById('saveSettings');
+    const serverUrlInput = document.getElementById('serverUrlInput');
+    const apiTokenInput = document.getElementById('apiTokenInput');
+    const folderIdInput = document.getElementById('folderIdInput');
+    const lastDownload = document.getElementById('lastDownload');
 
     bestQualityCheckbox.disabled = false;
     chrome.storage.sync.get(['bestQuality', 'selectedQuality'], function(data) {
@@ -18,6 +22,21 @@ document.addEventListener('DOMContentLoaded', function() {
         }
     });
 
+    chrome.storage.sync.get(['serverUrl', 'folderId'], function(data) {
+        serverUrlInput.value = data.serverUrl || 'http://localhost:8080';
+        folderIdInput.value = data.folderId || '';
+    });
+
+    // the token belongs to this install only, so it is not synced
+    chrome.storage.local.get(['apiToken', 'lastDownload'], function(data) {
+        apiTokenInput.value = data.apiToken || '';
+
+        if (data.lastDownload) {
+            lastDownload.textContent = 'Last download: ' + data.lastDownload.message;
+            lastDownload.className = data.lastDownload.status;
+        }
+    });
+
     bestQualityCheckbox.addEventListener('change', function() {
         qualitySelect.disabled = !!bestQualityCheckbox.checked;
     });
@@ -26,9 +45,12 @@ document.addEventListener('DOMContentLoaded', function() {
         const bestQuality = bestQualityCheckbox.checked;
         const selectedQuality = qualitySelect.value;
 
+        chrome.storage.local.set({ apiToken: apiTokenInput.value.trim() });
         chrome.storage.sync.set({
             bestQuality: bestQuality,
-            selectedQuality: selectedQuality
+            selectedQuality: selectedQuality,
+            serverUrl: serverUrlInput.value.trim(),
+            folderId: folderIdInput.value.trim()
         }, function() {
             alert('Settings saved!');
         });
@@ -1,7 +1,15 @@
 package main
 
-import "video-downloader-server/internal/app"
+import (
+	"os"
+	"video-downloader-server/internal/app"
+)
 
 func main() {
-	app.Run()
+	if len(os.Args) > 1 && os.Args[1] == "fsck" {
+		app.RunFsck(os.Args[2:])
+		return
+	}
+
+	app.Run(os.Args[1:])
 }
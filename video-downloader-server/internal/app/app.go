@@ -2,69 +2,274 @@ package app
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
+	"errors"
+	"flag"
 	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 	"video-downloader-server/internal/config"
+	"video-downloader-server/internal/delivery/handlers/admin_handler"
+	"video-downloader-server/internal/delivery/handlers/auth_handler"
 	"video-downloader-server/internal/delivery/handlers/folders_handler"
+	"video-downloader-server/internal/delivery/handlers/health_handler"
+	"video-downloader-server/internal/delivery/handlers/jobs_handler"
 	"video-downloader-server/internal/delivery/handlers/videos_handler"
-	"video-downloader-server/internal/repository"
+	"video-downloader-server/internal/delivery/middleware"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/logger"
+	"video-downloader-server/internal/metrics"
+	"video-downloader-server/internal/service/auth_service"
+	"video-downloader-server/internal/service/chapters_service"
 	"video-downloader-server/internal/service/folders_service"
+	"video-downloader-server/internal/service/fsck_service"
+	"video-downloader-server/internal/service/gc_service"
+	"video-downloader-server/internal/service/health_service"
+	"video-downloader-server/internal/service/hls_service"
+	"video-downloader-server/internal/service/intents_service"
+	"video-downloader-server/internal/service/jobs_service"
 	"video-downloader-server/internal/service/preview_service"
+	"video-downloader-server/internal/service/share_links_service"
+	"video-downloader-server/internal/service/sharing_service"
+	"video-downloader-server/internal/service/storyboard_service"
+	"video-downloader-server/internal/service/subtitles_service"
+	"video-downloader-server/internal/service/transcode_service"
 	"video-downloader-server/internal/service/videos_service"
+	"video-downloader-server/internal/tracing"
 	"video-downloader-server/internal/validator"
 )
 
 const (
 	errLoadingConfig    = "error loading config"
-	errCreatingDbClient = "error creating mongo db client"
-	errConnectingToDb   = "error connecting to mongo db"
+	errRecoveringIntent = "error recovering unfinished intents"
+	errCheckingFsck     = "error checking data consistency"
+	errPrintingConfig   = "error printing config"
+	errServing          = "error serving http"
+	errShuttingDown     = "error shutting down http server gracefully"
+	errDrainingJobs     = "error draining jobs, unfinished jobs have been canceled"
+	errSettingUpTracing = "error setting up tracing"
+	errBootstrapping    = "error creating the initial admin account"
+	errFlushingSpans    = "error flushing spans"
 
-	successfulConfigLoad     = "config has been loaded successfully"
-	successfulConnectionToDb = "successfully connected to MongoDB"
-	serverStart              = "server starting on port"
+	successfulConfigLoad = "config has been loaded successfully"
+	serverStart          = "server starting on port"
+	shutdownStart        = "shutting down, waiting for in-flight requests and jobs"
+	shutdownComplete     = "server has been shut down"
 )
 
-func Run() {
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		log.WithError(err).Fatal(errLoadingConfig)
+const tracingFlushTimeout = 5 * time.Second
+
+// corsRoutes lists the methods each route group accepts from the extension
+// and the web frontend. Health, debug and metrics endpoints are same-origin only.
+var corsRoutes = middleware.CorsRoutes{
+	"/videos":  {http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodDelete},
+	"/folders": {http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+	"/jobs":    {http.MethodGet},
+	"/auth":    {http.MethodGet, http.MethodPost, http.MethodDelete},
+	"/admin":   {http.MethodGet, http.MethodPost},
+}
+
+// Run starts the server and blocks until SIGINT or SIGTERM. In-flight requests
+// and running jobs then get the configured grace period to finish before they
+// are canceled. With -print-config it prints the effective config with
+// secrets redacted and exits instead.
+func Run(args []string) {
+	fs := flag.NewFlagSet("video-downloader-server", flag.ExitOnError)
+	printConfig := fs.Bool("print-config", false, "print the effective config with secrets redacted and exit")
+
+	cfg := loadConfig(fs, args)
+
+	if *printConfig {
+		if err := cfg.WriteYAML(os.Stdout); err != nil {
+			log.WithError(err).Fatal(errPrintingConfig)
+		}
+		return
 	}
-	log.Info(successfulConfigLoad)
 
-	serverAPI := options.ServerAPI(options.ServerAPIVersion1)
-	opts := options.Client().ApplyURI(fmt.Sprintf("mongodb+srv://%s:%s@cluster0.vs9z4.mongodb.net/?retryWrites=true&w=majority&appName=Cluster0", cfg.DbUser, cfg.DbPassword)).SetServerAPIOptions(serverAPI)
-	client, err := mongo.Connect(context.TODO(), opts)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing.Exporter, cfg.Tracing.Endpoint, cfg.Tracing.Insecure, cfg.Tracing.SampleRatio)
 	if err != nil {
-		log.WithError(err).Fatal(errCreatingDbClient)
+		log.WithError(err).Fatal(errSettingUpTracing)
 	}
-	defer client.Disconnect(context.TODO())
 
-	err = client.Ping(context.TODO(), nil)
-	if err != nil {
-		log.WithError(err).Fatal(errConnectingToDb)
+	store := openStorage(cfg)
+	defer store.close()
+
+	videosRepo, foldersRepo, intentsRepo := store.videos, store.folders, store.intents
+
+	videoDir, previewDir, hlsDir := cfg.Storage.VideoDir, cfg.Storage.PreviewDir, cfg.Storage.HlsDir
+
+	authService := auth_service.NewAuthService(store.users, store.sessions, store.apiTokens, videosRepo, foldersRepo, cfg.Auth.SessionTTL, cfg.Auth.BcryptCost, cfg.Auth.AllowSignup)
+	if err := authService.Bootstrap(context.Background(), cfg.Auth.AdminUsername, cfg.Auth.AdminPassword); err != nil {
+		log.WithError(err).Fatal(errBootstrapping)
 	}
-	log.Info(successfulConnectionToDb)
 
-	db := client.Database(cfg.DbName)
-	videosRepo := repository.NewVideosRepo(db)
-	foldersRepo := repository.NewFoldersRepo(db)
+	intentsService := intents_service.NewIntentsService(intentsRepo, videosRepo, foldersRepo, videoDir, previewDir, hlsDir)
+	if err := intentsService.Recover(context.Background()); err != nil {
+		log.WithError(err).Error(errRecoveringIntent)
+	}
 
-	previewService := preview_service.NewPreviewService()
-	videosService := videos_service.NewVideosService(videosRepo, previewService)
-	folderService := folders_service.NewFoldersService(foldersRepo, videosService)
+	previewService := preview_service.NewPreviewService(videoDir, previewDir, cfg.Ffmpeg.FfmpegPath, cfg.Ffmpeg.FfprobePath, cfg.Preview.MinTimeFraction, cfg.Preview.MaxTimeFraction, cfg.Preview.Candidates)
+	jobsService := jobs_service.NewJobsService(cfg.Downloads.Workers, cfg.Downloads.QueueSize, cfg.Downloads.MaxPerUser, cfg.Downloads.JobRetention)
+	jobsService.Start()
+	transcodeJobsService := jobs_service.NewJobsService(cfg.Transcode.Workers, cfg.Transcode.QueueSize, cfg.Transcode.MaxPerUser, cfg.Transcode.JobRetention)
+	transcodeJobsService.Start()
+	transcodeService := transcode_service.NewTranscodeService(videoDir, cfg.Ffmpeg.FfmpegPath, cfg.Ffmpeg.FfprobePath)
+	chaptersService := chapters_service.NewChaptersService(videoDir, cfg.Ffmpeg.FfmpegPath, cfg.Ffmpeg.FfprobePath)
+	sharingService := sharing_service.NewSharingService(foldersRepo, store.folderMembers, store.users)
+	videosService := videos_service.NewVideosService(videosRepo, foldersRepo, sharingService, previewService, intentsService, jobsService, transcodeService, transcodeJobsService, chaptersService, cfg.TranscodeProfiles(), cfg.Videos.ConflictPolicy, cfg.Videos.MaxNameSuffix, videoDir, cfg.Ffmpeg.FfmpegPath)
+	shareLinksService := share_links_service.NewShareLinksService(store.shareLinks, videosRepo, videosService, cfg.Auth.BcryptCost)
+	hlsService := hls_service.NewHlsService(videosService, transcodeJobsService, videoDir, hlsDir, cfg.Ffmpeg.FfmpegPath, cfg.Ffmpeg.FfprobePath, cfg.HlsRenditions(), cfg.Hls.SegmentDuration)
+	subtitlesService := subtitles_service.NewSubtitlesService(videosService, videosRepo, videoDir)
+	storyboardService := storyboard_service.NewStoryboardService(videosService, transcodeJobsService, videoDir, previewDir, cfg.Ffmpeg.FfmpegPath, cfg.Ffmpeg.FfprobePath, cfg.Preview.StoryboardInterval, cfg.Preview.StoryboardMaxTiles, cfg.Preview.StoryboardTileWidth)
+	folderService := folders_service.NewFoldersService(foldersRepo, videosService, sharingService)
+	fsckService := fsck_service.NewFsckService(videosRepo, foldersRepo, videoDir, previewDir)
+	gcService := gc_service.NewGcService(videosRepo, videoDir, previewDir, hlsDir, cfg.Gc.Interval, cfg.Gc.OrphanMinAge, cfg.Gc.TmpMinAge, cfg.Gc.DryRun)
+	gcService.Start(ctx)
 
 	v := validator.Init()
-	videosHandler := videos_handler.NewVideosHandler(videosService, v)
-	foldersHandler := folders_handler.NewFoldersHandler(folderService, v)
+	videosHandler := videos_handler.NewVideosHandler(videosService, shareLinksService, hlsService, subtitlesService, storyboardService, v, cfg.Downloads.RetryAfter, cfg.Auth.CookieSecure)
+	foldersHandler := folders_handler.NewFoldersHandler(folderService, sharingService, v)
+	adminHandler := admin_handler.NewAdminHandler(fsckService, gcService, authService, v)
+	jobsHandler := jobs_handler.NewJobsHandler(jobsService, transcodeJobsService)
+	authHandler := auth_handler.NewAuthHandler(authService, v, cfg.Auth.CookieSecure)
+
+	healthService := health_service.NewHealthService(store, jobsService, transcodeJobsService, []string{videoDir, previewDir, hlsDir}, cfg.Health.MinFreeBytes, []string{cfg.Ffmpeg.FfmpegPath, cfg.Ffmpeg.FfprobePath}, cfg.Health.CheckTimeout, cfg.Fields())
+	healthHandler := health_handler.NewHealthHandler(healthService)
+
+	metrics.RegisterLibrary(videosService.LibraryStats, cfg.Metrics.LibraryStatsTTL, cfg.Metrics.LibraryStatsTimeout)
+
+	rateLimiter := middleware.NewRateLimiter(cfg.RateLimit.Requests, cfg.RateLimit.Window)
+	rateLimit := middleware.RateLimit(rateLimiter, cfg.RateLimit.TrustProxy)
 
 	r := chi.NewRouter()
-	videosHandler.RegisterRoutes(r)
-	foldersHandler.RegisterRoutes(r)
+	r.Use(middleware.Tracing)
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Metrics)
+	r.Use(middleware.Cors(cfg.CorsOrigins(), cfg.Cors.AllowCredentials, cfg.Cors.MaxAge, corsRoutes))
+	healthHandler.RegisterRoutes(r)
+	r.Group(func(r chi.Router) {
+		r.Use(rateLimit)
+		authHandler.RegisterRoutes(r)
+	})
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.RateLimitShareLinks(rateLimiter, cfg.RateLimit.TrustProxy))
+		videosHandler.RegisterPublicRoutes(r)
+	})
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Authenticate(authService), rateLimit)
+		videosHandler.RegisterRoutes(r)
+		foldersHandler.RegisterRoutes(r)
+		jobsHandler.RegisterRoutes(r)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireRole(domain.RoleAdmin), middleware.RequireScope(domain.ScopeManage))
+			adminHandler.RegisterRoutes(r)
+			healthHandler.RegisterAdminRoutes(r)
+			r.Handle("/metrics", promhttp.Handler())
+		})
+	})
+
+	srv := &http.Server{Addr: ":" + cfg.Server.Port, Handler: r}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Infof(serverStart+" %s", cfg.Server.Port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+	}()
+
+	failed := false
+	select {
+	case <-ctx.Done():
+	case err := <-serveErr:
+		log.WithError(err).Error(errServing)
+		failed = true
+	}
+	stop()
+
+	log.Info(shutdownStart)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, pool := range []*jobs_service.JobsService{jobsService, transcodeJobsService} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := pool.Shutdown(shutdownCtx); err != nil {
+				log.WithError(err).Warn(errDrainingJobs)
+			}
+		}()
+	}
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.WithError(err).Warn(errShuttingDown)
+		srv.Close()
+	}
+	wg.Wait()
+
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), tracingFlushTimeout)
+	if err := shutdownTracing(flushCtx); err != nil {
+		log.WithError(err).Warn(errFlushingSpans)
+	}
+	flushCancel()
+
+	log.Info(shutdownComplete)
+
+	if failed {
+		store.close()
+		os.Exit(1)
+	}
+}
+
+// RunFsck checks the database against the storage directories, prints the
+// report as JSON to stdout and exits with a non-zero code on failure.
+func RunFsck(args []string) {
+	fs := flag.NewFlagSet("fsck", flag.ExitOnError)
+	repair := fs.Bool("repair", false, "delete dangling documents and orphaned files, reattach orphaned folders to the root")
+
+	cfg := loadConfig(fs, args)
+
+	store := openStorage(cfg)
+	defer store.close()
+
+	fsckService := fsck_service.NewFsckService(store.videos, store.folders, cfg.Storage.VideoDir, cfg.Storage.PreviewDir)
+
+	report, err := fsckService.Check(context.Background(), *repair)
+	if err != nil {
+		log.WithError(err).Error(errCheckingFsck)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+
+	if err != nil {
+		store.close()
+		os.Exit(1)
+	}
+}
+
+func loadConfig(fs *flag.FlagSet, args []string) *config.Config {
+	cfg, err := config.LoadConfig(fs, args)
+	if err != nil {
+		log.WithError(err).Fatal(errLoadingConfig)
+	}
+
+	level, _ := log.ParseLevel(cfg.Log.Level)
+	logger.Setup(cfg.Log.Format, level)
+
+	log.WithFields(cfg.Fields()).Info(successfulConfigLoad)
 
-	log.Infof(serverStart+" %s", cfg.Port)
-	log.Fatal(http.ListenAndServe(":"+cfg.Port, r))
+	return cfg
 }
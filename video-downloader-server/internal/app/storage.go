@@ -0,0 +1,174 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	"os"
+	"time"
+	"video-downloader-server/internal/config"
+	"video-downloader-server/internal/migrations"
+	"video-downloader-server/internal/repository"
+)
+
+const (
+	errCreatingDbClient = "error creating mongo db client"
+	errConnectingToDb   = "error connecting to mongo db"
+	errRunningMigration = "error running mongo db migrations"
+	errOpeningBoltDb    = "error opening embedded db"
+	errParsingCAFile    = "no certificates found in CA file"
+
+	successfulConnectionToDb = "successfully connected to MongoDB"
+	successfulMigration      = "mongo db migrations are up to date"
+	successfulBoltDbOpen     = "embedded db has been opened"
+	memoryStorageWarning     = "using in-memory storage, all data will be lost on exit"
+
+	maxDbRetryBackoff = 30 * time.Second
+)
+
+type storage struct {
+	videos        repository.Videos
+	folders       repository.Folders
+	intents       repository.Intents
+	users         repository.Users
+	sessions      repository.Sessions
+	apiTokens     repository.APITokens
+	folderMembers repository.FolderMembers
+	shareLinks    repository.ShareLinks
+	ping          func(ctx context.Context) error
+	close         func()
+}
+
+func (s storage) Ping(ctx context.Context) error {
+	return s.ping(ctx)
+}
+
+func openStorage(cfg *config.Config) storage {
+	switch cfg.Storage.Backend {
+	case config.BoltBackend:
+		db, err := repository.OpenBolt(cfg.Storage.BoltPath)
+		if err != nil {
+			log.WithError(err).Fatal(errOpeningBoltDb)
+		}
+		log.Info(successfulBoltDbOpen + ": " + cfg.Storage.BoltPath)
+
+		return storage{
+			videos:        repository.NewVideosBoltRepo(db),
+			folders:       repository.NewFoldersBoltRepo(db),
+			intents:       repository.NewIntentsBoltRepo(db),
+			users:         repository.NewUsersBoltRepo(db),
+			sessions:      repository.NewSessionsBoltRepo(db),
+			apiTokens:     repository.NewAPITokensBoltRepo(db),
+			folderMembers: repository.NewFolderMembersBoltRepo(db),
+			shareLinks:    repository.NewShareLinksBoltRepo(db),
+			ping:          func(ctx context.Context) error { return db.View(func(tx *bbolt.Tx) error { return nil }) },
+			close:         func() { db.Close() },
+		}
+	case config.MemoryBackend:
+		log.Warn(memoryStorageWarning)
+
+		return storage{
+			videos:        repository.NewVideosMemoryRepo(),
+			folders:       repository.NewFoldersMemoryRepo(),
+			intents:       repository.NewIntentsMemoryRepo(),
+			users:         repository.NewUsersMemoryRepo(),
+			sessions:      repository.NewSessionsMemoryRepo(),
+			apiTokens:     repository.NewAPITokensMemoryRepo(),
+			folderMembers: repository.NewFolderMembersMemoryRepo(),
+			shareLinks:    repository.NewShareLinksMemoryRepo(),
+			ping:          func(ctx context.Context) error { return nil },
+			close:         func() {},
+		}
+	default:
+		client, db := connectToDb(cfg)
+
+		if err := migrations.Run(context.Background(), db, cfg.Mongo.OpTimeout, cfg.Mongo.MigrationTimeout); err != nil {
+			log.WithError(err).Fatal(errRunningMigration)
+		}
+		log.Info(successfulMigration)
+
+		return storage{
+			videos:        repository.NewVideosMongoRepo(db, cfg.Mongo.OpTimeout),
+			folders:       repository.NewFoldersMongoRepo(db, cfg.Mongo.OpTimeout),
+			intents:       repository.NewIntentsMongoRepo(db, cfg.Mongo.OpTimeout),
+			users:         repository.NewUsersMongoRepo(db, cfg.Mongo.OpTimeout),
+			sessions:      repository.NewSessionsMongoRepo(db, cfg.Mongo.OpTimeout),
+			apiTokens:     repository.NewAPITokensMongoRepo(db, cfg.Mongo.OpTimeout),
+			folderMembers: repository.NewFolderMembersMongoRepo(db, cfg.Mongo.OpTimeout),
+			shareLinks:    repository.NewShareLinksMongoRepo(db, cfg.Mongo.OpTimeout),
+			ping:          func(ctx context.Context) error { return client.Ping(ctx, nil) },
+			close:         func() { client.Disconnect(context.TODO()) },
+		}
+	}
+}
+
+func connectToDb(cfg *config.Config) (*mongo.Client, *mongo.Database) {
+	opts := options.Client().
+		ApplyURI(cfg.Mongo.URI).
+		SetServerAPIOptions(options.ServerAPI(options.ServerAPIVersion1)).
+		SetConnectTimeout(cfg.Mongo.ConnectTimeout).
+		SetServerSelectionTimeout(cfg.Mongo.ServerSelectionTimeout).
+		SetMinPoolSize(cfg.Mongo.MinPoolSize).
+		SetMaxPoolSize(cfg.Mongo.MaxPoolSize).
+		SetMonitor(otelmongo.NewMonitor())
+
+	if cfg.Mongo.TLS {
+		tlsConfig, err := newDbTLSConfig(cfg)
+		if err != nil {
+			log.WithError(err).Fatal(errCreatingDbClient)
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	client, err := mongo.Connect(context.TODO(), opts)
+	if err != nil {
+		log.WithError(err).Fatal(errCreatingDbClient)
+	}
+
+	backoff := cfg.Mongo.RetryBackoff
+	for attempt := uint64(0); ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Mongo.ConnectTimeout)
+		err = client.Ping(ctx, nil)
+		cancel()
+
+		if err == nil {
+			break
+		}
+
+		if attempt >= cfg.Mongo.ConnectRetries {
+			client.Disconnect(context.TODO())
+			log.WithError(err).Fatal(errConnectingToDb)
+		}
+
+		log.WithError(err).WithField("retry_in", backoff.String()).Warn(errConnectingToDb)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxDbRetryBackoff)
+	}
+	log.Info(successfulConnectionToDb)
+
+	return client, client.Database(cfg.Mongo.Name)
+}
+
+func newDbTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.Mongo.TLSInsecure}
+
+	if cfg.Mongo.TLSCAFile != "" {
+		caCert, err := os.ReadFile(cfg.Mongo.TLSCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, errors.New(errParsingCAFile + ": " + cfg.Mongo.TLSCAFile)
+		}
+	}
+
+	return tlsConfig, nil
+}
@@ -2,64 +2,465 @@ package config
 
 import (
 	"errors"
-	"github.com/joho/godotenv"
-	"os"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/logger"
+	"video-downloader-server/internal/tracing"
 )
 
 const (
 	errParamNotDefined = "parameter is not defined"
+	errParamInvalid    = "parameter has invalid value"
 )
 
+const (
+	MongoBackend  = "mongo"
+	BoltBackend   = "bolt"
+	MemoryBackend = "memory"
+)
+
+// Config is loaded in layers: field defaults, then the config file, then the
+// environment and finally command line flags, each layer overriding the
+// previous one. Every leaf field is described by its tags:
+//
+//	key     - name in the config file, nested under the parent keys
+//	env     - environment variable
+//	default - value used when no layer sets the field
+//	secret  - the value is redacted when the config is printed or logged
+//
+// The flag name is the dotted key path with dashes, e.g. -mongo.op-timeout.
 type Config struct {
-	Port         string
-	ExtensionURL string
-	DbName       string
-	DbUser       string
-	DbPassword   string
+	Server    ServerConfig    `key:"server"`
+	Storage   StorageConfig   `key:"storage"`
+	Mongo     MongoConfig     `key:"mongo"`
+	Ffmpeg    FfmpegConfig    `key:"ffmpeg"`
+	Videos    VideosConfig    `key:"videos"`
+	Downloads DownloadsConfig `key:"downloads"`
+	RateLimit RateLimitConfig `key:"rate_limit"`
+	Preview   PreviewConfig   `key:"preview"`
+	Hls       HlsConfig       `key:"hls"`
+	Transcode TranscodeConfig `key:"transcode"`
+	Gc        GcConfig        `key:"gc"`
+	Health    HealthConfig    `key:"health"`
+	Metrics   MetricsConfig   `key:"metrics"`
+	Cors      CorsConfig      `key:"cors"`
+	Log       LogConfig       `key:"log"`
+	Tracing   TracingConfig   `key:"tracing"`
+	Auth      AuthConfig      `key:"auth"`
+}
+
+type ServerConfig struct {
+	Port            string        `key:"port" env:"PORT" usage:"HTTP port to listen on"`
+	ExtensionURL    string        `key:"extension_url" env:"EXTENSION_URL" usage:"origin of the browser extension, e.g. chrome-extension://<id>, allowed to call the API"`
+	ShutdownTimeout time.Duration `key:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" default:"30s" usage:"grace period for in-flight requests and jobs on shutdown"`
 }
 
-func LoadConfig() (*Config, error) {
-	err := godotenv.Load(".env")
+type StorageConfig struct {
+	Backend    string `key:"backend" env:"STORAGE_BACKEND" default:"mongo" usage:"storage backend: mongo, bolt or memory"`
+	BoltPath   string `key:"bolt_path" env:"BOLT_PATH" default:"video-downloader.db" usage:"path to the embedded db file"`
+	VideoDir   string `key:"video_dir" env:"VIDEO_DIR" default:"videos" usage:"root directory of video files"`
+	PreviewDir string `key:"preview_dir" env:"PREVIEW_DIR" default:"previews" usage:"root directory of preview images"`
+	HlsDir     string `key:"hls_dir" env:"HLS_DIR" default:"hls" usage:"root directory of generated HLS playlists and segments"`
+}
+
+type MongoConfig struct {
+	URI      string `key:"uri" env:"DB_URI" secret:"true" usage:"connection string, built from user, password and host when empty"`
+	Host     string `key:"host" env:"DB_HOST" default:"cluster0.vs9z4.mongodb.net" usage:"atlas cluster host"`
+	Name     string `key:"name" env:"DB_NAME" usage:"database name"`
+	User     string `key:"user" env:"DB_USER" usage:"database user"`
+	Password string `key:"password" env:"DB_PASSWORD" secret:"true" usage:"database password"`
+
+	TLS                    bool          `key:"tls" env:"DB_TLS" usage:"connect over TLS"`
+	TLSCAFile              string        `key:"tls_ca_file" env:"DB_TLS_CA_FILE" usage:"PEM file with the CA certificates"`
+	TLSInsecure            bool          `key:"tls_insecure" env:"DB_TLS_INSECURE" usage:"skip server certificate verification"`
+	MinPoolSize            uint64        `key:"min_pool_size" env:"DB_MIN_POOL_SIZE" default:"0" usage:"minimum number of pooled connections"`
+	MaxPoolSize            uint64        `key:"max_pool_size" env:"DB_MAX_POOL_SIZE" default:"100" usage:"maximum number of pooled connections"`
+	ConnectTimeout         time.Duration `key:"connect_timeout" env:"DB_CONNECT_TIMEOUT" default:"10s" usage:"timeout of a single connection attempt"`
+	ServerSelectionTimeout time.Duration `key:"server_selection_timeout" env:"DB_SERVER_SELECTION_TIMEOUT" default:"10s" usage:"timeout of selecting a server for an operation"`
+	OpTimeout              time.Duration `key:"op_timeout" env:"DB_OP_TIMEOUT" default:"5s" usage:"deadline of a single repository operation"`
+	MigrationTimeout       time.Duration `key:"migration_timeout" env:"DB_MIGRATION_TIMEOUT" default:"10m" usage:"deadline of a single startup migration, which may build indexes"`
+	ConnectRetries         uint64        `key:"connect_retries" env:"DB_CONNECT_RETRIES" default:"5" usage:"connection attempts at startup"`
+	RetryBackoff           time.Duration `key:"retry_backoff" env:"DB_RETRY_BACKOFF" default:"1s" usage:"initial delay between connection attempts"`
+}
+
+type FfmpegConfig struct {
+	FfmpegPath  string `key:"ffmpeg_path" env:"FFMPEG_PATH" default:"ffmpeg" usage:"ffmpeg executable"`
+	FfprobePath string `key:"ffprobe_path" env:"FFPROBE_PATH" default:"ffprobe" usage:"ffprobe executable"`
+}
+
+type VideosConfig struct {
+	ConflictPolicy string `key:"conflict_policy" env:"VIDEO_CONFLICT_POLICY" default:"error" usage:"on name conflict: error, suffix or overwrite"`
+	MaxNameSuffix  int    `key:"max_name_suffix" env:"VIDEO_MAX_NAME_SUFFIX" default:"1000" usage:"largest suffix tried by the suffix conflict policy"`
+}
+
+type DownloadsConfig struct {
+	Workers      int           `key:"workers" env:"DOWNLOAD_WORKERS" default:"2" usage:"number of concurrent download jobs"`
+	QueueSize    int           `key:"queue_size" env:"DOWNLOAD_QUEUE_SIZE" default:"16" usage:"number of download jobs waiting for a worker"`
+	JobRetention time.Duration `key:"job_retention" env:"DOWNLOAD_JOB_RETENTION" default:"1h" usage:"how long finished jobs can be queried"`
+	MaxPerUser   int           `key:"max_per_user" env:"DOWNLOAD_MAX_PER_USER" default:"4" usage:"number of queued and running download jobs a user may have, 0 removes the limit"`
+	RetryAfter   time.Duration `key:"retry_after" env:"DOWNLOAD_RETRY_AFTER" default:"30s" usage:"Retry-After sent when a download is rejected because the queue or the user's limit is full"`
+}
+
+type RateLimitConfig struct {
+	Requests   int           `key:"requests" env:"RATE_LIMIT_REQUESTS" default:"60" usage:"POST, PUT and DELETE requests, and requests to a share link, a client may make per window, 0 disables rate limiting"`
+	Window     time.Duration `key:"window" env:"RATE_LIMIT_WINDOW" default:"1m" usage:"window the request allowance refills over"`
+	TrustProxy bool          `key:"trust_proxy" env:"RATE_LIMIT_TRUST_PROXY" usage:"key anonymous clients by X-Forwarded-For, only safe behind a reverse proxy that sets it"`
+}
 
-	if err != nil {
-		return nil, err
+type PreviewConfig struct {
+	MinTimeFraction     float64       `key:"min_time_fraction" env:"PREVIEW_MIN_TIME_FRACTION" default:"0.1" usage:"earliest preview frame as a share of the duration"`
+	MaxTimeFraction     float64       `key:"max_time_fraction" env:"PREVIEW_MAX_TIME_FRACTION" default:"0.8" usage:"width of the window preview frames are sampled from as a share of the duration"`
+	Candidates          int           `key:"candidates" env:"PREVIEW_CANDIDATES" default:"5" usage:"number of frames sampled for the preview, the most detailed one that is neither dark nor blank is kept"`
+	StoryboardInterval  time.Duration `key:"storyboard_interval" env:"PREVIEW_STORYBOARD_INTERVAL" default:"10s" usage:"time between two thumbnails of a storyboard"`
+	StoryboardMaxTiles  int           `key:"storyboard_max_tiles" env:"PREVIEW_STORYBOARD_MAX_TILES" default:"100" usage:"maximum number of thumbnails of a storyboard, the interval is stretched for longer videos"`
+	StoryboardTileWidth int           `key:"storyboard_tile_width" env:"PREVIEW_STORYBOARD_TILE_WIDTH" default:"160" usage:"width of a storyboard thumbnail in pixels"`
+}
+
+type HlsConfig struct {
+	Renditions      []string      `key:"renditions" env:"HLS_RENDITIONS" default:"1080p,720p,480p,360p" usage:"comma separated HLS ladder from 2160p, 1440p, 1080p, 720p, 480p, 360p and 240p, renditions taller than the source are skipped"`
+	SegmentDuration time.Duration `key:"segment_duration" env:"HLS_SEGMENT_DURATION" default:"6s" usage:"target duration of an HLS segment"`
+}
+
+type TranscodeConfig struct {
+	Profiles  []string `key:"profiles" env:"TRANSCODE_PROFILES" default:"h264-720p,h264-1080p,h265-1080p,webm-480p" usage:"comma separated transcode profiles named <codec>-<height>p, codec h264, h265 or webm"`
+	Workers   int      `key:"workers" env:"TRANSCODE_WORKERS" default:"1" usage:"number of concurrent transcode, split, HLS and storyboard jobs"`
+	QueueSize int      `key:"queue_size" env:"TRANSCODE_QUEUE_SIZE" default:"8" usage:"number of transcode, split, HLS and storyboard jobs waiting for a worker"`
+
+	JobRetention time.Duration `key:"job_retention" env:"TRANSCODE_JOB_RETENTION" default:"1h" usage:"how long finished transcode jobs can be queried"`
+	MaxPerUser   int           `key:"max_per_user" env:"TRANSCODE_MAX_PER_USER" default:"4" usage:"number of queued and running transcode jobs a user may have, 0 removes the limit"`
+}
+
+type GcConfig struct {
+	Interval     time.Duration `key:"interval" env:"GC_INTERVAL" default:"1h" usage:"garbage collection interval, 0 disables it"`
+	OrphanMinAge time.Duration `key:"orphan_min_age" env:"GC_ORPHAN_MIN_AGE" default:"1h" usage:"minimum age of an orphaned file before it is collected"`
+	TmpMinAge    time.Duration `key:"tmp_min_age" env:"GC_TMP_MIN_AGE" default:"24h" usage:"minimum age of a tmp download file before it is collected"`
+	DryRun       bool          `key:"dry_run" env:"GC_DRY_RUN" usage:"only report collectable files"`
+}
+
+type HealthConfig struct {
+	MinFreeBytes uint64        `key:"min_free_bytes" env:"HEALTH_MIN_FREE_BYTES" default:"1073741824" usage:"free space required on the storage roots to be ready"`
+	CheckTimeout time.Duration `key:"check_timeout" env:"HEALTH_CHECK_TIMEOUT" default:"2s" usage:"deadline of the readiness checks"`
+}
+
+type MetricsConfig struct {
+	LibraryStatsTTL     time.Duration `key:"library_stats_ttl" env:"METRICS_LIBRARY_STATS_TTL" default:"1m" usage:"how long the library size and video count are cached between scrapes"`
+	LibraryStatsTimeout time.Duration `key:"library_stats_timeout" env:"METRICS_LIBRARY_STATS_TIMEOUT" default:"10s" usage:"deadline of collecting the library size and video count"`
+}
+
+type CorsConfig struct {
+	AllowedOrigins   []string      `key:"allowed_origins" env:"CORS_ALLOWED_ORIGINS" usage:"comma separated origins allowed to call the API besides the extension, e.g. the web frontend, may contain one * wildcard"`
+	AllowCredentials bool          `key:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS" default:"true" usage:"let allowed origins send the session cookie"`
+	MaxAge           time.Duration `key:"max_age" env:"CORS_MAX_AGE" default:"10m" usage:"how long browsers may cache a preflight response"`
+}
+
+type LogConfig struct {
+	Format string `key:"format" env:"LOG_FORMAT" default:"text" usage:"log output format: text or json"`
+	Level  string `key:"level" env:"LOG_LEVEL" default:"info" usage:"minimum log level: trace, debug, info, warn or error"`
+}
+
+type TracingConfig struct {
+	Exporter    string  `key:"exporter" env:"TRACING_EXPORTER" default:"none" usage:"span exporter: none, stdout or otlp"`
+	Endpoint    string  `key:"endpoint" env:"TRACING_ENDPOINT" usage:"OTLP/HTTP traces endpoint URL, the OTEL_EXPORTER_OTLP_* defaults when empty"`
+	Insecure    bool    `key:"insecure" env:"TRACING_INSECURE" usage:"export spans over plain HTTP"`
+	SampleRatio float64 `key:"sample_ratio" env:"TRACING_SAMPLE_RATIO" default:"1" usage:"share of new traces that are sampled"`
+}
+
+type AuthConfig struct {
+	SessionTTL    time.Duration `key:"session_ttl" env:"AUTH_SESSION_TTL" default:"720h" usage:"how long a login session stays valid"`
+	AllowSignup   bool          `key:"allow_signup" env:"AUTH_ALLOW_SIGNUP" usage:"let anyone register an account"`
+	AdminUsername string        `key:"admin_username" env:"AUTH_ADMIN_USERNAME" default:"admin" usage:"admin account created when there are no users yet"`
+	AdminPassword string        `key:"admin_password" env:"AUTH_ADMIN_PASSWORD" secret:"true" usage:"password of the initial admin account, no account is created when empty"`
+	BcryptCost    int           `key:"bcrypt_cost" env:"AUTH_BCRYPT_COST" default:"10" usage:"bcrypt cost of password hashes"`
+	CookieSecure  bool          `key:"cookie_secure" env:"AUTH_COOKIE_SECURE" usage:"send the session and share link view cookies over HTTPS only"`
+}
+
+// resolve fills the fields that are derived from other fields.
+func (c *Config) resolve() {
+	if c.Mongo.URI == "" && c.Mongo.User != "" && c.Mongo.Password != "" {
+		c.Mongo.URI = fmt.Sprintf("mongodb+srv://%s:%s@%s/?retryWrites=true&w=majority", url.QueryEscape(c.Mongo.User), url.QueryEscape(c.Mongo.Password), c.Mongo.Host)
+	}
+}
+
+// CorsOrigins returns the origins allowed to call the API, the extension's
+// included.
+func (c *Config) CorsOrigins() []string {
+	origins := make([]string, 0, len(c.Cors.AllowedOrigins)+1)
+	for _, origin := range c.Cors.AllowedOrigins {
+		origins = append(origins, strings.TrimSuffix(origin, "/"))
+	}
+
+	if c.Server.ExtensionURL != "" {
+		origins = append(origins, strings.TrimSuffix(c.Server.ExtensionURL, "/"))
+	}
+
+	return origins
+}
+
+// HlsRenditions returns the configured renditions from the highest to the
+// lowest. Unknown and repeated names are left out.
+func (c *Config) HlsRenditions() []domain.HlsRendition {
+	var renditions []domain.HlsRendition
+
+	for _, rendition := range domain.HlsRenditions {
+		if slices.Contains(c.Hls.Renditions, rendition.Name) {
+			renditions = append(renditions, rendition)
+		}
+	}
+
+	return renditions
+}
+
+// TranscodeProfiles returns the configured profiles by name. Invalid names
+// are left out.
+func (c *Config) TranscodeProfiles() map[string]domain.TranscodeProfile {
+	profiles := make(map[string]domain.TranscodeProfile, len(c.Transcode.Profiles))
+
+	for _, name := range c.Transcode.Profiles {
+		if profile, err := domain.ParseTranscodeProfile(name); err == nil {
+			profiles[profile.Name] = profile
+		}
 	}
 
-	port := os.Getenv("PORT")
+	return profiles
+}
+
+// validate reports every invalid parameter at once.
+func (c *Config) validate() error {
+	var errs []error
 
-	if port == "" {
-		return nil, errors.New("PORT " + errParamNotDefined)
+	notDefined := func(key string) {
+		errs = append(errs, errors.New(key+" "+errParamNotDefined))
+	}
+	invalid := func(key string) {
+		errs = append(errs, errors.New(key+" "+errParamInvalid))
 	}
 
-	extensionURL := os.Getenv("EXTENSION_URL")
+	if c.Server.Port == "" {
+		notDefined("server.port")
+	} else if port, err := strconv.ParseUint(c.Server.Port, 10, 16); err != nil || port == 0 {
+		invalid("server.port")
+	}
 
-	if extensionURL == "" {
-		return nil, errors.New("EXTENSION_URL " + errParamNotDefined)
+	if c.Server.ExtensionURL != "" && !validOrigin(c.Server.ExtensionURL) {
+		invalid("server.extension_url")
 	}
 
-	dbName := os.Getenv("DB_NAME")
+	switch c.Storage.Backend {
+	case MongoBackend:
+		if c.Mongo.Name == "" {
+			notDefined("mongo.name")
+		}
 
-	if dbName == "" {
-		return nil, errors.New("DB_NAME " + errParamNotDefined)
+		if c.Mongo.URI == "" {
+			if c.Mongo.User == "" {
+				notDefined("mongo.user")
+			}
+
+			if c.Mongo.Password == "" {
+				notDefined("mongo.password")
+			}
+		}
+
+		if c.Mongo.MaxPoolSize != 0 && c.Mongo.MinPoolSize > c.Mongo.MaxPoolSize {
+			invalid("mongo.min_pool_size")
+		}
+
+		if c.Mongo.MigrationTimeout <= 0 {
+			invalid("mongo.migration_timeout")
+		}
+	case BoltBackend:
+		if c.Storage.BoltPath == "" {
+			notDefined("storage.bolt_path")
+		}
+	case MemoryBackend:
+	default:
+		invalid("storage.backend")
+	}
+
+	if c.Storage.VideoDir == "" {
+		notDefined("storage.video_dir")
 	}
 
-	dbUser := os.Getenv("DB_USER")
+	if c.Storage.PreviewDir == "" {
+		notDefined("storage.preview_dir")
+	} else if c.Storage.PreviewDir == c.Storage.VideoDir {
+		invalid("storage.preview_dir")
+	}
 
-	if dbUser == "" {
-		return nil, errors.New("DB_USER " + errParamNotDefined)
+	if c.Storage.HlsDir == "" {
+		notDefined("storage.hls_dir")
+	} else if c.Storage.HlsDir == c.Storage.VideoDir || c.Storage.HlsDir == c.Storage.PreviewDir {
+		invalid("storage.hls_dir")
 	}
 
-	dbPassword := os.Getenv("DB_PASSWORD")
+	if c.Ffmpeg.FfmpegPath == "" {
+		notDefined("ffmpeg.ffmpeg_path")
+	}
 
-	if dbPassword == "" {
-		return nil, errors.New("DB_PASSWORD " + errParamNotDefined)
+	if c.Ffmpeg.FfprobePath == "" {
+		notDefined("ffmpeg.ffprobe_path")
 	}
 
-	return &Config{
-		Port:         port,
-		ExtensionURL: extensionURL,
-		DbName:       dbName,
-		DbUser:       dbUser,
-		DbPassword:   dbPassword,
-	}, nil
+	switch c.Videos.ConflictPolicy {
+	case domain.ConflictPolicyError, domain.ConflictPolicySuffix, domain.ConflictPolicyOverwrite:
+	default:
+		invalid("videos.conflict_policy")
+	}
+
+	if c.Videos.MaxNameSuffix < 2 {
+		invalid("videos.max_name_suffix")
+	}
+
+	if c.Downloads.Workers < 1 {
+		invalid("downloads.workers")
+	}
+
+	if c.Downloads.QueueSize < 0 {
+		invalid("downloads.queue_size")
+	}
+
+	if c.Downloads.MaxPerUser < 0 {
+		invalid("downloads.max_per_user")
+	}
+
+	if c.Downloads.RetryAfter < time.Second {
+		invalid("downloads.retry_after")
+	}
+
+	if c.RateLimit.Requests < 0 {
+		invalid("rate_limit.requests")
+	}
+
+	if c.RateLimit.Window <= 0 {
+		invalid("rate_limit.window")
+	}
+
+	if c.Preview.MinTimeFraction < 0 || c.Preview.MinTimeFraction >= 1 {
+		invalid("preview.min_time_fraction")
+	}
+
+	if c.Preview.MaxTimeFraction < 0 || c.Preview.MinTimeFraction+c.Preview.MaxTimeFraction > 1 {
+		invalid("preview.max_time_fraction")
+	}
+
+	if c.Preview.Candidates < 1 {
+		invalid("preview.candidates")
+	}
+
+	if c.Preview.StoryboardInterval < time.Second {
+		invalid("preview.storyboard_interval")
+	}
+
+	if c.Preview.StoryboardMaxTiles < 1 {
+		invalid("preview.storyboard_max_tiles")
+	}
+
+	if c.Preview.StoryboardTileWidth < 16 || c.Preview.StoryboardTileWidth%2 != 0 {
+		invalid("preview.storyboard_tile_width")
+	}
+
+	if len(c.Hls.Renditions) == 0 || len(c.HlsRenditions()) != len(c.Hls.Renditions) {
+		invalid("hls.renditions")
+	}
+
+	if c.Hls.SegmentDuration < time.Second {
+		invalid("hls.segment_duration")
+	}
+
+	for _, name := range c.Transcode.Profiles {
+		if _, err := domain.ParseTranscodeProfile(name); err != nil {
+			invalid("transcode.profiles")
+			break
+		}
+	}
+
+	if c.Transcode.Workers < 1 {
+		invalid("transcode.workers")
+	}
+
+	if c.Transcode.QueueSize < 0 {
+		invalid("transcode.queue_size")
+	}
+
+	if c.Transcode.JobRetention <= 0 {
+		invalid("transcode.job_retention")
+	}
+
+	if c.Transcode.MaxPerUser < 0 {
+		invalid("transcode.max_per_user")
+	}
+
+	for _, origin := range c.Cors.AllowedOrigins {
+		if origin == "*" {
+			// browsers refuse credentials from a wildcard origin
+			if c.Cors.AllowCredentials {
+				invalid("cors.allow_credentials")
+			}
+			continue
+		}
+
+		if !validOrigin(origin) {
+			invalid("cors.allowed_origins")
+			break
+		}
+	}
+
+	if c.Cors.MaxAge < 0 {
+		invalid("cors.max_age")
+	}
+
+	switch c.Log.Format {
+	case logger.TextFormat, logger.JSONFormat:
+	default:
+		invalid("log.format")
+	}
+
+	if _, err := log.ParseLevel(c.Log.Level); err != nil {
+		invalid("log.level")
+	}
+
+	switch c.Tracing.Exporter {
+	case tracing.NoneExporter, tracing.StdoutExporter, tracing.OTLPExporter:
+	default:
+		invalid("tracing.exporter")
+	}
+
+	if c.Tracing.Endpoint != "" {
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			invalid("tracing.endpoint")
+		}
+	}
+
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		invalid("tracing.sample_ratio")
+	}
+
+	if c.Auth.SessionTTL <= 0 {
+		invalid("auth.session_ttl")
+	}
+
+	if c.Auth.BcryptCost < bcrypt.MinCost || c.Auth.BcryptCost > bcrypt.MaxCost {
+		invalid("auth.bcrypt_cost")
+	}
+
+	if len(c.Auth.AdminPassword) > domain.MaxPasswordLength {
+		invalid("auth.admin_password")
+	}
+
+	return errors.Join(errs...)
+}
+
+// validOrigin reports whether origin is a scheme and a host without a path,
+// e.g. https://example.com or chrome-extension://<id>. A trailing slash is
+// tolerated.
+func validOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && u.Scheme != "" && u.Host != "" && (u.Path == "" || u.Path == "/") && u.RawQuery == ""
 }
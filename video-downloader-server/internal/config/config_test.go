@@ -0,0 +1,148 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// load runs LoadConfig on a fresh flag set with the memory backend, so that
+// no database parameters are needed, and env set on top.
+func load(t *testing.T, env map[string]string, args ...string) (*Config, error) {
+	t.Helper()
+
+	t.Setenv("PORT", "8080")
+	t.Setenv("STORAGE_BACKEND", MemoryBackend)
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+
+	return LoadConfig(flagSet, args)
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadConfigLayers(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+downloads:
+  workers: 3
+  queue_size: 5
+rate_limit:
+  window: 2m
+`)
+
+	cfg, err := load(t, map[string]string{"DOWNLOAD_QUEUE_SIZE": "7", "DOWNLOAD_WORKERS": "8"}, "-config", path, "-downloads.workers", "9")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"default", cfg.Downloads.MaxPerUser, 4},
+		{"file over default", cfg.RateLimit.Window, 2 * time.Minute},
+		{"env over file", cfg.Downloads.QueueSize, 7},
+		{"flag over env and file", cfg.Downloads.Workers, 9},
+		{"env only", cfg.Storage.Backend, MemoryBackend},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"config.yaml", "downloads:\n  workers: 3\n"},
+		{"config.yml", "downloads:\n  workers: 3\n"},
+		{"config.toml", "[downloads]\nworkers = 3\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := load(t, map[string]string{configFileEnv: writeConfigFile(t, tt.name, tt.content)})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.Downloads.Workers != 3 {
+				t.Errorf("got %d workers, want 3", cfg.Downloads.Workers)
+			}
+		})
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   map[string]string
+		file  string
+		args  []string
+		wants []string
+	}{
+		{"valid", nil, "", nil, nil},
+		{"invalid port", map[string]string{"PORT": "0"}, "", nil, []string{"server.port " + errParamInvalid}},
+		{"unparsable env", map[string]string{"DOWNLOAD_WORKERS": "many"}, "", nil, []string{"DOWNLOAD_WORKERS " + errParamInvalid}},
+		{"unparsable flag", nil, "", []string{"-rate-limit.window", "soon"}, []string{"-rate-limit.window " + errParamInvalid}},
+		{"unknown file key", nil, "downloads:\n  speed: 3\n", nil, []string{"downloads.speed " + errUnknownParam}},
+		{"unknown backend", map[string]string{"STORAGE_BACKEND": "paper"}, "", nil, []string{"storage.backend " + errParamInvalid}},
+		{"mongo without a database", map[string]string{"STORAGE_BACKEND": MongoBackend, "DB_URI": "mongodb://localhost"}, "", nil, []string{"mongo.name " + errParamNotDefined}},
+		{"no migration timeout", map[string]string{"STORAGE_BACKEND": MongoBackend, "DB_URI": "mongodb://localhost", "DB_NAME": "videos", "DB_MIGRATION_TIMEOUT": "0s"}, "", nil, []string{"mongo.migration_timeout " + errParamInvalid}},
+		{"same directories", map[string]string{"VIDEO_DIR": "data", "PREVIEW_DIR": "data"}, "", nil, []string{"storage.preview_dir " + errParamInvalid}},
+		{"negative transcode limit", map[string]string{"TRANSCODE_MAX_PER_USER": "-1"}, "", nil, []string{"transcode.max_per_user " + errParamInvalid}},
+		{"no transcode job retention", map[string]string{"TRANSCODE_JOB_RETENTION": "0s"}, "", nil, []string{"transcode.job_retention " + errParamInvalid}},
+		{"every invalid parameter at once", map[string]string{"DOWNLOAD_WORKERS": "0", "RATE_LIMIT_WINDOW": "0s"}, "", nil, []string{"downloads.workers " + errParamInvalid, "rate_limit.window " + errParamInvalid}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeConfigFile(t, "config.yaml", tt.file)}, args...)
+			}
+
+			_, err := load(t, tt.env, args...)
+			if len(tt.wants) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			for _, want := range tt.wants {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	configFileFlag = "config"
+	configFileEnv  = "CONFIG_FILE"
+
+	errReadingConfigFile = "error reading config file"
+	errUnknownConfigFile = "unknown config file format, expected .yaml, .yml or .toml"
+	errUnknownParam      = "unknown parameter"
+
+	redacted = "******"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// field is a leaf of the Config tree.
+type field struct {
+	key    string
+	env    string
+	def    string
+	usage  string
+	secret bool
+	value  reflect.Value
+}
+
+func (f field) flagName() string {
+	return strings.ReplaceAll(f.key, "_", "-")
+}
+
+// flagValue remembers the raw flag value, it is applied only after the file
+// and environment layers.
+type flagValue struct {
+	raw    string
+	isBool bool
+}
+
+func (v *flagValue) String() string { return v.raw }
+
+func (v *flagValue) Set(raw string) error {
+	v.raw = raw
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool { return v.isBool }
+
+// LoadConfig registers a flag for every parameter plus -config on flagSet,
+// parses args and builds the config from the defaults, the config file, the
+// environment and the flags. A .env file in the working directory is loaded
+// into the environment when it exists.
+func LoadConfig(flagSet *flag.FlagSet, args []string) (*Config, error) {
+	cfg := &Config{}
+	fields := collectFields(reflect.ValueOf(cfg).Elem(), "")
+
+	configFile := flagSet.String(configFileFlag, "", "path to a YAML or TOML config file (env "+configFileEnv+")")
+
+	flags := make(map[string]*flagValue, len(fields))
+	byFlag := make(map[string]field, len(fields))
+	for _, f := range fields {
+		value := &flagValue{isBool: f.value.Kind() == reflect.Bool}
+		flags[f.flagName()] = value
+		byFlag[f.flagName()] = f
+		flagSet.Var(value, f.flagName(), fmt.Sprintf("%s (env %s)", f.usage, f.env))
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := godotenv.Load(".env"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	for _, f := range fields {
+		if f.def == "" {
+			continue
+		}
+
+		if err := setValue(f.value, f.def); err != nil {
+			return nil, fmt.Errorf("%s: %w", f.key, err)
+		}
+	}
+
+	path := *configFile
+	if path == "" {
+		path = os.Getenv(configFileEnv)
+	}
+
+	if path != "" {
+		fileValues, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		byKey := make(map[string]field, len(fields))
+		for _, f := range fields {
+			byKey[f.key] = f
+		}
+
+		for key, raw := range fileValues {
+			f, ok := byKey[key]
+			if !ok {
+				return nil, fmt.Errorf("%s: %s %s", path, key, errUnknownParam)
+			}
+
+			if err := setValue(f.value, raw); err != nil {
+				return nil, fmt.Errorf("%s: %s %s", path, key, errParamInvalid)
+			}
+		}
+	}
+
+	for _, f := range fields {
+		raw := os.Getenv(f.env)
+		if raw == "" {
+			continue
+		}
+
+		if err := setValue(f.value, raw); err != nil {
+			return nil, errors.New(f.env + " " + errParamInvalid)
+		}
+	}
+
+	var flagErr error
+	flagSet.Visit(func(fl *flag.Flag) {
+		f, ok := byFlag[fl.Name]
+		if !ok || flagErr != nil {
+			return
+		}
+
+		if err := setValue(f.value, flags[fl.Name].raw); err != nil {
+			flagErr = errors.New("-" + fl.Name + " " + errParamInvalid)
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	cfg.resolve()
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Fields returns every parameter keyed by its dotted path with secrets
+// redacted, for logging.
+func (c *Config) Fields() map[string]interface{} {
+	res := make(map[string]interface{})
+
+	for _, f := range collectFields(reflect.ValueOf(c).Elem(), "") {
+		res[f.key] = printable(f)
+	}
+
+	return res
+}
+
+// WriteYAML writes the effective config in the config file format with
+// secrets redacted.
+func (c *Config) WriteYAML(w io.Writer) error {
+	tree := make(map[string]interface{})
+
+	for _, f := range collectFields(reflect.ValueOf(c).Elem(), "") {
+		node := tree
+		parts := strings.Split(f.key, ".")
+
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node[part].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				node[part] = child
+			}
+			node = child
+		}
+
+		node[parts[len(parts)-1]] = printable(f)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	defer encoder.Close()
+
+	return encoder.Encode(tree)
+}
+
+func collectFields(v reflect.Value, prefix string) []field {
+	var res []field
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		key := prefix + structField.Tag.Get("key")
+
+		if structField.Type.Kind() == reflect.Struct && structField.Type != durationType {
+			res = append(res, collectFields(v.Field(i), key+".")...)
+			continue
+		}
+
+		res = append(res, field{
+			key:    key,
+			env:    structField.Tag.Get("env"),
+			def:    structField.Tag.Get("default"),
+			usage:  structField.Tag.Get("usage"),
+			secret: structField.Tag.Get("secret") == "true",
+			value:  v.Field(i),
+		})
+	}
+
+	return res
+}
+
+func setValue(v reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+
+	switch {
+	case v.Type() == durationType:
+		duration, err := time.ParseDuration(raw)
+		if err != nil || duration < 0 {
+			return errors.New(errParamInvalid)
+		}
+		v.SetInt(int64(duration))
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Kind() == reflect.Bool:
+		res, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(res)
+	case v.Kind() == reflect.Int:
+		res, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(res)
+	case v.Kind() == reflect.Uint64:
+		res, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetUint(res)
+	case v.Kind() == reflect.Float64:
+		res, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(res)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var res []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				res = append(res, item)
+			}
+		}
+		v.Set(reflect.ValueOf(res))
+	default:
+		return fmt.Errorf("unsupported parameter type %s", v.Type())
+	}
+
+	return nil
+}
+
+func printable(f field) interface{} {
+	if f.secret {
+		if f.value.IsZero() {
+			return ""
+		}
+		return redacted
+	}
+
+	if f.value.Type() == durationType {
+		return f.value.Interface().(time.Duration).String()
+	}
+
+	return f.value.Interface()
+}
+
+// readConfigFile decodes a YAML or TOML file into raw values keyed by their
+// dotted path.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s (path: %s): %s", errReadingConfigFile, path, err)
+	}
+
+	tree := make(map[string]interface{})
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("%s (path: %s)", errUnknownConfigFile, path)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s (path: %s): %s", errReadingConfigFile, path, err)
+	}
+
+	res := make(map[string]string)
+	flatten(tree, "", res)
+
+	return res, nil
+}
+
+func flatten(tree map[string]interface{}, prefix string, res map[string]string) {
+	for key, value := range tree {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			flatten(value, prefix+key+".", res)
+		case []interface{}:
+			items := make([]string, 0, len(value))
+			for _, item := range value {
+				items = append(items, fmt.Sprint(item))
+			}
+			res[prefix+key] = strings.Join(items, ",")
+		case nil:
+		default:
+			res[prefix+key] = fmt.Sprint(value)
+		}
+	}
+}
@@ -8,54 +8,115 @@ type JsonError struct {
 type ContextKey string
 
 const (
-	DownloadVideoInputKey ContextKey = "downloadVideoInput"
-	RenameVideoInputKey   ContextKey = "renameVideoInput"
-	MoveVideoInputKey     ContextKey = "modeVideoInput"
-	DeleteVideoInputKey   ContextKey = "deleteVideoInput"
-	CreateFolderInputKey  ContextKey = "createFolderInput"
-	RenameFolderInputKey  ContextKey = "renameFolderInput"
-	MoveFolderInputKey    ContextKey = "moveFolderInput"
-	DeleteFolderInputKey  ContextKey = "deleteFolderInput"
-	VideoIDInputKey       ContextKey = "videoIDInput"
-	FolderIDInputKey      ContextKey = "folderIDInput"
+	DownloadVideoInputKey   ContextKey = "downloadVideoInput"
+	RenameVideoInputKey     ContextKey = "renameVideoInput"
+	MoveVideoInputKey       ContextKey = "modeVideoInput"
+	DeleteVideoInputKey     ContextKey = "deleteVideoInput"
+	CopyVideoInputKey       ContextKey = "copyVideoInput"
+	CreateFolderInputKey    ContextKey = "createFolderInput"
+	RenameFolderInputKey    ContextKey = "renameFolderInput"
+	MoveFolderInputKey      ContextKey = "moveFolderInput"
+	DeleteFolderInputKey    ContextKey = "deleteFolderInput"
+	VideoIDInputKey         ContextKey = "videoIDInput"
+	FolderIDInputKey        ContextKey = "folderIDInput"
+	JobIDInputKey           ContextKey = "jobIDInput"
+	RegisterInputKey        ContextKey = "registerInput"
+	LoginInputKey           ContextKey = "loginInput"
+	CreateUserInputKey      ContextKey = "createUserInput"
+	CreateTokenInputKey     ContextKey = "createTokenInput"
+	TokenIDInputKey         ContextKey = "tokenIDInput"
+	GrantAccessInputKey     ContextKey = "grantAccessInput"
+	RevokeAccessInputKey    ContextKey = "revokeAccessInput"
+	CreateShareLinkInputKey ContextKey = "createShareLinkInput"
+	ShareLinkIDInputKey     ContextKey = "shareLinkIDInput"
+	TranscodeVideoInputKey  ContextKey = "transcodeVideoInput"
+	UploadSubtitleInputKey  ContextKey = "uploadSubtitleInput"
+	SplitVideoInputKey      ContextKey = "splitVideoInput"
+	UserKey                 ContextKey = "user"
+	ScopesKey               ContextKey = "scopes"
 )
 
 const (
-	ErrInvalidDownloadVideoInput = "invalid download video input body"
-	MesInvalidDownloadVideoInput = "fields video_url, type and folder_id are required and can't be empty, field video_url must be url format, field type can be 'general' or 'youtube', field quality can be empty or one of 2160p 1440p 1080p 720p 480p 360p 240p 144p best, field folder_id must be object id"
-	ErrInvalidRenameVideoInput   = "invalid rename video input body"
-	MesInvalidRenameVideoInput   = "fields id and video_name are required and can't be empty, id must be valid object id, video_name must be valid name"
-	ErrInvalidMoveVideoInput     = "invalid move video input body"
-	MesInvalidMoveVideoInput     = "fields id and folder_id are required, can't be empty and must be valid object id"
-	ErrInvalidDeleteVideoInput   = "invalid delete video input body"
-	MesInvalidDeleteVideoInput   = "field id are required, can't be empty and must be valid object id"
-	ErrInvalidCreateFolderInput  = "invalid create folder input body"
-	MesInvalidCreateFolderInput  = "field folder_name is required, can't be empty and must be valid name, field parent_dir_id must be valid object id"
-	ErrInvalidRenameFolderInput  = "invalid rename folder input body"
-	MesInvalidRenameFolderInput  = "fields id and folder_name are required and can't be empty, id must be valid object id, folder_name must be valid name"
-	ErrInvalidMoveFolderInput    = "invalid move folder input body"
-	MesInvalidMoveFolderInput    = "fields id and parent_dir_id are required, can't be empty and must be valid object id"
-	ErrInvalidDeleteFolderInput  = "invalid delete folder input body"
-	MesInvalidDeleteFolderInput  = "field id are required, can't be empty and must be valid object id"
-	ErrInvalidVideoIDInput       = "invalid video id input"
-	MesInvalidVideoIDInput       = "video id param must be valid object id"
-	ErrInvalidFolderIDInput      = "invalid folder id input"
-	MesInvalidFolderIDInput      = "folder_id param must be valid object id"
-	ErrEmptyIDParam              = "empty id param"
-	MesInvalidJSON               = "invalid JSON body"
+	ErrInvalidDownloadVideoInput   = "invalid download video input body"
+	MesInvalidDownloadVideoInput   = "fields video_url, type and folder_id are required and can't be empty, field video_url must be url format, field type can be 'general' or 'youtube', field quality can be empty or one of 2160p 1440p 1080p 720p 480p 360p 240p 144p best, field folder_id must be object id, field transcode_profile can be empty or the name of a configured profile, field subtitles can be empty or up to 16 distinct language tags such as 'en' or 'pt-BR', downloaded for youtube videos only"
+	ErrInvalidRenameVideoInput     = "invalid rename video input body"
+	MesInvalidRenameVideoInput     = "fields id and video_name are required and can't be empty, id must be valid object id, video_name must be valid name"
+	ErrInvalidMoveVideoInput       = "invalid move video input body"
+	MesInvalidMoveVideoInput       = "fields id and folder_id are required, can't be empty and must be valid object id"
+	ErrInvalidDeleteVideoInput     = "invalid delete video input body"
+	MesInvalidDeleteVideoInput     = "field id are required, can't be empty and must be valid object id"
+	ErrInvalidCopyVideoInput       = "invalid copy video input body"
+	MesInvalidCopyVideoInput       = "fields id and folder_id are required, can't be empty and must be valid object id"
+	ErrInvalidCreateFolderInput    = "invalid create folder input body"
+	MesInvalidCreateFolderInput    = "field folder_name is required, can't be empty and must be valid name, field parent_dir_id must be valid object id"
+	ErrInvalidRenameFolderInput    = "invalid rename folder input body"
+	MesInvalidRenameFolderInput    = "fields id and folder_name are required and can't be empty, id must be valid object id, folder_name must be valid name"
+	ErrInvalidMoveFolderInput      = "invalid move folder input body"
+	MesInvalidMoveFolderInput      = "fields id and parent_dir_id are required, can't be empty and must be valid object id"
+	ErrInvalidDeleteFolderInput    = "invalid delete folder input body"
+	MesInvalidDeleteFolderInput    = "field id are required, can't be empty and must be valid object id"
+	ErrInvalidVideoIDInput         = "invalid video id input"
+	MesInvalidVideoIDInput         = "video id param must be valid object id"
+	ErrInvalidFolderIDInput        = "invalid folder id input"
+	MesInvalidFolderIDInput        = "folder_id param must be valid object id"
+	ErrInvalidJobIDInput           = "invalid job id input"
+	MesInvalidJobIDInput           = "job_id param must be valid object id"
+	ErrInvalidRegisterInput        = "invalid register input body"
+	MesInvalidRegisterInput        = "fields username and password are required, username must be 3 to 32 letters or digits, password must be 8 to 72 characters"
+	ErrInvalidLoginInput           = "invalid login input body"
+	MesInvalidLoginInput           = "fields username and password are required and can't be empty"
+	ErrInvalidCreateUserInput      = "invalid create user input body"
+	MesInvalidCreateUserInput      = "fields username, password and role are required, username must be 3 to 32 letters or digits, password must be 8 to 72 characters, role can be 'user' or 'admin'"
+	ErrInvalidCreateTokenInput     = "invalid create token input body"
+	MesInvalidCreateTokenInput     = "fields name and scopes are required, name must be 1 to 64 characters, scopes must be a non-empty list of distinct values from 'download', 'read' and 'manage'"
+	ErrInvalidTokenIDInput         = "invalid token id input"
+	MesInvalidTokenIDInput         = "token_id param must be valid object id"
+	ErrInvalidGrantAccessInput     = "invalid grant access input body"
+	MesInvalidGrantAccessInput     = "fields folder_id, username and role are required, folder_id must be valid object id, role can be 'viewer', 'contributor' or 'owner'"
+	ErrInvalidRevokeAccessInput    = "invalid revoke access input body"
+	MesInvalidRevokeAccessInput    = "fields folder_id and user_id are required, can't be empty and must be valid object id"
+	ErrInvalidCreateShareLinkInput = "invalid create share link input body"
+	MesInvalidCreateShareLinkInput = "fields are optional, expires_in must be 60 to 31536000 seconds, max_views must be positive, password must be 4 to 72 characters"
+	ErrInvalidShareLinkIDInput     = "invalid share link id input"
+	MesInvalidShareLinkIDInput     = "link_id param must be valid object id"
+	ErrInvalidTranscodeVideoInput  = "invalid transcode video input body"
+	MesInvalidTranscodeVideoInput  = "field profile is required and must be the name of a configured profile, such as 'h264-720p'"
+	ErrInvalidUploadSubtitleInput  = "invalid upload subtitle input"
+	MesInvalidUploadSubtitleInput  = "fields lang and file are required, lang must be a language tag such as 'en' or 'pt-BR', label must be at most 64 characters"
+	MesInvalidMultipartForm        = "invalid multipart form body"
+	ErrInvalidSplitVideoInput      = "invalid split video input body"
+	MesInvalidSplitVideoInput      = "field folder_name is required, can't be empty and must be valid name"
+	MesSubtitleTooLarge            = "subtitle file must be at most 5 MiB"
+	ErrEmptyIDParam                = "empty id param"
+	MesInvalidJSON                 = "invalid JSON body"
 )
 
 const (
 	ErrGettingID                = "error getting videoID from VideoURL"
 	ErrDownloadingVideoToServer = "error downloading video to server"
-	SuccessfulLoad              = "successful video load"
+	SuccessfulLoadQueued        = "video download has been queued"
 
-	ErrGettingVideoRange          = "error getting video range info"
+	ErrStreamingVideo             = "error streaming video"
 	ErrGettingVideo               = "error getting video"
 	ErrDownloadingVideoFromServer = "error downloading video from server"
 	ErrRenamingVideo              = "error renaming video"
 	ErrMovingVideo                = "error moving video"
 	ErrDeletingVideo              = "error deleting video"
+	ErrCopyingVideo               = "error copying video"
+	ErrCreatingShareLink          = "error creating share link"
+	ErrGettingShareLinks          = "error getting share links"
+	ErrRevokingShareLink          = "error revoking share link"
+	ErrOpeningShareLink           = "error opening share link"
+	ErrGeneratingHls              = "error generating hls renditions"
+	ErrGettingHlsFile             = "error getting hls file"
+	ErrTranscodingVideo           = "error transcoding video"
+	ErrUploadingSubtitle          = "error uploading subtitle"
+	ErrGettingSubtitle            = "error getting subtitle"
+	ErrExtractingChapters         = "error extracting chapters"
+	ErrSplittingVideo             = "error splitting video"
+	ErrGettingPreview             = "error getting preview"
+	ErrGeneratingStoryboard       = "error generating storyboard"
+	ErrGettingStoryboardFile      = "error getting storyboard file"
 )
 
 const (
@@ -64,4 +125,42 @@ const (
 	ErrMovingFolder   = "error moving folder"
 	ErrDeletingFolder = "error deleting folder"
 	ErrGettingFolder  = "error getting folder content"
+	ErrGrantingAccess = "error granting folder access"
+	ErrRevokingAccess = "error revoking folder access"
+	ErrGettingMembers = "error getting folder members"
+	ErrGettingShared  = "error getting shared folders"
+)
+
+const (
+	ErrCheckingConsistency  = "error checking data consistency"
+	ErrRepairingConsistency = "error repairing data consistency"
+	ErrCollectingGarbage    = "error collecting orphaned files"
+)
+
+const (
+	ErrGettingJob = "error getting job"
+)
+
+const (
+	ErrRegistering    = "error registering user"
+	ErrLoggingIn      = "error logging in"
+	ErrLoggingOut     = "error logging out"
+	ErrCreatingUser   = "error creating user"
+	ErrAuthenticating = "error authenticating request"
+	ErrAuthorizing    = "error authorizing request"
+	ErrCreatingToken  = "error creating api token"
+	ErrGettingTokens  = "error getting api tokens"
+	ErrRevokingToken  = "error revoking api token"
+)
+
+const (
+	ErrNotReady = "server is not ready"
+)
+
+const (
+	ErrRateLimiting = "error rate limiting request"
+)
+
+const (
+	RequestCompleted = "request completed"
 )
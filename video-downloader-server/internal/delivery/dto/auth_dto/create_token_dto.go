@@ -0,0 +1,6 @@
+package auth_dto
+
+type CreateTokenDto struct {
+	Name   string   `json:"name" validate:"required,min=1,max=64"`
+	Scopes []string `json:"scopes" validate:"required,min=1,unique,dive,oneof=download read manage"`
+}
@@ -0,0 +1,7 @@
+package auth_dto
+
+type CreateUserDto struct {
+	Username string `json:"username" validate:"required,alphanum,min=3,max=32"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+	Role     string `json:"role" validate:"required,oneof=user admin"`
+}
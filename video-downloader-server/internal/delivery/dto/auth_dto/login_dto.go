@@ -0,0 +1,6 @@
+package auth_dto
+
+type LoginDto struct {
+	Username string `json:"username" validate:"required,max=32"`
+	Password string `json:"password" validate:"required,max=72"`
+}
@@ -0,0 +1,6 @@
+package auth_dto
+
+type RegisterDto struct {
+	Username string `json:"username" validate:"required,alphanum,min=3,max=32"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
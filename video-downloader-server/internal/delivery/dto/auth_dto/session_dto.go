@@ -0,0 +1,9 @@
+package auth_dto
+
+import "time"
+
+type SessionDto struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      UserDto   `json:"user"`
+}
@@ -0,0 +1,17 @@
+package auth_dto
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// TokenDto describes an API token. Token is only set in the response that
+// creates it, the plaintext is not stored and can't be shown again.
+type TokenDto struct {
+	ID         primitive.ObjectID `json:"id"`
+	Name       string             `json:"name"`
+	Scopes     []string           `json:"scopes"`
+	CreatedAt  time.Time          `json:"created_at"`
+	LastUsedAt *time.Time         `json:"last_used_at"`
+	Token      string             `json:"token,omitempty"`
+}
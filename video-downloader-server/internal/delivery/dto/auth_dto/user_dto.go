@@ -0,0 +1,13 @@
+package auth_dto
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+type UserDto struct {
+	ID        primitive.ObjectID `json:"id"`
+	Username  string             `json:"username"`
+	Role      string             `json:"role"`
+	CreatedAt time.Time          `json:"created_at"`
+}
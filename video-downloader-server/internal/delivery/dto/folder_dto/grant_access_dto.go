@@ -0,0 +1,9 @@
+package folder_dto
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type GrantAccessDto struct {
+	FolderID primitive.ObjectID `json:"folder_id" validate:"required,objectid"`
+	Username string             `json:"username" validate:"required,max=32"`
+	Role     string             `json:"role" validate:"required,oneof=viewer contributor owner"`
+}
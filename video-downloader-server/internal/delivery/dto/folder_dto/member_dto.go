@@ -0,0 +1,12 @@
+package folder_dto
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// MemberDto is a user with access to a folder. GrantedOn is the folder the
+// role was granted on, the folder itself or one of its parents.
+type MemberDto struct {
+	UserID    primitive.ObjectID `json:"user_id"`
+	Username  string             `json:"username"`
+	Role      string             `json:"role"`
+	GrantedOn primitive.ObjectID `json:"granted_on"`
+}
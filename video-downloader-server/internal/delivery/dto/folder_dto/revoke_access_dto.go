@@ -0,0 +1,8 @@
+package folder_dto
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type RevokeAccessDto struct {
+	FolderID primitive.ObjectID `json:"folder_id" validate:"required,objectid"`
+	UserID   primitive.ObjectID `json:"user_id" validate:"required,objectid"`
+}
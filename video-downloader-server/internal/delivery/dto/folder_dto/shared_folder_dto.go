@@ -0,0 +1,10 @@
+package folder_dto
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type SharedFolderDto struct {
+	ID         primitive.ObjectID `json:"id"`
+	FolderName string             `json:"folder_name"`
+	OwnerID    primitive.ObjectID `json:"owner_id"`
+	Role       string             `json:"role"`
+}
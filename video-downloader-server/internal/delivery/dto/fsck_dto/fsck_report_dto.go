@@ -0,0 +1,13 @@
+package fsck_dto
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type FsckReportDto struct {
+	DanglingVideos            []primitive.ObjectID `json:"dangling_videos"`
+	MissingPreviews           []primitive.ObjectID `json:"missing_previews"`
+	VideosInMissingFolders    []primitive.ObjectID `json:"videos_in_missing_folders"`
+	FoldersWithMissingParents []primitive.ObjectID `json:"folders_with_missing_parents"`
+	OrphanedVideoFiles        []string             `json:"orphaned_video_files"`
+	OrphanedPreviewFiles      []string             `json:"orphaned_preview_files"`
+	Repaired                  bool                 `json:"repaired"`
+}
@@ -0,0 +1,21 @@
+package gc_dto
+
+import "time"
+
+type GcReportDto struct {
+	OrphanedVideoFiles   []string `json:"orphaned_video_files"`
+	OrphanedPreviewFiles []string `json:"orphaned_preview_files"`
+	OrphanedHlsFiles     []string `json:"orphaned_hls_files"`
+	StaleTmpFiles        []string `json:"stale_tmp_files"`
+	ReclaimableBytes     int64    `json:"reclaimable_bytes"`
+	DryRun               bool     `json:"dry_run"`
+}
+
+type GcMetricsDto struct {
+	Runs           int64     `json:"runs"`
+	FailedRuns     int64     `json:"failed_runs"`
+	FilesDeleted   int64     `json:"files_deleted"`
+	BytesReclaimed int64     `json:"bytes_reclaimed"`
+	LastRunAt      time.Time `json:"last_run_at"`
+	LastError      string    `json:"last_error,omitempty"`
+}
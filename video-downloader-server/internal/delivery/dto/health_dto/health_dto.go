@@ -0,0 +1,32 @@
+package health_dto
+
+import (
+	"time"
+	"video-downloader-server/internal/delivery/dto/job_dto"
+)
+
+type CheckDto struct {
+	Name      string `json:"name"`
+	Ok        bool   `json:"ok"`
+	Error     string `json:"error,omitempty"`
+	Version   string `json:"version,omitempty"`
+	FreeBytes uint64 `json:"free_bytes,omitempty"`
+}
+
+type ReadinessDto struct {
+	Ready  bool       `json:"ready"`
+	Checks []CheckDto `json:"checks"`
+}
+
+type InfoDto struct {
+	Version           string                 `json:"version"`
+	Commit            string                 `json:"commit,omitempty"`
+	BuildTime         string                 `json:"build_time,omitempty"`
+	GoVersion         string                 `json:"go_version"`
+	StartedAt         time.Time              `json:"started_at"`
+	Uptime            string                 `json:"uptime"`
+	Config            map[string]interface{} `json:"config"`
+	Jobs              job_dto.JobsStatsDto   `json:"jobs"`
+	TranscodeJobs     job_dto.JobsStatsDto   `json:"transcode_jobs"`
+	WorkerUtilization float64                `json:"worker_utilization"`
+}
@@ -0,0 +1,27 @@
+package job_dto
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+type JobDto struct {
+	ID         primitive.ObjectID  `json:"id"`
+	Type       string              `json:"type"`
+	Status     string              `json:"status"`
+	Source     string              `json:"source"`
+	VideoID    *primitive.ObjectID `json:"video_id,omitempty"`
+	Error      string              `json:"error,omitempty"`
+	Progress   float64             `json:"progress,omitempty"`
+	NextJobID  *primitive.ObjectID `json:"next_job_id,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	StartedAt  *time.Time          `json:"started_at,omitempty"`
+	FinishedAt *time.Time          `json:"finished_at,omitempty"`
+}
+
+type JobsStatsDto struct {
+	Workers   int `json:"workers"`
+	Busy      int `json:"busy"`
+	Queued    int `json:"queued"`
+	QueueSize int `json:"queue_size"`
+}
@@ -0,0 +1,15 @@
+package video_dto
+
+// ChapterDto is a chapter of a video, start and end are in seconds. The end
+// of the last chapter is 0 when the duration of the video is unknown.
+type ChapterDto struct {
+	Title string  `json:"title"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// SplitVideoDto names the folder created next to the video to hold one video
+// per chapter.
+type SplitVideoDto struct {
+	FolderName string `json:"folder_name" validate:"required,foldername"`
+}
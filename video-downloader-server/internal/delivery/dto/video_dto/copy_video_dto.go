@@ -0,0 +1,8 @@
+package video_dto
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type CopyVideoDto struct {
+	ID       primitive.ObjectID `json:"id" validate:"required,objectid"`
+	FolderID primitive.ObjectID `json:"folder_id" validate:"required,objectid"`
+}
@@ -0,0 +1,9 @@
+package video_dto
+
+// CreateShareLinkDto configures a new share link, ExpiresIn is in seconds.
+// Every field is optional.
+type CreateShareLinkDto struct {
+	ExpiresIn int64  `json:"expires_in" validate:"omitempty,min=60,max=31536000"`
+	MaxViews  int    `json:"max_views" validate:"omitempty,min=1"`
+	Password  string `json:"password" validate:"omitempty,min=4,max=72"`
+}
@@ -0,0 +1,10 @@
+package video_dto
+
+import "video-downloader-server/internal/delivery/dto/job_dto"
+
+// HlsStatusDto tells whether the renditions of a video are ready to play, and
+// otherwise which job is generating them.
+type HlsStatusDto struct {
+	Ready bool            `json:"ready"`
+	Job   *job_dto.JobDto `json:"job,omitempty"`
+}
@@ -0,0 +1,19 @@
+package video_dto
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// ShareLinkDto describes a share link. Token is only set in the response that
+// creates it, the link is then served under /s/{token}.
+type ShareLinkDto struct {
+	ID                primitive.ObjectID `json:"id"`
+	VideoID           primitive.ObjectID `json:"video_id"`
+	PasswordProtected bool               `json:"password_protected"`
+	ExpiresAt         *time.Time         `json:"expires_at"`
+	MaxViews          int                `json:"max_views"`
+	Views             int                `json:"views"`
+	CreatedAt         time.Time          `json:"created_at"`
+	Token             string             `json:"token,omitempty"`
+}
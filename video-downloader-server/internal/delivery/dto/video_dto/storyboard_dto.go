@@ -0,0 +1,10 @@
+package video_dto
+
+import "video-downloader-server/internal/delivery/dto/job_dto"
+
+// StoryboardStatusDto tells whether the storyboard of a video is ready, and
+// otherwise which job is generating it.
+type StoryboardStatusDto struct {
+	Ready bool            `json:"ready"`
+	Job   *job_dto.JobDto `json:"job,omitempty"`
+}
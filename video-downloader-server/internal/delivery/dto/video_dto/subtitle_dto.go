@@ -0,0 +1,15 @@
+package video_dto
+
+type SubtitleDto struct {
+	Lang  string `json:"lang"`
+	Label string `json:"label"`
+	Auto  bool   `json:"auto"`
+}
+
+// UploadSubtitleDto holds the lang and label fields of a subtitle upload,
+// which is multipart form data with the file in the file field. The track
+// replaces the one of the same language, label defaults to the language.
+type UploadSubtitleDto struct {
+	Lang  string `validate:"required,subtitlelang"`
+	Label string `validate:"omitempty,max=64"`
+}
@@ -0,0 +1,7 @@
+package video_dto
+
+// TranscodeVideoDto names one of the configured transcode profiles, such as
+// h264-720p.
+type TranscodeVideoDto struct {
+	Profile string `json:"profile" validate:"required,max=32"`
+}
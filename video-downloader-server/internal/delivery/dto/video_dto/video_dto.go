@@ -8,4 +8,6 @@ type VideoDto struct {
 	FolderID    primitive.ObjectID `json:"folder_id"`
 	RealPath    string             `json:"real_path"`
 	PreviewPath string             `json:"preview_path"`
+	Subtitles   []SubtitleDto      `json:"subtitles,omitempty"`
+	Chapters    []ChapterDto       `json:"chapters,omitempty"`
 }
@@ -0,0 +1,14 @@
+package video_dto
+
+import (
+	"os"
+	"time"
+)
+
+type VideoFileInfoDto struct {
+	VideoName   string
+	FileSize    int64
+	ModTime     time.Time
+	ContentType string
+	VideoFile   *os.File
+}
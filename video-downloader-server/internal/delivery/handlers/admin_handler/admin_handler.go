@@ -0,0 +1,123 @@
+package admin_handler
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+	"video-downloader-server/internal/delivery"
+	"video-downloader-server/internal/delivery/dto/auth_dto"
+	"video-downloader-server/internal/delivery/dto/fsck_dto"
+	"video-downloader-server/internal/delivery/dto/gc_dto"
+	"video-downloader-server/internal/delivery/middleware"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/logger"
+)
+
+type FsckService interface {
+	Check(ctx context.Context, repair bool) (fsck_dto.FsckReportDto, error)
+}
+
+type GcService interface {
+	Collect(ctx context.Context, dryRun bool) (gc_dto.GcReportDto, error)
+	Metrics() gc_dto.GcMetricsDto
+}
+
+type AuthService interface {
+	CreateUser(ctx context.Context, createUserInput auth_dto.CreateUserDto) (auth_dto.UserDto, error)
+}
+
+type AdminHandler struct {
+	fsckService FsckService
+	gcService   GcService
+	authService AuthService
+	validator   *validator.Validate
+}
+
+func NewAdminHandler(fsckService FsckService, gcService GcService, authService AuthService, validator *validator.Validate) *AdminHandler {
+	return &AdminHandler{
+		fsckService: fsckService,
+		gcService:   gcService,
+		authService: authService,
+		validator:   validator,
+	}
+}
+
+func (a AdminHandler) RegisterRoutes(r chi.Router) {
+	r.Route("/admin", func(r chi.Router) {
+		r.Get("/fsck", a.checkConsistency)
+		r.Post("/fsck/repair", a.repairConsistency)
+		r.Get("/gc", a.reportGarbage)
+		r.Post("/gc/run", a.collectGarbage)
+		r.Get("/gc/metrics", a.getGcMetrics)
+		r.With(middleware.ValidateCreateUserInput(a.validator)).Post("/users", a.createUser)
+	})
+}
+
+func (a AdminHandler) checkConsistency(w http.ResponseWriter, r *http.Request) {
+	report, err := a.fsckService.Check(r.Context(), false)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrCheckingConsistency)
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrCheckingConsistency})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusOK, report)
+}
+
+func (a AdminHandler) repairConsistency(w http.ResponseWriter, r *http.Request) {
+	report, err := a.fsckService.Check(r.Context(), true)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrRepairingConsistency)
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrRepairingConsistency})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusOK, report)
+}
+
+func (a AdminHandler) reportGarbage(w http.ResponseWriter, r *http.Request) {
+	report, err := a.gcService.Collect(r.Context(), true)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrCollectingGarbage)
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrCollectingGarbage})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusOK, report)
+}
+
+func (a AdminHandler) collectGarbage(w http.ResponseWriter, r *http.Request) {
+	report, err := a.gcService.Collect(r.Context(), false)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrCollectingGarbage)
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrCollectingGarbage})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusOK, report)
+}
+
+func (a AdminHandler) getGcMetrics(w http.ResponseWriter, r *http.Request) {
+	delivery.RespondWithJSON(w, http.StatusOK, a.gcService.Metrics())
+}
+
+func (a AdminHandler) createUser(w http.ResponseWriter, r *http.Request) {
+	createUserInput := r.Context().Value(delivery.CreateUserInputKey).(auth_dto.CreateUserDto)
+
+	user, err := a.authService.CreateUser(r.Context(), createUserInput)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrCreatingUser)
+
+		if errors.Is(err, domain.ErrUserAlreadyExist) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrCreatingUser, Message: domain.ErrUserAlreadyExist.Error()})
+			return
+		}
+
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrCreatingUser})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusCreated, user)
+}
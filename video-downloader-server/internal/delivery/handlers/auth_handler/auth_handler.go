@@ -0,0 +1,184 @@
+package auth_handler
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"video-downloader-server/internal/delivery"
+	"video-downloader-server/internal/delivery/dto/auth_dto"
+	"video-downloader-server/internal/delivery/middleware"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/logger"
+)
+
+type AuthService interface {
+	Register(ctx context.Context, registerInput auth_dto.RegisterDto) (auth_dto.UserDto, error)
+	Login(ctx context.Context, loginInput auth_dto.LoginDto) (auth_dto.SessionDto, error)
+	Logout(ctx context.Context, token string) error
+	Authenticate(ctx context.Context, token string) (domain.User, []string, error)
+	CreateToken(ctx context.Context, userID primitive.ObjectID, createTokenInput auth_dto.CreateTokenDto) (auth_dto.TokenDto, error)
+	ListTokens(ctx context.Context, userID primitive.ObjectID) ([]auth_dto.TokenDto, error)
+	RevokeToken(ctx context.Context, userID primitive.ObjectID, tokenID primitive.ObjectID) error
+}
+
+type AuthHandler struct {
+	authService  AuthService
+	validator    *validator.Validate
+	cookieSecure bool
+}
+
+func NewAuthHandler(authService AuthService, validator *validator.Validate, cookieSecure bool) *AuthHandler {
+	return &AuthHandler{
+		authService:  authService,
+		validator:    validator,
+		cookieSecure: cookieSecure,
+	}
+}
+
+func (h AuthHandler) RegisterRoutes(r chi.Router) {
+	r.Route("/auth", func(r chi.Router) {
+		r.With(middleware.ValidateRegisterInput(h.validator)).Post("/register", h.register)
+		r.With(middleware.ValidateLoginInput(h.validator)).Post("/login", h.login)
+		r.With(middleware.Authenticate(h.authService)).Post("/logout", h.logout)
+		r.With(middleware.Authenticate(h.authService)).Get("/me", h.getMe)
+		r.Route("/tokens", func(r chi.Router) {
+			r.Use(middleware.Authenticate(h.authService), middleware.RequireScope(domain.ScopeManage))
+			r.With(middleware.ValidateCreateTokenInput(h.validator)).Post("/", h.createToken)
+			r.Get("/", h.listTokens)
+			r.With(middleware.ValidateTokenIDInput).Delete("/", h.revokeToken)
+		})
+	})
+}
+
+func (h AuthHandler) register(w http.ResponseWriter, r *http.Request) {
+	registerInput := r.Context().Value(delivery.RegisterInputKey).(auth_dto.RegisterDto)
+
+	user, err := h.authService.Register(r.Context(), registerInput)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrRegistering)
+
+		if errors.Is(err, domain.ErrSignupDisabled) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrRegistering, Message: domain.ErrSignupDisabled.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrUserAlreadyExist) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrRegistering, Message: domain.ErrUserAlreadyExist.Error()})
+			return
+		}
+
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrRegistering})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusCreated, user)
+}
+
+func (h AuthHandler) login(w http.ResponseWriter, r *http.Request) {
+	loginInput := r.Context().Value(delivery.LoginInputKey).(auth_dto.LoginDto)
+
+	session, err := h.authService.Login(r.Context(), loginInput)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrLoggingIn)
+
+		if errors.Is(err, domain.ErrInvalidCredentials) {
+			delivery.RespondWithJSON(w, http.StatusUnauthorized, delivery.JsonError{Error: delivery.ErrLoggingIn, Message: domain.ErrInvalidCredentials.Error()})
+			return
+		}
+
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrLoggingIn})
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.SessionCookie,
+		Value:    session.Token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   h.cookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	delivery.RespondWithJSON(w, http.StatusOK, session)
+}
+
+func (h AuthHandler) logout(w http.ResponseWriter, r *http.Request) {
+	if err := h.authService.Logout(r.Context(), middleware.SessionToken(r)); err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrLoggingOut)
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrLoggingOut})
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.SessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   h.cookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	delivery.RespondWithJSON(w, http.StatusOK, nil)
+}
+
+func (h AuthHandler) getMe(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+
+	delivery.RespondWithJSON(w, http.StatusOK, auth_dto.UserDto{
+		ID:        user.ID,
+		Username:  user.Username,
+		Role:      user.Role,
+		CreatedAt: user.CreatedAt,
+	})
+}
+
+func (h AuthHandler) createToken(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+	createTokenInput := r.Context().Value(delivery.CreateTokenInputKey).(auth_dto.CreateTokenDto)
+
+	token, err := h.authService.CreateToken(r.Context(), user.ID, createTokenInput)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrCreatingToken)
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrCreatingToken})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusCreated, token)
+}
+
+func (h AuthHandler) listTokens(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+
+	tokens, err := h.authService.ListTokens(r.Context(), user.ID)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrGettingTokens)
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrGettingTokens})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusOK, tokens)
+}
+
+func (h AuthHandler) revokeToken(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+	tokenID := r.Context().Value(delivery.TokenIDInputKey).(primitive.ObjectID)
+
+	if err := h.authService.RevokeToken(r.Context(), user.ID, tokenID); err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrRevokingToken)
+
+		if errors.Is(err, domain.ErrAPITokenNotFound) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrRevokingToken, Message: domain.ErrAPITokenNotFound.Error()})
+			return
+		}
+
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrRevokingToken})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusOK, nil)
+}
@@ -1,54 +1,69 @@
 package folders_handler
 
 import (
+	"context"
 	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
-	log "github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"net/http"
 	"video-downloader-server/internal/delivery"
 	"video-downloader-server/internal/delivery/dto/folder_dto"
 	"video-downloader-server/internal/delivery/middleware"
 	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/logger"
 )
 
 type FoldersService interface {
-	Create(createFolderInput folder_dto.CreateFolderDto) (folder_dto.FolderDto, error)
-	Rename(renameFolderInput folder_dto.RenameFolderDto) (folder_dto.FolderDto, error)
-	Move(moveFolderInput folder_dto.MoveFolderDto) (folder_dto.FolderDto, error)
-	Delete(deleteFolderInput folder_dto.DeleteFolderDto) error
-	Get(folderID primitive.ObjectID) (folder_dto.FolderContentDto, error)
+	Create(ctx context.Context, ownerID primitive.ObjectID, createFolderInput folder_dto.CreateFolderDto) (folder_dto.FolderDto, error)
+	Rename(ctx context.Context, ownerID primitive.ObjectID, renameFolderInput folder_dto.RenameFolderDto) (folder_dto.FolderDto, error)
+	Move(ctx context.Context, ownerID primitive.ObjectID, moveFolderInput folder_dto.MoveFolderDto) (folder_dto.FolderDto, error)
+	Delete(ctx context.Context, ownerID primitive.ObjectID, deleteFolderInput folder_dto.DeleteFolderDto) error
+	Get(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) (folder_dto.FolderContentDto, error)
+}
+
+type SharingService interface {
+	Grant(ctx context.Context, userID primitive.ObjectID, grantAccessInput folder_dto.GrantAccessDto) (folder_dto.MemberDto, error)
+	Revoke(ctx context.Context, userID primitive.ObjectID, revokeAccessInput folder_dto.RevokeAccessDto) error
+	GetMembers(ctx context.Context, userID primitive.ObjectID, folderID primitive.ObjectID) ([]folder_dto.MemberDto, error)
+	GetShared(ctx context.Context, userID primitive.ObjectID) ([]folder_dto.SharedFolderDto, error)
 }
 
 type FoldersHandler struct {
 	foldersService FoldersService
+	sharingService SharingService
 	validator      *validator.Validate
 }
 
-func NewFoldersHandler(foldersService FoldersService, validator *validator.Validate) *FoldersHandler {
+func NewFoldersHandler(foldersService FoldersService, sharingService SharingService, validator *validator.Validate) *FoldersHandler {
 	return &FoldersHandler{
 		foldersService: foldersService,
+		sharingService: sharingService,
 		validator:      validator,
 	}
 }
 
-func (f FoldersHandler) RegisterRoutes(r *chi.Mux) {
+func (f FoldersHandler) RegisterRoutes(r chi.Router) {
 	r.Route("/folders", func(r chi.Router) {
-		r.With(middleware.ValidateCreateFolderInput(f.validator)).Post("/", f.createFolder)
-		r.With(middleware.ValidateRenameFolderInput(f.validator)).Put("/rename", f.renameFolder)
-		r.With(middleware.ValidateMoveFolderInput(f.validator)).Put("/move", f.moveFolder)
-		r.With(middleware.ValidateDeleteFolderInput(f.validator)).Delete("/", f.deleteFolder)
-		r.With(middleware.ValidateFolderIDInput).Get("/", f.getFolders)
+		r.With(middleware.RequireScope(domain.ScopeManage), middleware.ValidateCreateFolderInput(f.validator)).Post("/", f.createFolder)
+		r.With(middleware.RequireScope(domain.ScopeManage), middleware.ValidateRenameFolderInput(f.validator)).Put("/rename", f.renameFolder)
+		r.With(middleware.RequireScope(domain.ScopeManage), middleware.ValidateMoveFolderInput(f.validator)).Put("/move", f.moveFolder)
+		r.With(middleware.RequireScope(domain.ScopeManage), middleware.ValidateDeleteFolderInput(f.validator)).Delete("/", f.deleteFolder)
+		r.With(middleware.RequireScope(domain.ScopeRead), middleware.ValidateFolderIDInput).Get("/", f.getFolders)
+		r.With(middleware.RequireScope(domain.ScopeRead)).Get("/shared", f.getShared)
+		r.With(middleware.RequireScope(domain.ScopeRead), middleware.ValidateFolderIDInput).Get("/members", f.getMembers)
+		r.With(middleware.RequireScope(domain.ScopeManage), middleware.ValidateGrantAccessInput(f.validator)).Post("/members", f.grantAccess)
+		r.With(middleware.RequireScope(domain.ScopeManage), middleware.ValidateRevokeAccessInput(f.validator)).Delete("/members", f.revokeAccess)
 	})
 }
 
 func (f FoldersHandler) createFolder(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
 	createFolderInput := r.Context().Value(delivery.CreateFolderInputKey).(folder_dto.CreateFolderDto)
 
-	folder, err := f.foldersService.Create(createFolderInput)
+	folder, err := f.foldersService.Create(r.Context(), user.ID, createFolderInput)
 	if err != nil {
-		log.WithError(err).Error(delivery.ErrCreatingFolder)
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrCreatingFolder)
 
 		if errors.Is(err, domain.ErrFolderNotFound) {
 			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrCreatingFolder, Message: domain.ErrFolderNotFound.Error()})
@@ -60,6 +75,11 @@ func (f FoldersHandler) createFolder(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrCreatingFolder, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
 		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrCreatingFolder})
 		return
 	}
@@ -68,11 +88,12 @@ func (f FoldersHandler) createFolder(w http.ResponseWriter, r *http.Request) {
 }
 
 func (f FoldersHandler) renameFolder(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
 	renameFolderInput := r.Context().Value(delivery.RenameFolderInputKey).(folder_dto.RenameFolderDto)
 
-	folder, err := f.foldersService.Rename(renameFolderInput)
+	folder, err := f.foldersService.Rename(r.Context(), user.ID, renameFolderInput)
 	if err != nil {
-		log.WithError(err).Error(delivery.ErrRenamingFolder)
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrRenamingFolder)
 		if errors.Is(err, domain.ErrFolderNotFound) {
 			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrRenamingFolder, Message: domain.ErrFolderNotFound.Error()})
 			return
@@ -83,6 +104,11 @@ func (f FoldersHandler) renameFolder(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrRenamingFolder, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
 		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrRenamingFolder})
 		return
 	}
@@ -91,11 +117,12 @@ func (f FoldersHandler) renameFolder(w http.ResponseWriter, r *http.Request) {
 }
 
 func (f FoldersHandler) moveFolder(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
 	moveFolderInput := r.Context().Value(delivery.MoveFolderInputKey).(folder_dto.MoveFolderDto)
 
-	folder, err := f.foldersService.Move(moveFolderInput)
+	folder, err := f.foldersService.Move(r.Context(), user.ID, moveFolderInput)
 	if err != nil {
-		log.WithError(err).Error(delivery.ErrMovingFolder)
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrMovingFolder)
 		if errors.Is(err, domain.ErrFolderNotFound) {
 			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrMovingFolder, Message: domain.ErrFolderNotFound.Error()})
 			return
@@ -106,6 +133,16 @@ func (f FoldersHandler) moveFolder(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if errors.Is(err, domain.ErrMovingAcrossOwners) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrMovingFolder, Message: domain.ErrMovingAcrossOwners.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrMovingFolder, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
 		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrMovingFolder})
 		return
 	}
@@ -114,16 +151,22 @@ func (f FoldersHandler) moveFolder(w http.ResponseWriter, r *http.Request) {
 }
 
 func (f FoldersHandler) deleteFolder(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
 	deleteFolderInput := r.Context().Value(delivery.DeleteFolderInputKey).(folder_dto.DeleteFolderDto)
 
-	err := f.foldersService.Delete(deleteFolderInput)
+	err := f.foldersService.Delete(r.Context(), user.ID, deleteFolderInput)
 	if err != nil {
-		log.WithError(err).Error(delivery.ErrDeletingFolder)
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrDeletingFolder)
 		if errors.Is(err, domain.ErrFolderNotFound) {
 			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrDeletingFolder, Message: domain.ErrFolderNotFound.Error()})
 			return
 		}
 
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrDeletingFolder, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
 		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrDeletingFolder})
 		return
 	}
@@ -132,19 +175,120 @@ func (f FoldersHandler) deleteFolder(w http.ResponseWriter, r *http.Request) {
 }
 
 func (f FoldersHandler) getFolders(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
 	folderID := r.Context().Value(delivery.FolderIDInputKey).(primitive.ObjectID)
 
-	folderContent, err := f.foldersService.Get(folderID)
+	folderContent, err := f.foldersService.Get(r.Context(), user.ID, folderID)
 	if err != nil {
-		log.WithError(err).Error(delivery.ErrGettingFolder)
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrGettingFolder)
 		if errors.Is(err, domain.ErrFolderNotFound) {
 			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrGettingFolder, Message: domain.ErrFolderNotFound.Error()})
 			return
 		}
 
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrGettingFolder, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
 		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrGettingFolder})
 		return
 	}
 
 	delivery.RespondWithJSON(w, http.StatusOK, folderContent)
 }
+
+func (f FoldersHandler) getShared(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+
+	folders, err := f.sharingService.GetShared(r.Context(), user.ID)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrGettingShared)
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrGettingShared})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusOK, folders)
+}
+
+func (f FoldersHandler) getMembers(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+	folderID := r.Context().Value(delivery.FolderIDInputKey).(primitive.ObjectID)
+
+	members, err := f.sharingService.GetMembers(r.Context(), user.ID, folderID)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrGettingMembers)
+		if errors.Is(err, domain.ErrFolderNotFound) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrGettingMembers, Message: domain.ErrFolderNotFound.Error()})
+			return
+		}
+
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrGettingMembers})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusOK, members)
+}
+
+func (f FoldersHandler) grantAccess(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+	grantAccessInput := r.Context().Value(delivery.GrantAccessInputKey).(folder_dto.GrantAccessDto)
+
+	member, err := f.sharingService.Grant(r.Context(), user.ID, grantAccessInput)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrGrantingAccess)
+		if errors.Is(err, domain.ErrFolderNotFound) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrGrantingAccess, Message: domain.ErrFolderNotFound.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrUserNotFound) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrGrantingAccess, Message: domain.ErrUserNotFound.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrSharingWithOwner) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrGrantingAccess, Message: domain.ErrSharingWithOwner.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrGrantingAccess, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrGrantingAccess})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusOK, member)
+}
+
+func (f FoldersHandler) revokeAccess(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+	revokeAccessInput := r.Context().Value(delivery.RevokeAccessInputKey).(folder_dto.RevokeAccessDto)
+
+	err := f.sharingService.Revoke(r.Context(), user.ID, revokeAccessInput)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrRevokingAccess)
+		if errors.Is(err, domain.ErrFolderNotFound) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrRevokingAccess, Message: domain.ErrFolderNotFound.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrMemberNotFound) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrRevokingAccess, Message: domain.ErrMemberNotFound.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrRevokingAccess, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrRevokingAccess})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusOK, nil)
+}
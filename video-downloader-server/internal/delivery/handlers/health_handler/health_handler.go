@@ -0,0 +1,55 @@
+package health_handler
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"video-downloader-server/internal/delivery"
+	"video-downloader-server/internal/delivery/dto/health_dto"
+	"video-downloader-server/internal/logger"
+)
+
+type HealthService interface {
+	Ready(ctx context.Context) health_dto.ReadinessDto
+	Info() health_dto.InfoDto
+}
+
+type HealthHandler struct {
+	healthService HealthService
+}
+
+func NewHealthHandler(healthService HealthService) *HealthHandler {
+	return &HealthHandler{
+		healthService: healthService,
+	}
+}
+
+func (h HealthHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/healthz", h.checkHealth)
+	r.Get("/readyz", h.checkReadiness)
+}
+
+// RegisterAdminRoutes registers the debug routes, they expose the config and
+// must only be reachable by admins.
+func (h HealthHandler) RegisterAdminRoutes(r chi.Router) {
+	r.Get("/debug/info", h.getInfo)
+}
+
+func (h HealthHandler) checkHealth(w http.ResponseWriter, r *http.Request) {
+	delivery.RespondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h HealthHandler) checkReadiness(w http.ResponseWriter, r *http.Request) {
+	readiness := h.healthService.Ready(r.Context())
+	if !readiness.Ready {
+		logger.FromContext(r.Context()).WithField("checks", readiness.Checks).Warn(delivery.ErrNotReady)
+		delivery.RespondWithJSON(w, http.StatusServiceUnavailable, readiness)
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusOK, readiness)
+}
+
+func (h HealthHandler) getInfo(w http.ResponseWriter, r *http.Request) {
+	delivery.RespondWithJSON(w, http.StatusOK, h.healthService.Info())
+}
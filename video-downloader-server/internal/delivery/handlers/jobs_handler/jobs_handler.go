@@ -0,0 +1,67 @@
+package jobs_handler
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"video-downloader-server/internal/delivery"
+	"video-downloader-server/internal/delivery/dto/job_dto"
+	"video-downloader-server/internal/delivery/middleware"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/logger"
+)
+
+type JobsService interface {
+	Get(ownerID primitive.ObjectID, jobID primitive.ObjectID) (job_dto.JobDto, error)
+}
+
+type JobsHandler struct {
+	jobsServices []JobsService
+}
+
+// NewJobsHandler serves the jobs of every pool, a job is looked up in each of
+// them in turn.
+func NewJobsHandler(jobsServices ...JobsService) *JobsHandler {
+	return &JobsHandler{
+		jobsServices: jobsServices,
+	}
+}
+
+func (h JobsHandler) RegisterRoutes(r chi.Router) {
+	r.Route("/jobs", func(r chi.Router) {
+		r.With(middleware.RequireScope(domain.ScopeDownload), middleware.ValidateJobIDInput).Get("/", h.getJob)
+	})
+}
+
+func (h JobsHandler) getJob(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+	jobID := r.Context().Value(delivery.JobIDInputKey).(primitive.ObjectID)
+
+	job, err := h.get(user.ID, jobID)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrGettingJob)
+
+		if errors.Is(err, domain.ErrJobNotFound) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrGettingJob, Message: domain.ErrJobNotFound.Error()})
+			return
+		}
+
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrGettingJob})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusOK, job)
+}
+
+func (h JobsHandler) get(ownerID primitive.ObjectID, jobID primitive.ObjectID) (job_dto.JobDto, error) {
+	var err error
+	for _, jobsService := range h.jobsServices {
+		var job job_dto.JobDto
+		if job, err = jobsService.Get(ownerID, jobID); err == nil {
+			return job, nil
+		}
+	}
+
+	return job_dto.JobDto{}, err
+}
@@ -1,59 +1,164 @@
 package videos_handler
 
 import (
+	"context"
 	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
-	log "github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
 	"net/http"
+	"time"
 	"video-downloader-server/internal/delivery"
+	"video-downloader-server/internal/delivery/dto/job_dto"
 	"video-downloader-server/internal/delivery/dto/video_dto"
 	"video-downloader-server/internal/delivery/middleware"
 	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/logger"
+)
+
+const (
+	// shareViewCookie carries the grant of a counted share link view, it is
+	// scoped to the path of the link.
+	shareViewCookie = "share_view"
+	// sharePasswordHeader carries the password of a protected share link.
+	sharePasswordHeader = "X-Share-Password"
+	// sharePasswordField carries the password of a protected share link
+	// posted from a form.
+	sharePasswordField = "password"
 )
 
 type VideosService interface {
-	DownloadToServer(downloadVideoInput video_dto.DownloadVideoDto) error
-	GetVideoFileInfo(videoID primitive.ObjectID) (video_dto.VideoFileInfoDto, error)
-	GetVideoRangeInfo(videoID primitive.ObjectID, rangeHeader string) (video_dto.VideoRangeInfoDto, error)
-	Rename(renameVideoInput video_dto.RenameVideoDto) (video_dto.VideoDto, error)
-	Move(moveVideoInput video_dto.MoveVideoDto) (video_dto.VideoDto, error)
-	Delete(deleteVideoInput video_dto.DeleteVideoDto) error
+	DownloadToServer(ctx context.Context, ownerID primitive.ObjectID, downloadVideoInput video_dto.DownloadVideoDto) (job_dto.JobDto, error)
+	GetVideoFileInfo(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID) (video_dto.VideoFileInfoDto, error)
+	GetPreviewFileInfo(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID) (video_dto.VideoFileInfoDto, error)
+	Rename(ctx context.Context, ownerID primitive.ObjectID, renameVideoInput video_dto.RenameVideoDto) (video_dto.VideoDto, error)
+	Move(ctx context.Context, ownerID primitive.ObjectID, moveVideoInput video_dto.MoveVideoDto) (video_dto.VideoDto, error)
+	Copy(ctx context.Context, ownerID primitive.ObjectID, copyVideoInput video_dto.CopyVideoDto) (video_dto.VideoDto, error)
+	Delete(ctx context.Context, ownerID primitive.ObjectID, deleteVideoInput video_dto.DeleteVideoDto) error
+	Transcode(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, transcodeVideoInput video_dto.TranscodeVideoDto) (job_dto.JobDto, error)
+	ExtractChapters(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID) ([]video_dto.ChapterDto, error)
+	SplitChapters(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, splitVideoInput video_dto.SplitVideoDto) (job_dto.JobDto, error)
+}
+
+type ShareLinksService interface {
+	Create(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, createShareLinkInput video_dto.CreateShareLinkDto) (video_dto.ShareLinkDto, error)
+	List(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID) ([]video_dto.ShareLinkDto, error)
+	Revoke(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, linkID primitive.ObjectID) error
+	GetVideoFileInfo(ctx context.Context, token, password, grant string, countView bool) (video_dto.VideoFileInfoDto, string, error)
+}
+
+type StoryboardService interface {
+	Generate(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID) (video_dto.StoryboardStatusDto, error)
+	GetFile(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, name string) (video_dto.VideoFileInfoDto, error)
+}
+
+type HlsService interface {
+	Generate(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID) (video_dto.HlsStatusDto, error)
+	GetFile(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, name string) (video_dto.VideoFileInfoDto, error)
+}
+
+type SubtitlesService interface {
+	Upload(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, uploadSubtitleInput video_dto.UploadSubtitleDto, fileName string, file io.Reader) (video_dto.SubtitleDto, error)
+	GetFile(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, lang string) (video_dto.VideoFileInfoDto, error)
 }
 
 type VideosHandler struct {
-	videosService VideosService
-	validator     *validator.Validate
+	videosService     VideosService
+	shareLinksService ShareLinksService
+	hlsService        HlsService
+	subtitlesService  SubtitlesService
+	storyboardService StoryboardService
+	validator         *validator.Validate
+	retryAfter        time.Duration
+	cookieSecure      bool
 }
 
-func NewVideosHandler(videosService VideosService, validator *validator.Validate) *VideosHandler {
+// NewVideosHandler creates the handler, retryAfter is sent with download and
+// transcode jobs rejected because the job queue or the user's job limit is
+// full and with HLS renditions that are still being generated.
+func NewVideosHandler(videosService VideosService, shareLinksService ShareLinksService, hlsService HlsService, subtitlesService SubtitlesService, storyboardService StoryboardService, validator *validator.Validate, retryAfter time.Duration, cookieSecure bool) *VideosHandler {
 	return &VideosHandler{
-		videosService: videosService,
-		validator:     validator,
+		videosService:     videosService,
+		shareLinksService: shareLinksService,
+		hlsService:        hlsService,
+		subtitlesService:  subtitlesService,
+		storyboardService: storyboardService,
+		validator:         validator,
+		retryAfter:        retryAfter,
+		cookieSecure:      cookieSecure,
 	}
 }
 
-func (h VideosHandler) RegisterRoutes(r *chi.Mux) {
+func (h VideosHandler) RegisterRoutes(r chi.Router) {
 	r.Route("/videos", func(r chi.Router) {
-		r.With(middleware.ValidateDownloadVideoInput(h.validator)).Post("/download-to-server", h.downloadVideoToServer)
-		r.With(middleware.ValidateVideoIDInput).Get("/download-to-local", h.downloadVideoToLocal)
-		r.With(middleware.ValidateVideoIDInput).Get("/stream", h.streamVideo)
-		r.With(middleware.ValidateRenameVideoInput(h.validator)).Put("/rename", h.renameVideo)
-		r.With(middleware.ValidateMoveVideoInput(h.validator)).Put("/move", h.moveVideo)
-		r.With(middleware.ValidateDeleteVideoInput(h.validator)).Delete("/", h.deleteVideo)
+		r.With(middleware.RequireScope(domain.ScopeDownload), middleware.ValidateDownloadVideoInput(h.validator)).Post("/download-to-server", h.downloadVideoToServer)
+		r.With(middleware.RequireScope(domain.ScopeRead), middleware.ValidateVideoIDInput).Get("/download-to-local", h.downloadVideoToLocal)
+		r.With(middleware.RequireScope(domain.ScopeRead), middleware.ValidateVideoIDInput).Get("/stream", h.streamVideo)
+		r.With(middleware.RequireScope(domain.ScopeRead), middleware.ValidateVideoIDInput).Head("/stream", h.streamVideo)
+		r.With(middleware.RequireScope(domain.ScopeManage), middleware.ValidateRenameVideoInput(h.validator)).Put("/rename", h.renameVideo)
+		r.With(middleware.RequireScope(domain.ScopeManage), middleware.ValidateMoveVideoInput(h.validator)).Put("/move", h.moveVideo)
+		r.With(middleware.RequireScope(domain.ScopeManage), middleware.ValidateCopyVideoInput(h.validator)).Post("/copy", h.copyVideo)
+		r.With(middleware.RequireScope(domain.ScopeManage), middleware.ValidateDeleteVideoInput(h.validator)).Delete("/", h.deleteVideo)
+		r.With(middleware.RequireScope(domain.ScopeManage), middleware.ValidateVideoIDParam, middleware.ValidateCreateShareLinkInput(h.validator)).Post("/{video_id}/share", h.createShareLink)
+		r.With(middleware.RequireScope(domain.ScopeManage), middleware.ValidateVideoIDParam).Get("/{video_id}/share", h.listShareLinks)
+		r.With(middleware.RequireScope(domain.ScopeManage), middleware.ValidateVideoIDParam, middleware.ValidateShareLinkIDParam).Delete("/{video_id}/share/{link_id}", h.revokeShareLink)
+		r.With(middleware.RequireScope(domain.ScopeManage), middleware.ValidateVideoIDParam, middleware.ValidateTranscodeVideoInput(h.validator)).Post("/{video_id}/transcode", h.transcodeVideo)
+		r.With(middleware.RequireScope(domain.ScopeManage), middleware.ValidateVideoIDParam, middleware.ValidateUploadSubtitleInput(h.validator)).Post("/{video_id}/subtitles", h.uploadSubtitle)
+		r.With(middleware.RequireScope(domain.ScopeManage), middleware.ValidateVideoIDParam).Post("/{video_id}/chapters", h.extractChapters)
+		r.With(middleware.RequireScope(domain.ScopeManage), middleware.ValidateVideoIDParam, middleware.ValidateSplitVideoInput(h.validator)).Post("/{video_id}/split", h.splitVideo)
+		r.With(middleware.RequireScope(domain.ScopeRead), middleware.ValidateVideoIDParam).Get("/{video_id}/subtitles/{lang}", h.getSubtitle)
+		r.With(middleware.RequireScope(domain.ScopeManage), middleware.ValidateVideoIDParam).Post("/{video_id}/hls", h.generateHls)
+		r.With(middleware.RequireScope(domain.ScopeRead), middleware.ValidateVideoIDParam).Get("/{video_id}/hls/*", h.getHlsFile)
+		r.With(middleware.RequireScope(domain.ScopeRead), middleware.ValidateVideoIDParam).Get("/{video_id}/preview", h.getPreview)
+		r.With(middleware.RequireScope(domain.ScopeManage), middleware.ValidateVideoIDParam).Post("/{video_id}/preview/storyboard", h.generateStoryboard)
+		r.With(middleware.RequireScope(domain.ScopeRead), middleware.ValidateVideoIDParam).Get("/{video_id}/preview/{name}", h.getStoryboardFile)
 	})
 }
 
+// RegisterPublicRoutes registers the share link routes, which need no account.
+// POST lets a form submit the password of a protected link.
+func (h VideosHandler) RegisterPublicRoutes(r chi.Router) {
+	r.Get("/s/{token}", h.openShareLink)
+	r.Head("/s/{token}", h.openShareLink)
+	r.Post("/s/{token}", h.openShareLink)
+}
+
 func (h VideosHandler) downloadVideoToServer(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
 	downloadVideoInput := r.Context().Value(delivery.DownloadVideoInputKey).(video_dto.DownloadVideoDto)
 
-	err := h.videosService.DownloadToServer(downloadVideoInput)
+	job, err := h.videosService.DownloadToServer(r.Context(), user.ID, downloadVideoInput)
 	if err != nil {
-		log.WithError(err).Error(delivery.ErrDownloadingVideoToServer)
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrDownloadingVideoToServer)
 
-		if errors.Is(err, domain.ErrNotFoundVideoID) || errors.Is(err, domain.ErrParsingURL) {
-			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrDownloadingVideoToServer, Message: delivery.ErrGettingID})
+		if errors.Is(err, domain.ErrUnknownTranscodeProfile) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrDownloadingVideoToServer, Message: domain.ErrUnknownTranscodeProfile.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrJobQueueFull) {
+			delivery.RespondTooManyRequests(w, h.retryAfter, delivery.JsonError{Error: delivery.ErrDownloadingVideoToServer, Message: domain.ErrJobQueueFull.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrTooManyJobs) {
+			delivery.RespondTooManyRequests(w, h.retryAfter, delivery.JsonError{Error: delivery.ErrDownloadingVideoToServer, Message: domain.ErrTooManyJobs.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrShuttingDown) {
+			delivery.RespondWithJSON(w, http.StatusServiceUnavailable, delivery.JsonError{Error: delivery.ErrDownloadingVideoToServer, Message: domain.ErrShuttingDown.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrFolderNotFound) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrDownloadingVideoToServer, Message: domain.ErrFolderNotFound.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrDownloadingVideoToServer, Message: domain.ErrFolderAccessDenied.Error()})
 			return
 		}
 
@@ -61,22 +166,28 @@ func (h VideosHandler) downloadVideoToServer(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	log.Infof(delivery.SuccessfulLoad+": %s\n", downloadVideoInput.VideoURL)
-	delivery.RespondWithJSON(w, http.StatusOK, nil)
+	logger.FromContext(r.Context()).WithField(logger.JobIDField, job.ID.Hex()).Infof(delivery.SuccessfulLoadQueued+": %s", downloadVideoInput.VideoURL)
+	delivery.RespondWithJSON(w, http.StatusAccepted, job)
 }
 
 func (h VideosHandler) downloadVideoToLocal(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
 	videoID := r.Context().Value(delivery.VideoIDInputKey).(primitive.ObjectID)
 
-	videoInfo, err := h.videosService.GetVideoFileInfo(videoID)
+	videoInfo, err := h.videosService.GetVideoFileInfo(r.Context(), user.ID, videoID)
 	if err != nil {
-		log.WithError(err).Error(delivery.ErrGettingVideo)
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrGettingVideo)
 
 		if errors.Is(err, domain.ErrVideoNotFound) {
 			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrGettingVideo, Message: domain.ErrVideoNotFound.Error()})
 			return
 		}
 
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrGettingVideo, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
 		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrGettingVideo})
 		return
 	}
@@ -85,42 +196,53 @@ func (h VideosHandler) downloadVideoToLocal(w http.ResponseWriter, r *http.Reque
 }
 
 func (h VideosHandler) streamVideo(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
 	videoID := r.Context().Value(delivery.VideoIDInputKey).(primitive.ObjectID)
-	rangeHeader := r.Header.Get("Range")
 
-	videoRangeInfo, err := h.videosService.GetVideoRangeInfo(videoID, rangeHeader)
+	videoInfo, err := h.videosService.GetVideoFileInfo(r.Context(), user.ID, videoID)
 	if err != nil {
-		log.WithError(err).Error(delivery.ErrGettingVideoRange)
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrStreamingVideo)
 
 		if errors.Is(err, domain.ErrVideoNotFound) {
-			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrGettingVideoRange, Message: domain.ErrVideoNotFound.Error()})
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrStreamingVideo, Message: domain.ErrVideoNotFound.Error()})
 			return
 		}
 
-		if errors.Is(err, domain.ErrInvalidRangeHeader) {
-			delivery.RespondWithJSON(w, http.StatusRequestedRangeNotSatisfiable, delivery.JsonError{Error: delivery.ErrGettingVideoRange, Message: domain.ErrInvalidRangeHeader.Error()})
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrStreamingVideo, Message: domain.ErrFolderAccessDenied.Error()})
 			return
 		}
 
-		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrGettingVideoRange})
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrStreamingVideo})
 		return
 	}
 
-	delivery.RespondWithVideoRange(w, videoRangeInfo)
+	delivery.RespondWithVideoStream(w, r, videoInfo)
 }
 
 func (h VideosHandler) renameVideo(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
 	renameVideoInput := r.Context().Value(delivery.RenameVideoInputKey).(video_dto.RenameVideoDto)
 
-	video, err := h.videosService.Rename(renameVideoInput)
+	video, err := h.videosService.Rename(r.Context(), user.ID, renameVideoInput)
 	if err != nil {
-		log.WithError(err).Error(delivery.ErrRenamingVideo)
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrRenamingVideo)
 
 		if errors.Is(err, domain.ErrVideoNotFound) {
 			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrRenamingVideo, Message: domain.ErrVideoNotFound.Error()})
 			return
 		}
 
+		if errors.Is(err, domain.ErrVideoAlreadyExist) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrRenamingVideo, Message: domain.ErrVideoAlreadyExist.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrRenamingVideo, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
 		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrRenamingVideo})
 		return
 	}
@@ -129,11 +251,12 @@ func (h VideosHandler) renameVideo(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h VideosHandler) moveVideo(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
 	moveVideoInput := r.Context().Value(delivery.MoveVideoInputKey).(video_dto.MoveVideoDto)
 
-	video, err := h.videosService.Move(moveVideoInput)
+	video, err := h.videosService.Move(r.Context(), user.ID, moveVideoInput)
 	if err != nil {
-		log.WithError(err).Error(delivery.ErrMovingVideo)
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrMovingVideo)
 
 		if errors.Is(err, domain.ErrVideoNotFound) {
 			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrMovingVideo, Message: domain.ErrVideoNotFound.Error()})
@@ -150,6 +273,16 @@ func (h VideosHandler) moveVideo(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if errors.Is(err, domain.ErrMovingAcrossOwners) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrMovingVideo, Message: domain.ErrMovingAcrossOwners.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrMovingVideo, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
 		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrMovingVideo})
 		return
 	}
@@ -157,21 +290,646 @@ func (h VideosHandler) moveVideo(w http.ResponseWriter, r *http.Request) {
 	delivery.RespondWithJSON(w, http.StatusOK, video)
 }
 
+func (h VideosHandler) copyVideo(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+	copyVideoInput := r.Context().Value(delivery.CopyVideoInputKey).(video_dto.CopyVideoDto)
+
+	video, err := h.videosService.Copy(r.Context(), user.ID, copyVideoInput)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrCopyingVideo)
+
+		if errors.Is(err, domain.ErrVideoNotFound) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrCopyingVideo, Message: domain.ErrVideoNotFound.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrFolderNotFound) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrCopyingVideo, Message: domain.ErrFolderNotFound.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrVideoAlreadyExist) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrCopyingVideo, Message: domain.ErrVideoAlreadyExist.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrCopyingVideo, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrCopyingVideo})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusCreated, video)
+}
+
 func (h VideosHandler) deleteVideo(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
 	deleteVideoInput := r.Context().Value(delivery.DeleteVideoInputKey).(video_dto.DeleteVideoDto)
 
-	err := h.videosService.Delete(deleteVideoInput)
+	err := h.videosService.Delete(r.Context(), user.ID, deleteVideoInput)
 	if err != nil {
-		log.WithError(err).Error(delivery.ErrDeletingVideo)
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrDeletingVideo)
 
 		if errors.Is(err, domain.ErrVideoNotFound) {
 			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrDeletingVideo, Message: domain.ErrVideoNotFound.Error()})
 			return
 		}
 
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrDeletingVideo, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
 		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrDeletingVideo})
 		return
 	}
 
 	delivery.RespondWithJSON(w, http.StatusOK, nil)
 }
+
+func (h VideosHandler) createShareLink(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+	videoID := r.Context().Value(delivery.VideoIDInputKey).(primitive.ObjectID)
+	createShareLinkInput := r.Context().Value(delivery.CreateShareLinkInputKey).(video_dto.CreateShareLinkDto)
+
+	link, err := h.shareLinksService.Create(r.Context(), user.ID, videoID, createShareLinkInput)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrCreatingShareLink)
+
+		if errors.Is(err, domain.ErrVideoNotFound) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrCreatingShareLink, Message: domain.ErrVideoNotFound.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrCreatingShareLink, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrCreatingShareLink})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusCreated, link)
+}
+
+func (h VideosHandler) listShareLinks(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+	videoID := r.Context().Value(delivery.VideoIDInputKey).(primitive.ObjectID)
+
+	links, err := h.shareLinksService.List(r.Context(), user.ID, videoID)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrGettingShareLinks)
+
+		if errors.Is(err, domain.ErrVideoNotFound) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrGettingShareLinks, Message: domain.ErrVideoNotFound.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrGettingShareLinks, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrGettingShareLinks})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusOK, links)
+}
+
+func (h VideosHandler) revokeShareLink(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+	videoID := r.Context().Value(delivery.VideoIDInputKey).(primitive.ObjectID)
+	linkID := r.Context().Value(delivery.ShareLinkIDInputKey).(primitive.ObjectID)
+
+	err := h.shareLinksService.Revoke(r.Context(), user.ID, videoID, linkID)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrRevokingShareLink)
+
+		if errors.Is(err, domain.ErrVideoNotFound) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrRevokingShareLink, Message: domain.ErrVideoNotFound.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrShareLinkNotFound) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrRevokingShareLink, Message: domain.ErrShareLinkNotFound.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrRevokingShareLink, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrRevokingShareLink})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusOK, nil)
+}
+
+// transcodeVideo queues the encoding of the video with a configured profile
+// and responds 202 with the job. The transcoded video is added to the folder
+// of the original when the job is done.
+func (h VideosHandler) transcodeVideo(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+	videoID := r.Context().Value(delivery.VideoIDInputKey).(primitive.ObjectID)
+	transcodeVideoInput := r.Context().Value(delivery.TranscodeVideoInputKey).(video_dto.TranscodeVideoDto)
+
+	job, err := h.videosService.Transcode(r.Context(), user.ID, videoID, transcodeVideoInput)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrTranscodingVideo)
+
+		if errors.Is(err, domain.ErrUnknownTranscodeProfile) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrTranscodingVideo, Message: domain.ErrUnknownTranscodeProfile.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrVideoNotFound) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrTranscodingVideo, Message: domain.ErrVideoNotFound.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrTranscodingVideo, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrJobQueueFull) {
+			delivery.RespondTooManyRequests(w, h.retryAfter, delivery.JsonError{Error: delivery.ErrTranscodingVideo, Message: domain.ErrJobQueueFull.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrTooManyJobs) {
+			delivery.RespondTooManyRequests(w, h.retryAfter, delivery.JsonError{Error: delivery.ErrTranscodingVideo, Message: domain.ErrTooManyJobs.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrShuttingDown) {
+			delivery.RespondWithJSON(w, http.StatusServiceUnavailable, delivery.JsonError{Error: delivery.ErrTranscodingVideo, Message: domain.ErrShuttingDown.Error()})
+			return
+		}
+
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrTranscodingVideo})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusAccepted, job)
+}
+
+// extractChapters reads the chapters of the video file again and responds
+// with them.
+func (h VideosHandler) extractChapters(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+	videoID := r.Context().Value(delivery.VideoIDInputKey).(primitive.ObjectID)
+
+	chapters, err := h.videosService.ExtractChapters(r.Context(), user.ID, videoID)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrExtractingChapters)
+
+		if errors.Is(err, domain.ErrNoChapters) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrExtractingChapters, Message: domain.ErrNoChapters.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrVideoNotFound) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrExtractingChapters, Message: domain.ErrVideoNotFound.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrExtractingChapters, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrExtractingChapters})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusOK, chapters)
+}
+
+// splitVideo queues cutting the video into one video per chapter and
+// responds 202 with the job. The videos are added to a new folder next to
+// the video.
+func (h VideosHandler) splitVideo(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+	videoID := r.Context().Value(delivery.VideoIDInputKey).(primitive.ObjectID)
+	splitVideoInput := r.Context().Value(delivery.SplitVideoInputKey).(video_dto.SplitVideoDto)
+
+	job, err := h.videosService.SplitChapters(r.Context(), user.ID, videoID, splitVideoInput)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrSplittingVideo)
+
+		if errors.Is(err, domain.ErrNoChapters) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrSplittingVideo, Message: domain.ErrNoChapters.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrFolderAlreadyExist) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrSplittingVideo, Message: domain.ErrFolderAlreadyExist.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrVideoNotFound) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrSplittingVideo, Message: domain.ErrVideoNotFound.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrSplittingVideo, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrJobQueueFull) {
+			delivery.RespondTooManyRequests(w, h.retryAfter, delivery.JsonError{Error: delivery.ErrSplittingVideo, Message: domain.ErrJobQueueFull.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrTooManyJobs) {
+			delivery.RespondTooManyRequests(w, h.retryAfter, delivery.JsonError{Error: delivery.ErrSplittingVideo, Message: domain.ErrTooManyJobs.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrShuttingDown) {
+			delivery.RespondWithJSON(w, http.StatusServiceUnavailable, delivery.JsonError{Error: delivery.ErrSplittingVideo, Message: domain.ErrShuttingDown.Error()})
+			return
+		}
+
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrSplittingVideo})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusAccepted, job)
+}
+
+// uploadSubtitle saves a .srt or .vtt file sent as multipart form data as a
+// WebVTT track of the video.
+func (h VideosHandler) uploadSubtitle(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+	videoID := r.Context().Value(delivery.VideoIDInputKey).(primitive.ObjectID)
+	uploadSubtitleInput := r.Context().Value(delivery.UploadSubtitleInputKey).(video_dto.UploadSubtitleDto)
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrUploadingSubtitle)
+		delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrUploadingSubtitle, Message: delivery.MesInvalidUploadSubtitleInput})
+		return
+	}
+	defer file.Close()
+
+	subtitle, err := h.subtitlesService.Upload(r.Context(), user.ID, videoID, uploadSubtitleInput, header.Filename, file)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrUploadingSubtitle)
+
+		if errors.Is(err, domain.ErrUnsupportedSubtitleFormat) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrUploadingSubtitle, Message: domain.ErrUnsupportedSubtitleFormat.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrInvalidSubtitle) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrUploadingSubtitle, Message: domain.ErrInvalidSubtitle.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrVideoNotFound) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrUploadingSubtitle, Message: domain.ErrVideoNotFound.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrUploadingSubtitle, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrUploadingSubtitle})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusCreated, subtitle)
+}
+
+// getSubtitle serves a WebVTT track of the video for the track element of
+// the player.
+func (h VideosHandler) getSubtitle(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+	videoID := r.Context().Value(delivery.VideoIDInputKey).(primitive.ObjectID)
+
+	fileInfo, err := h.subtitlesService.GetFile(r.Context(), user.ID, videoID, chi.URLParam(r, "lang"))
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrGettingSubtitle)
+
+		if errors.Is(err, domain.ErrSubtitleNotFound) {
+			delivery.RespondWithJSON(w, http.StatusNotFound, delivery.JsonError{Error: delivery.ErrGettingSubtitle, Message: domain.ErrSubtitleNotFound.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrVideoNotFound) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrGettingSubtitle, Message: domain.ErrVideoNotFound.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrGettingSubtitle, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrGettingSubtitle})
+		return
+	}
+
+	delivery.RespondWithVideoStream(w, r, fileInfo)
+}
+
+// generateHls queues the generation of the HLS renditions ahead of the first
+// play. It responds 200 when they are ready and 202 with the job otherwise.
+func (h VideosHandler) generateHls(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+	videoID := r.Context().Value(delivery.VideoIDInputKey).(primitive.ObjectID)
+
+	status, err := h.hlsService.Generate(r.Context(), user.ID, videoID)
+	if err != nil {
+		h.respondHlsError(w, r, delivery.ErrGeneratingHls, err)
+		return
+	}
+
+	h.respondHlsStatus(w, status)
+}
+
+// getHlsFile serves master.m3u8 and the playlists and segments it links to.
+// The first request for master.m3u8 of a video without renditions queues
+// their generation and responds 202 with the job and Retry-After, if the
+// token may start jobs, like POST /{video_id}/hls.
+func (h VideosHandler) getHlsFile(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+	videoID := r.Context().Value(delivery.VideoIDInputKey).(primitive.ObjectID)
+	name := chi.URLParam(r, "*")
+
+	fileInfo, err := h.hlsService.GetFile(r.Context(), user.ID, videoID, name)
+	if errors.Is(err, domain.ErrHlsNotGenerated) && name == domain.HlsMasterPlaylist && middleware.HasScope(r, domain.ScopeManage) {
+		status, err := h.hlsService.Generate(r.Context(), user.ID, videoID)
+		if err != nil {
+			h.respondHlsError(w, r, delivery.ErrGeneratingHls, err)
+			return
+		}
+
+		h.respondHlsStatus(w, status)
+		return
+	}
+	if err != nil {
+		h.respondHlsError(w, r, delivery.ErrGettingHlsFile, err)
+		return
+	}
+
+	delivery.RespondWithVideoStream(w, r, fileInfo)
+}
+
+func (h VideosHandler) respondHlsStatus(w http.ResponseWriter, status video_dto.HlsStatusDto) {
+	if status.Ready {
+		delivery.RespondWithJSON(w, http.StatusOK, status)
+		return
+	}
+
+	delivery.SetRetryAfter(w, h.retryAfter)
+	delivery.RespondWithJSON(w, http.StatusAccepted, status)
+}
+
+func (h VideosHandler) respondHlsError(w http.ResponseWriter, r *http.Request, errMessage string, err error) {
+	logger.FromContext(r.Context()).WithError(err).Error(errMessage)
+
+	if errors.Is(err, domain.ErrVideoNotFound) {
+		delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: errMessage, Message: domain.ErrVideoNotFound.Error()})
+		return
+	}
+
+	if errors.Is(err, domain.ErrFolderAccessDenied) {
+		delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: errMessage, Message: domain.ErrFolderAccessDenied.Error()})
+		return
+	}
+
+	if errors.Is(err, domain.ErrHlsNotGenerated) {
+		delivery.RespondWithJSON(w, http.StatusNotFound, delivery.JsonError{Error: errMessage, Message: domain.ErrHlsNotGenerated.Error()})
+		return
+	}
+
+	if errors.Is(err, domain.ErrHlsFileNotFound) {
+		delivery.RespondWithJSON(w, http.StatusNotFound, delivery.JsonError{Error: errMessage, Message: domain.ErrHlsFileNotFound.Error()})
+		return
+	}
+
+	if errors.Is(err, domain.ErrJobQueueFull) {
+		delivery.RespondTooManyRequests(w, h.retryAfter, delivery.JsonError{Error: errMessage, Message: domain.ErrJobQueueFull.Error()})
+		return
+	}
+
+	if errors.Is(err, domain.ErrTooManyJobs) {
+		delivery.RespondTooManyRequests(w, h.retryAfter, delivery.JsonError{Error: errMessage, Message: domain.ErrTooManyJobs.Error()})
+		return
+	}
+
+	if errors.Is(err, domain.ErrShuttingDown) {
+		delivery.RespondWithJSON(w, http.StatusServiceUnavailable, delivery.JsonError{Error: errMessage, Message: domain.ErrShuttingDown.Error()})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: errMessage})
+}
+
+// getPreview serves the preview image of the video.
+func (h VideosHandler) getPreview(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+	videoID := r.Context().Value(delivery.VideoIDInputKey).(primitive.ObjectID)
+
+	fileInfo, err := h.videosService.GetPreviewFileInfo(r.Context(), user.ID, videoID)
+	if err != nil {
+		logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrGettingPreview)
+
+		if errors.Is(err, domain.ErrVideoNotFound) {
+			delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrGettingPreview, Message: domain.ErrVideoNotFound.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrFolderAccessDenied) {
+			delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrGettingPreview, Message: domain.ErrFolderAccessDenied.Error()})
+			return
+		}
+
+		if errors.Is(err, domain.ErrPreviewNotFound) {
+			delivery.RespondWithJSON(w, http.StatusNotFound, delivery.JsonError{Error: delivery.ErrGettingPreview, Message: domain.ErrPreviewNotFound.Error()})
+			return
+		}
+
+		delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrGettingPreview})
+		return
+	}
+
+	delivery.RespondWithVideoStream(w, r, fileInfo)
+}
+
+// generateStoryboard queues the generation of the storyboard ahead of the
+// first scrub. It responds 200 when it is ready and 202 with the job
+// otherwise.
+func (h VideosHandler) generateStoryboard(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+	videoID := r.Context().Value(delivery.VideoIDInputKey).(primitive.ObjectID)
+
+	status, err := h.storyboardService.Generate(r.Context(), user.ID, videoID)
+	if err != nil {
+		h.respondStoryboardError(w, r, delivery.ErrGeneratingStoryboard, err)
+		return
+	}
+
+	h.respondStoryboardStatus(w, status)
+}
+
+// getStoryboardFile serves storyboard.vtt, the WebVTT thumbnails track of the
+// video, and storyboard.jpg, the sprite its cues point into. The first
+// request for storyboard.vtt of a video without a storyboard queues its
+// generation and responds 202 with the job and Retry-After, if the token may
+// start jobs, like POST /{video_id}/preview/storyboard.
+func (h VideosHandler) getStoryboardFile(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(delivery.UserKey).(domain.User)
+	videoID := r.Context().Value(delivery.VideoIDInputKey).(primitive.ObjectID)
+	name := chi.URLParam(r, "name")
+
+	fileInfo, err := h.storyboardService.GetFile(r.Context(), user.ID, videoID, name)
+	if errors.Is(err, domain.ErrStoryboardNotGenerated) && name == domain.StoryboardTrack && middleware.HasScope(r, domain.ScopeManage) {
+		status, err := h.storyboardService.Generate(r.Context(), user.ID, videoID)
+		if err != nil {
+			h.respondStoryboardError(w, r, delivery.ErrGeneratingStoryboard, err)
+			return
+		}
+
+		h.respondStoryboardStatus(w, status)
+		return
+	}
+	if err != nil {
+		h.respondStoryboardError(w, r, delivery.ErrGettingStoryboardFile, err)
+		return
+	}
+
+	delivery.RespondWithVideoStream(w, r, fileInfo)
+}
+
+func (h VideosHandler) respondStoryboardStatus(w http.ResponseWriter, status video_dto.StoryboardStatusDto) {
+	if status.Ready {
+		delivery.RespondWithJSON(w, http.StatusOK, status)
+		return
+	}
+
+	delivery.SetRetryAfter(w, h.retryAfter)
+	delivery.RespondWithJSON(w, http.StatusAccepted, status)
+}
+
+func (h VideosHandler) respondStoryboardError(w http.ResponseWriter, r *http.Request, errMessage string, err error) {
+	logger.FromContext(r.Context()).WithError(err).Error(errMessage)
+
+	if errors.Is(err, domain.ErrVideoNotFound) {
+		delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: errMessage, Message: domain.ErrVideoNotFound.Error()})
+		return
+	}
+
+	if errors.Is(err, domain.ErrFolderAccessDenied) {
+		delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: errMessage, Message: domain.ErrFolderAccessDenied.Error()})
+		return
+	}
+
+	if errors.Is(err, domain.ErrStoryboardNotGenerated) {
+		delivery.RespondWithJSON(w, http.StatusNotFound, delivery.JsonError{Error: errMessage, Message: domain.ErrStoryboardNotGenerated.Error()})
+		return
+	}
+
+	if errors.Is(err, domain.ErrStoryboardFileNotFound) {
+		delivery.RespondWithJSON(w, http.StatusNotFound, delivery.JsonError{Error: errMessage, Message: domain.ErrStoryboardFileNotFound.Error()})
+		return
+	}
+
+	if errors.Is(err, domain.ErrJobQueueFull) {
+		delivery.RespondTooManyRequests(w, h.retryAfter, delivery.JsonError{Error: errMessage, Message: domain.ErrJobQueueFull.Error()})
+		return
+	}
+
+	if errors.Is(err, domain.ErrTooManyJobs) {
+		delivery.RespondTooManyRequests(w, h.retryAfter, delivery.JsonError{Error: errMessage, Message: domain.ErrTooManyJobs.Error()})
+		return
+	}
+
+	if errors.Is(err, domain.ErrShuttingDown) {
+		delivery.RespondWithJSON(w, http.StatusServiceUnavailable, delivery.JsonError{Error: errMessage, Message: domain.ErrShuttingDown.Error()})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: errMessage})
+}
+
+// openShareLink streams the shared video, or downloads it with ?download=true.
+// A password protected link takes the password in the X-Share-Password header
+// or the password field of a posted form, never in the URL, where it would end
+// up in logs, browser history and Referer headers.
+//
+// Every stream request that starts a view is counted and gets the grant of the
+// view in a cookie, the range requests of a player seeking in it are then
+// served without counting them again. A download is always a new view.
+func (h VideosHandler) openShareLink(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+	password := r.Header.Get(sharePasswordHeader)
+	if r.Method == http.MethodPost {
+		password = r.PostFormValue(sharePasswordField)
+	}
+	download := r.URL.Query().Get("download") == "true"
+
+	grant := ""
+	if cookie, err := r.Cookie(shareViewCookie); err == nil && !download {
+		grant = cookie.Value
+	}
+
+	videoInfo, newGrant, err := h.shareLinksService.GetVideoFileInfo(r.Context(), token, password, grant, download || r.Method != http.MethodHead)
+	if err != nil {
+		h.respondShareLinkError(w, r, err)
+		return
+	}
+
+	if download {
+		delivery.RespondWithVideo(w, videoInfo)
+		return
+	}
+
+	if newGrant != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     shareViewCookie,
+			Value:    newGrant,
+			Path:     "/s/" + token,
+			MaxAge:   int(domain.ShareViewGrantTTL.Seconds()),
+			HttpOnly: true,
+			Secure:   h.cookieSecure,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	delivery.RespondWithVideoStream(w, r, videoInfo)
+}
+
+func (h VideosHandler) respondShareLinkError(w http.ResponseWriter, r *http.Request, err error) {
+	logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrOpeningShareLink)
+
+	if errors.Is(err, domain.ErrShareLinkNotFound) || errors.Is(err, domain.ErrVideoNotFound) {
+		delivery.RespondWithJSON(w, http.StatusNotFound, delivery.JsonError{Error: delivery.ErrOpeningShareLink, Message: domain.ErrShareLinkNotFound.Error()})
+		return
+	}
+
+	if errors.Is(err, domain.ErrShareLinkUsedUp) {
+		delivery.RespondWithJSON(w, http.StatusGone, delivery.JsonError{Error: delivery.ErrOpeningShareLink, Message: domain.ErrShareLinkUsedUp.Error()})
+		return
+	}
+
+	if errors.Is(err, domain.ErrInvalidSharePassword) {
+		delivery.RespondWithJSON(w, http.StatusUnauthorized, delivery.JsonError{Error: delivery.ErrOpeningShareLink, Message: domain.ErrInvalidSharePassword.Error()})
+		return
+	}
+
+	delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrOpeningShareLink})
+}
@@ -0,0 +1,263 @@
+package videos_handler
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"video-downloader-server/internal/delivery"
+	"video-downloader-server/internal/delivery/dto/job_dto"
+	"video-downloader-server/internal/delivery/dto/video_dto"
+	"video-downloader-server/internal/delivery/middleware"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/repository"
+	"video-downloader-server/internal/service/share_links_service"
+	"video-downloader-server/internal/validator"
+)
+
+// fakeVideos authorizes everyone and opens the videos from disk.
+type fakeVideos struct {
+	repo *repository.VideosMemoryRepo
+}
+
+func (f fakeVideos) Authorize(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, role string) (domain.Video, error) {
+	return f.repo.Lookup(ctx, videoID)
+}
+
+func (f fakeVideos) OpenVideo(ctx context.Context, video domain.Video) (video_dto.VideoFileInfoDto, error) {
+	file, err := os.Open(video.RealPath)
+	if err != nil {
+		return video_dto.VideoFileInfoDto{}, err
+	}
+
+	return video_dto.VideoFileInfoDto{
+		VideoName:   video.VideoName,
+		FileSize:    int64(len("video")),
+		ModTime:     time.Now(),
+		ContentType: domain.VideoContentType(video.VideoName),
+		VideoFile:   file,
+	}, nil
+}
+
+// fakeTranscodes queues every transcode and counts them.
+type fakeTranscodes struct {
+	VideosService
+	queued int
+}
+
+func (f *fakeTranscodes) Transcode(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, transcodeVideoInput video_dto.TranscodeVideoDto) (job_dto.JobDto, error) {
+	f.queued++
+	return job_dto.JobDto{}, nil
+}
+
+// newShareLink serves the public routes of a handler sharing a video with
+// createShareLinkInput behind middlewares and returns the path of the link.
+func newShareLink(t *testing.T, createShareLinkInput video_dto.CreateShareLinkDto, middlewares ...func(http.Handler) http.Handler) (http.Handler, string) {
+	t.Helper()
+	ctx := context.Background()
+
+	realPath := filepath.Join(t.TempDir(), "a.mp4")
+	if err := os.WriteFile(realPath, []byte("video"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	videosRepo := repository.NewVideosMemoryRepo()
+	videoID, err := videosRepo.Create(ctx, domain.Video{OwnerID: primitive.NewObjectID(), VideoName: "a.mp4", FolderID: primitive.NewObjectID(), RealPath: realPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shareLinksService := share_links_service.NewShareLinksService(repository.NewShareLinksMemoryRepo(), videosRepo, fakeVideos{videosRepo}, 4)
+	link, err := shareLinksService.Create(ctx, primitive.NewObjectID(), videoID, createShareLinkInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Group(func(r chi.Router) {
+		r.Use(middlewares...)
+		NewVideosHandler(nil, shareLinksService, nil, nil, nil, nil, time.Second, false).RegisterPublicRoutes(r)
+	})
+
+	return r, "/s/" + link.Token
+}
+
+func serve(h http.Handler, method, path, rangeHeader string) int {
+	code, _ := view(h, method, path, rangeHeader, "")
+	return code
+}
+
+// view serves a request sending grant as the view cookie and returns the
+// status and the grant of the view, if a new one was set.
+func view(h http.Handler, method, path, rangeHeader, grant string) (int, string) {
+	req := httptest.NewRequest(method, path, nil)
+	if rangeHeader != "" {
+		req.Header.Set("Range", rangeHeader)
+	}
+	if grant != "" {
+		req.AddCookie(&http.Cookie{Name: shareViewCookie, Value: grant})
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == shareViewCookie {
+			return rec.Code, cookie.Value
+		}
+	}
+
+	return rec.Code, ""
+}
+
+func serveRequest(h http.Handler, req *http.Request) int {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	return rec.Code
+}
+
+func TestOpenShareLinkMaxViews(t *testing.T) {
+	h, path := newShareLink(t, video_dto.CreateShareLinkDto{MaxViews: 1})
+
+	// a view may start anywhere in the video, it is counted all the same
+	code, grant := view(h, http.MethodGet, path, "bytes=1-", "")
+	if code != http.StatusPartialContent || grant == "" {
+		t.Fatalf("first view: got %d with grant %q, want %d with a grant", code, grant, http.StatusPartialContent)
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		rangeHeader string
+		grant       string
+		want        int
+	}{
+		{"seeking in the view", http.MethodGet, path, "bytes=3-", grant, http.StatusPartialContent},
+		{"restarting the view", http.MethodGet, path, "bytes=0-", grant, http.StatusPartialContent},
+		{"reloading the view", http.MethodGet, path, "", grant, http.StatusOK},
+		{"full request", http.MethodGet, path, "", "", http.StatusGone},
+		{"range from the first byte", http.MethodGet, path, "bytes=0-", "", http.StatusGone},
+		{"range from a later byte", http.MethodGet, path, "bytes=1-", "", http.StatusGone},
+		{"head", http.MethodHead, path, "", "", http.StatusGone},
+		{"unknown grant", http.MethodGet, path, "bytes=1-", "unknown", http.StatusGone},
+		{"download with the grant", http.MethodGet, path + "?download=true", "", grant, http.StatusGone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _ := view(h, tt.method, tt.path, tt.rangeHeader, tt.grant); code != tt.want {
+				t.Errorf("got %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenShareLinkGrantPerLink(t *testing.T) {
+	h, path := newShareLink(t, video_dto.CreateShareLinkDto{MaxViews: 1})
+	other, otherPath := newShareLink(t, video_dto.CreateShareLinkDto{MaxViews: 1})
+
+	_, grant := view(other, http.MethodGet, otherPath, "", "")
+
+	if code, _ := view(h, http.MethodGet, path, "", grant); code != http.StatusOK {
+		t.Fatalf("first view: got %d, want %d", code, http.StatusOK)
+	}
+
+	// the grant of the other link does not cover a second view of this one
+	if code, _ := view(h, http.MethodGet, path, "bytes=1-", grant); code != http.StatusGone {
+		t.Errorf("second view: got %d, want %d", code, http.StatusGone)
+	}
+}
+
+func TestOpenShareLinkPassword(t *testing.T) {
+	h, path := newShareLink(t, video_dto.CreateShareLinkDto{Password: "secret"})
+
+	tests := []struct {
+		name string
+		req  func() *http.Request
+		want int
+	}{
+		{"no password", func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, path, nil)
+		}, http.StatusUnauthorized},
+		{"password in the query", func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, path+"?password=secret", nil)
+		}, http.StatusUnauthorized},
+		{"wrong header", func() *http.Request {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set(sharePasswordHeader, "wrong")
+			return req
+		}, http.StatusUnauthorized},
+		{"header", func() *http.Request {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set(sharePasswordHeader, "secret")
+			return req
+		}, http.StatusOK},
+		{"posted form", func() *http.Request {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(url.Values{sharePasswordField: {"secret"}}.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			return req
+		}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := serveRequest(h, tt.req()); code != tt.want {
+				t.Errorf("got %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenShareLinkRateLimit(t *testing.T) {
+	h, path := newShareLink(t, video_dto.CreateShareLinkDto{Password: "secret"}, middleware.RateLimitShareLinks(middleware.NewRateLimiter(2, time.Hour), false))
+
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		if code := serve(h, http.MethodGet, path, ""); code != want {
+			t.Fatalf("request %d: got %d, want %d", i+1, code, want)
+		}
+	}
+
+	if code := serve(h, http.MethodGet, "/s/other", ""); code != http.StatusNotFound {
+		t.Errorf("other token: got %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestTranscodeScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		want   int
+	}{
+		{"download token", []string{domain.ScopeDownload, domain.ScopeRead}, http.StatusForbidden},
+		{"manage token", []string{domain.ScopeManage}, http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			videos := &fakeTranscodes{}
+			r := chi.NewRouter()
+			NewVideosHandler(videos, nil, nil, nil, nil, validator.Init(), time.Second, false).RegisterRoutes(r)
+
+			req := httptest.NewRequest(http.MethodPost, "/videos/"+primitive.NewObjectID().Hex()+"/transcode", strings.NewReader(`{"profile":"h264-720p"}`))
+			ctx := context.WithValue(req.Context(), delivery.UserKey, domain.User{ID: primitive.NewObjectID()})
+			ctx = context.WithValue(ctx, delivery.ScopesKey, tt.scopes)
+
+			if code := serveRequest(r, req.WithContext(ctx)); code != tt.want {
+				t.Fatalf("got %d, want %d", code, tt.want)
+			}
+
+			if queued := tt.want == http.StatusAccepted; (videos.queued == 1) != queued {
+				t.Errorf("queued %d transcodes", videos.queued)
+			}
+		})
+	}
+}
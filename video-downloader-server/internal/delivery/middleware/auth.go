@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"slices"
+	"strings"
+	"video-downloader-server/internal/delivery"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/logger"
+)
+
+const (
+	SessionCookie = "session"
+
+	bearerPrefix = "Bearer "
+)
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (domain.User, []string, error)
+}
+
+// Authenticate rejects requests without a valid session or API token, taken
+// from the Authorization bearer header or else the session cookie, and stores
+// the user and the scopes granted to the token in the context.
+func Authenticate(auth Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := SessionToken(r)
+			if token == "" {
+				logger.FromContext(r.Context()).WithError(domain.ErrUnauthorized).Warn(delivery.ErrAuthenticating)
+				delivery.RespondWithJSON(w, http.StatusUnauthorized, delivery.JsonError{Error: delivery.ErrAuthenticating, Message: domain.ErrUnauthorized.Error()})
+				return
+			}
+
+			user, scopes, err := auth.Authenticate(r.Context(), token)
+			if err != nil {
+				if errors.Is(err, domain.ErrUnauthorized) {
+					logger.FromContext(r.Context()).WithError(err).Warn(delivery.ErrAuthenticating)
+					delivery.RespondWithJSON(w, http.StatusUnauthorized, delivery.JsonError{Error: delivery.ErrAuthenticating, Message: domain.ErrUnauthorized.Error()})
+					return
+				}
+
+				logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrAuthenticating)
+				delivery.RespondWithJSON(w, http.StatusInternalServerError, delivery.JsonError{Error: delivery.ErrAuthenticating})
+				return
+			}
+
+			ctx := logger.WithFields(r.Context(), log.Fields{logger.UserIDField: user.ID.Hex()})
+			ctx = context.WithValue(ctx, delivery.UserKey, user)
+			ctx = context.WithValue(ctx, delivery.ScopesKey, scopes)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireRole lets only users with role through. It must run after Authenticate.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := r.Context().Value(delivery.UserKey).(domain.User)
+			if user.Role != role {
+				logger.FromContext(r.Context()).WithError(domain.ErrForbidden).Warn(delivery.ErrAuthorizing)
+				delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrAuthorizing, Message: domain.ErrForbidden.Error()})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireScope lets only tokens granted scope through. It must run after
+// Authenticate.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasScope(r, scope) {
+				logger.FromContext(r.Context()).WithError(domain.ErrMissingScope).WithField("scope", scope).Warn(delivery.ErrAuthorizing)
+				delivery.RespondWithJSON(w, http.StatusForbidden, delivery.JsonError{Error: delivery.ErrAuthorizing, Message: domain.ErrMissingScope.Error()})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HasScope reports whether the token of the request was granted scope. It
+// must run after Authenticate.
+func HasScope(r *http.Request, scope string) bool {
+	scopes, _ := r.Context().Value(delivery.ScopesKey).([]string)
+	return slices.Contains(scopes, scope)
+}
+
+// SessionToken returns the token of the request, or an empty string.
+func SessionToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, bearerPrefix) {
+		return strings.TrimSpace(strings.TrimPrefix(header, bearerPrefix))
+	}
+
+	if cookie, err := r.Cookie(SessionCookie); err == nil {
+		return cookie.Value
+	}
+
+	return ""
+}
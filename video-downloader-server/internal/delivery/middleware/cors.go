@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"github.com/go-chi/cors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	corsAllowedHeaders = []string{"Accept", "Authorization", "Content-Type", "If-Match", "If-Modified-Since", "If-None-Match", "If-Range", "Range", RequestIDHeader}
+	corsExposedHeaders = []string{"Accept-Ranges", "Content-Disposition", "Content-Length", "Content-Range", "ETag", "Last-Modified", "Retry-After", RequestIDHeader}
+)
+
+// CorsRoutes maps the first segment of a path, e.g. "/videos", to the methods
+// its routes accept from other origins.
+type CorsRoutes map[string][]string
+
+// Cors answers preflight requests and adds the CORS headers for requests from
+// origins, allowing the methods of the route group of the request path. Paths
+// outside every group, and every path when origins is empty, get no CORS
+// headers. It must run before Authenticate since preflight requests carry no
+// credentials.
+func Cors(origins []string, allowCredentials bool, maxAge time.Duration, routes CorsRoutes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(origins) == 0 {
+			return next
+		}
+
+		handlers := make(map[string]http.Handler, len(routes))
+		for group, methods := range routes {
+			handlers[group] = cors.New(cors.Options{
+				AllowedOrigins:   origins,
+				AllowedMethods:   methods,
+				AllowedHeaders:   corsAllowedHeaders,
+				ExposedHeaders:   corsExposedHeaders,
+				AllowCredentials: allowCredentials,
+				MaxAge:           int(maxAge.Seconds()),
+			}).Handler(next)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if handler, ok := handlers[routeGroup(r.URL.Path)]; ok {
+				handler.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routeGroup returns the first segment of path with its leading slash.
+func routeGroup(path string) string {
+	group, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return "/" + group
+}
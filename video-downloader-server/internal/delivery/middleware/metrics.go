@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"net/http"
+	"strconv"
+	"time"
+	"video-downloader-server/internal/metrics"
+)
+
+const unmatchedRoute = "unmatched"
+
+// Metrics records the count and latency of requests per chi route pattern,
+// so that path and query parameters do not blow up the label cardinality.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := unmatchedRoute
+		if routeCtx := chi.RouteContext(r.Context()); routeCtx != nil && routeCtx.RoutePattern() != "" {
+			route = routeCtx.RoutePattern()
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		metrics.HttpRequests.WithLabelValues(route, r.Method, strconv.Itoa(status)).Inc()
+		metrics.HttpRequestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
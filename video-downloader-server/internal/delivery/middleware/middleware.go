@@ -3,40 +3,40 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
-	log "github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"net/http"
 	"video-downloader-server/internal/delivery"
+	"video-downloader-server/internal/delivery/dto/auth_dto"
 	"video-downloader-server/internal/delivery/dto/folder_dto"
 	"video-downloader-server/internal/delivery/dto/video_dto"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/logger"
 )
 
-//func ApplyCors(extensionURL string) func(next http.Handler) http.Handler {
-//	return cors.Handler(cors.Options{
-//		AllowedOrigins:     []string{extensionURL},
-//		AllowCredentials:   true,
-//		AllowedMethods:     []string{"POST", "OPTIONS"},
-//		AllowedHeaders:     []string{"Origin", "Content-Type", "Accept"},
-//		OptionsPassthrough: true,
-//	})
-//}
+// maxFormOverhead is the room left in a multipart form for the boundaries,
+// the part headers and the fields next to the uploaded file.
+const maxFormOverhead = 64 << 10
 
 type ValidatableDto interface {
-	video_dto.DownloadVideoDto | video_dto.RenameVideoDto | video_dto.MoveVideoDto | video_dto.DeleteVideoDto | folder_dto.CreateFolderDto | folder_dto.RenameFolderDto | folder_dto.MoveFolderDto | folder_dto.DeleteFolderDto
+	video_dto.DownloadVideoDto | video_dto.RenameVideoDto | video_dto.MoveVideoDto | video_dto.DeleteVideoDto | video_dto.CopyVideoDto | video_dto.CreateShareLinkDto | video_dto.TranscodeVideoDto | video_dto.SplitVideoDto | folder_dto.CreateFolderDto | folder_dto.RenameFolderDto | folder_dto.MoveFolderDto | folder_dto.DeleteFolderDto | folder_dto.GrantAccessDto | folder_dto.RevokeAccessDto | auth_dto.RegisterDto | auth_dto.LoginDto | auth_dto.CreateUserDto | auth_dto.CreateTokenDto
 }
 
 func validateInput[V ValidatableDto](validate *validator.Validate, input V, ctxKey delivery.ContextKey, errInvalidInput, errMessage string) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// decode into a copy, input is shared by every request of the route
+			input := input
 			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-				log.WithError(err).Error(errInvalidInput)
+				logger.FromContext(r.Context()).WithError(err).Error(errInvalidInput)
 				delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: errInvalidInput, Message: delivery.MesInvalidJSON})
 				return
 			}
 
 			if err := validate.Struct(input); err != nil {
-				log.WithError(err).Error(errInvalidInput)
+				logger.FromContext(r.Context()).WithError(err).Error(errInvalidInput)
 				delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: errInvalidInput, Message: errMessage})
 				return
 			}
@@ -51,6 +51,14 @@ func ValidateDownloadVideoInput(v *validator.Validate) func(next http.Handler) h
 	return validateInput(v, video_dto.DownloadVideoDto{}, delivery.DownloadVideoInputKey, delivery.ErrInvalidDownloadVideoInput, delivery.MesInvalidDownloadVideoInput)
 }
 
+func ValidateTranscodeVideoInput(v *validator.Validate) func(next http.Handler) http.Handler {
+	return validateInput(v, video_dto.TranscodeVideoDto{}, delivery.TranscodeVideoInputKey, delivery.ErrInvalidTranscodeVideoInput, delivery.MesInvalidTranscodeVideoInput)
+}
+
+func ValidateSplitVideoInput(v *validator.Validate) func(next http.Handler) http.Handler {
+	return validateInput(v, video_dto.SplitVideoDto{}, delivery.SplitVideoInputKey, delivery.ErrInvalidSplitVideoInput, delivery.MesInvalidSplitVideoInput)
+}
+
 func ValidateRenameVideoInput(v *validator.Validate) func(next http.Handler) http.Handler {
 	return validateInput(v, video_dto.RenameVideoDto{}, delivery.RenameVideoInputKey, delivery.ErrInvalidRenameVideoInput, delivery.MesInvalidRenameVideoInput)
 }
@@ -63,6 +71,53 @@ func ValidateDeleteVideoInput(v *validator.Validate) func(next http.Handler) htt
 	return validateInput(v, video_dto.DeleteVideoDto{}, delivery.DeleteVideoInputKey, delivery.ErrInvalidDeleteVideoInput, delivery.MesInvalidDeleteVideoInput)
 }
 
+func ValidateCopyVideoInput(v *validator.Validate) func(next http.Handler) http.Handler {
+	return validateInput(v, video_dto.CopyVideoDto{}, delivery.CopyVideoInputKey, delivery.ErrInvalidCopyVideoInput, delivery.MesInvalidCopyVideoInput)
+}
+
+func ValidateCreateShareLinkInput(v *validator.Validate) func(next http.Handler) http.Handler {
+	return validateInput(v, video_dto.CreateShareLinkDto{}, delivery.CreateShareLinkInputKey, delivery.ErrInvalidCreateShareLinkInput, delivery.MesInvalidCreateShareLinkInput)
+}
+
+// ValidateUploadSubtitleInput parses the multipart form of a subtitle upload
+// and validates its fields. The file is left in the form for the handler and
+// the temporary files of the form are removed once the handler returns.
+func ValidateUploadSubtitleInput(v *validator.Validate) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Body = http.MaxBytesReader(w, r.Body, domain.MaxSubtitleSize+maxFormOverhead)
+			if err := r.ParseMultipartForm(domain.MaxSubtitleSize); err != nil {
+				logger.FromContext(r.Context()).WithError(err).Error(delivery.ErrInvalidUploadSubtitleInput)
+
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					delivery.RespondWithJSON(w, http.StatusRequestEntityTooLarge, delivery.JsonError{Error: delivery.ErrInvalidUploadSubtitleInput, Message: delivery.MesSubtitleTooLarge})
+					return
+				}
+
+				delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrInvalidUploadSubtitleInput, Message: delivery.MesInvalidMultipartForm})
+				return
+			}
+			defer r.MultipartForm.RemoveAll()
+
+			input := video_dto.UploadSubtitleDto{
+				Lang:  r.FormValue("lang"),
+				Label: r.FormValue("label"),
+			}
+
+			_, _, fileErr := r.FormFile("file")
+			if err := v.Struct(input); err != nil || fileErr != nil {
+				logger.FromContext(r.Context()).WithError(errors.Join(err, fileErr)).Error(delivery.ErrInvalidUploadSubtitleInput)
+				delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: delivery.ErrInvalidUploadSubtitleInput, Message: delivery.MesInvalidUploadSubtitleInput})
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), delivery.UploadSubtitleInputKey, input)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
 func ValidateCreateFolderInput(v *validator.Validate) func(next http.Handler) http.Handler {
 	return validateInput(v, folder_dto.CreateFolderDto{}, delivery.CreateFolderInputKey, delivery.ErrInvalidCreateFolderInput, delivery.MesInvalidCreateFolderInput)
 }
@@ -79,19 +134,48 @@ func ValidateDeleteFolderInput(v *validator.Validate) func(http.Handler) http.Ha
 	return validateInput(v, folder_dto.DeleteFolderDto{}, delivery.DeleteFolderInputKey, delivery.ErrInvalidDeleteFolderInput, delivery.MesInvalidDeleteFolderInput)
 }
 
-func validateIDInput(paramName string, ctxKey delivery.ContextKey, errInvalidInput, errMessage string) func(http.Handler) http.Handler {
+func ValidateGrantAccessInput(v *validator.Validate) func(http.Handler) http.Handler {
+	return validateInput(v, folder_dto.GrantAccessDto{}, delivery.GrantAccessInputKey, delivery.ErrInvalidGrantAccessInput, delivery.MesInvalidGrantAccessInput)
+}
+
+func ValidateRevokeAccessInput(v *validator.Validate) func(http.Handler) http.Handler {
+	return validateInput(v, folder_dto.RevokeAccessDto{}, delivery.RevokeAccessInputKey, delivery.ErrInvalidRevokeAccessInput, delivery.MesInvalidRevokeAccessInput)
+}
+
+func ValidateRegisterInput(v *validator.Validate) func(http.Handler) http.Handler {
+	return validateInput(v, auth_dto.RegisterDto{}, delivery.RegisterInputKey, delivery.ErrInvalidRegisterInput, delivery.MesInvalidRegisterInput)
+}
+
+func ValidateLoginInput(v *validator.Validate) func(http.Handler) http.Handler {
+	return validateInput(v, auth_dto.LoginDto{}, delivery.LoginInputKey, delivery.ErrInvalidLoginInput, delivery.MesInvalidLoginInput)
+}
+
+func ValidateCreateUserInput(v *validator.Validate) func(http.Handler) http.Handler {
+	return validateInput(v, auth_dto.CreateUserDto{}, delivery.CreateUserInputKey, delivery.ErrInvalidCreateUserInput, delivery.MesInvalidCreateUserInput)
+}
+
+func ValidateCreateTokenInput(v *validator.Validate) func(http.Handler) http.Handler {
+	return validateInput(v, auth_dto.CreateTokenDto{}, delivery.CreateTokenInputKey, delivery.ErrInvalidCreateTokenInput, delivery.MesInvalidCreateTokenInput)
+}
+
+// queryParam and chi.URLParam are the sources validateIDInput reads ids from.
+func queryParam(r *http.Request, key string) string {
+	return r.URL.Query().Get(key)
+}
+
+func validateIDInput(param func(r *http.Request, key string) string, paramName string, ctxKey delivery.ContextKey, errInvalidInput, errMessage string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			paramValueStr := r.URL.Query().Get(paramName)
+			paramValueStr := param(r, paramName)
 			if paramValueStr == "" {
-				log.Error(errInvalidInput)
+				logger.FromContext(r.Context()).Error(errInvalidInput)
 				delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: errInvalidInput, Message: delivery.ErrEmptyIDParam})
 				return
 			}
 
 			paramValueID, err := primitive.ObjectIDFromHex(paramValueStr)
 			if err != nil {
-				log.Error(errInvalidInput)
+				logger.FromContext(r.Context()).Error(errInvalidInput)
 				delivery.RespondWithJSON(w, http.StatusBadRequest, delivery.JsonError{Error: errInvalidInput, Message: errMessage})
 				return
 			}
@@ -103,9 +187,26 @@ func validateIDInput(paramName string, ctxKey delivery.ContextKey, errInvalidInp
 }
 
 func ValidateVideoIDInput(next http.Handler) http.Handler {
-	return validateIDInput("video_id", delivery.VideoIDInputKey, delivery.ErrInvalidVideoIDInput, delivery.MesInvalidVideoIDInput)(next)
+	return validateIDInput(queryParam, "video_id", delivery.VideoIDInputKey, delivery.ErrInvalidVideoIDInput, delivery.MesInvalidVideoIDInput)(next)
 }
 
 func ValidateFolderIDInput(next http.Handler) http.Handler {
-	return validateIDInput("folder_id", delivery.FolderIDInputKey, delivery.ErrInvalidFolderIDInput, delivery.MesInvalidFolderIDInput)(next)
+	return validateIDInput(queryParam, "folder_id", delivery.FolderIDInputKey, delivery.ErrInvalidFolderIDInput, delivery.MesInvalidFolderIDInput)(next)
+}
+
+func ValidateJobIDInput(next http.Handler) http.Handler {
+	return validateIDInput(queryParam, "job_id", delivery.JobIDInputKey, delivery.ErrInvalidJobIDInput, delivery.MesInvalidJobIDInput)(next)
+}
+
+func ValidateTokenIDInput(next http.Handler) http.Handler {
+	return validateIDInput(queryParam, "token_id", delivery.TokenIDInputKey, delivery.ErrInvalidTokenIDInput, delivery.MesInvalidTokenIDInput)(next)
+}
+
+// ValidateVideoIDParam reads the video id from the {video_id} path segment.
+func ValidateVideoIDParam(next http.Handler) http.Handler {
+	return validateIDInput(chi.URLParam, "video_id", delivery.VideoIDInputKey, delivery.ErrInvalidVideoIDInput, delivery.MesInvalidVideoIDInput)(next)
+}
+
+func ValidateShareLinkIDParam(next http.Handler) http.Handler {
+	return validateIDInput(chi.URLParam, "link_id", delivery.ShareLinkIDInputKey, delivery.ErrInvalidShareLinkIDInput, delivery.MesInvalidShareLinkIDInput)(next)
 }
@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"github.com/go-chi/chi/v5"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"video-downloader-server/internal/delivery"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/logger"
+	"video-downloader-server/internal/metrics"
+)
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// RateLimiter is a token bucket per client. A bucket holds up to requests
+// tokens and refills at requests per window, so a client can burst the whole
+// window's allowance and is then throttled to the average rate.
+type RateLimiter struct {
+	requests float64
+	window   time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// NewRateLimiter allows requests per window to every client, requests 0
+// disables the limit.
+func NewRateLimiter(requests int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		requests: float64(requests),
+		window:   window,
+		buckets:  make(map[string]*bucket),
+		swept:    time.Now(),
+	}
+}
+
+// Allow takes a token from the bucket of key. When there is none it returns
+// false and how long until the next one.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	if l.requests == 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.requests, updated: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = min(l.requests, b.tokens+now.Sub(b.updated).Seconds()*l.rate())
+	b.updated = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate() * float64(time.Second))
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// rate is the number of tokens added per second.
+func (l *RateLimiter) rate() float64 {
+	return l.requests / l.window.Seconds()
+}
+
+// sweep forgets the buckets that have refilled completely once per window,
+// l.mu must be held.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < l.window {
+		return
+	}
+
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= l.window {
+			delete(l.buckets, key)
+		}
+	}
+	l.swept = now
+}
+
+// RateLimit rejects POST, PUT and DELETE requests of clients that have used
+// up their allowance with 429 and Retry-After. Authenticated clients are
+// keyed by their user, whichever session or token they use, so it must run
+// after Authenticate on routes that require it; other clients are keyed by
+// IP, taken from X-Forwarded-For when trustProxy is set.
+func RateLimit(limiter *RateLimiter, trustProxy bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodPost, http.MethodPut, http.MethodDelete:
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := "ip:" + clientIP(r, trustProxy)
+			if user, ok := r.Context().Value(delivery.UserKey).(domain.User); ok {
+				key = "user:" + user.ID.Hex()
+			}
+
+			if allow(w, r, limiter, key) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// RateLimitShareLinks is RateLimit for the public share link routes. Every
+// request may try a link password, so all methods count, and clients are
+// keyed by IP and link token. It reads the {token} URL param, so it must be
+// used on the routes, not before routing.
+func RateLimitShareLinks(limiter *RateLimiter, trustProxy bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := "share:" + clientIP(r, trustProxy) + ":" + chi.URLParam(r, "token")
+
+			if allow(w, r, limiter, key) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// allow takes a token for key and responds with 429 when there is none.
+func allow(w http.ResponseWriter, r *http.Request, limiter *RateLimiter, key string) bool {
+	ok, retryAfter := limiter.Allow(key)
+	if !ok {
+		metrics.RateLimitedRequests.Inc()
+		logger.FromContext(r.Context()).WithError(domain.ErrTooManyRequests).Warn(delivery.ErrRateLimiting)
+		delivery.RespondTooManyRequests(w, retryAfter, delivery.JsonError{Error: delivery.ErrRateLimiting, Message: domain.ErrTooManyRequests.Error()})
+	}
+
+	return ok
+}
+
+// clientIP returns the left-most X-Forwarded-For address when trustProxy is
+// set and the header is present, and the address of the peer otherwise.
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			ip, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(ip)
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	tests := []struct {
+		name     string
+		requests int
+		window   time.Duration
+		keys     []string
+		want     []bool
+	}{
+		{"disabled", 0, time.Hour, []string{"a", "a", "a"}, []bool{true, true, true}},
+		{"burst up to the allowance", 2, time.Hour, []string{"a", "a", "a"}, []bool{true, true, false}},
+		{"keys have their own buckets", 1, time.Hour, []string{"a", "b", "a", "b"}, []bool{true, true, false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewRateLimiter(tt.requests, tt.window)
+
+			for i, key := range tt.keys {
+				ok, retryAfter := limiter.Allow(key)
+				if ok != tt.want[i] {
+					t.Fatalf("request %d for %q: got %t, want %t", i+1, key, ok, tt.want[i])
+				}
+
+				if ok && retryAfter != 0 || !ok && (retryAfter <= 0 || retryAfter > tt.window) {
+					t.Errorf("request %d for %q: retry after %s", i+1, key, retryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	limiter := NewRateLimiter(1, 50*time.Millisecond)
+
+	if ok, _ := limiter.Allow("a"); !ok {
+		t.Fatal("first request denied")
+	}
+
+	ok, retryAfter := limiter.Allow("a")
+	if ok {
+		t.Fatal("second request allowed")
+	}
+
+	time.Sleep(retryAfter)
+
+	if ok, _ := limiter.Allow("a"); !ok {
+		t.Fatal("request after retry after denied")
+	}
+}
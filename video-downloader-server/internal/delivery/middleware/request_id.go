@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"regexp"
+	"time"
+	"video-downloader-server/internal/delivery"
+	"video-downloader-server/internal/logger"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID propagates a valid X-Request-ID or assigns a new one, echoes it in
+// the response, stores a logger with the request fields in the context and
+// logs every completed request.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = primitive.NewObjectID().Hex()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		entry := logger.FromContext(r.Context()).WithFields(log.Fields{
+			logger.RequestIDField: requestID,
+			"method":              r.Method,
+			"path":                r.URL.Path,
+		})
+
+		start := time.Now()
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r.WithContext(logger.WithContext(r.Context(), entry)))
+
+		entry.WithFields(log.Fields{
+			"status":   ww.Status(),
+			"bytes":    ww.BytesWritten(),
+			"duration": time.Since(start).String(),
+		}).Info(delivery.RequestCompleted)
+	})
+}
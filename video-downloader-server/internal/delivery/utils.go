@@ -4,8 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 	"video-downloader-server/internal/delivery/dto/video_dto"
+	"video-downloader-server/internal/metrics"
 )
 
 func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
@@ -16,18 +20,41 @@ func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.Write(data)
 }
 
-func RespondWithVideoRange(w http.ResponseWriter, info video_dto.VideoRangeInfoDto) {
-	defer info.VideoInfo.VideoFile.Close()
+// RespondTooManyRequests responds with 429 and tells the client to retry
+// after retryAfter.
+func RespondTooManyRequests(w http.ResponseWriter, retryAfter time.Duration, payload interface{}) {
+	SetRetryAfter(w, retryAfter)
+	RespondWithJSON(w, http.StatusTooManyRequests, payload)
+}
 
-	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", info.RangeStart, info.RangeEnd, info.VideoInfo.FileSize))
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", info.RangeEnd-info.RangeStart+1))
-	w.Header().Set("Content-Type", "video/mp4")
-	w.WriteHeader(http.StatusPartialContent)
+// SetRetryAfter sets the Retry-After header to retryAfter rounded up to whole
+// seconds.
+func SetRetryAfter(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(retryAfter.Seconds())))))
+}
 
-	info.VideoInfo.VideoFile.Seek(info.RangeStart, 0)
-	if _, err := io.CopyN(w, info.VideoInfo.VideoFile, info.RangeEnd-info.RangeStart+1); err != nil {
-		RespondWithJSON(w, http.StatusInternalServerError, ErrGettingVideoRange)
-	}
+// RespondWithVideoStream serves the video with http.ServeContent, which
+// answers full and single or multipart range requests, HEAD and the
+// If-Match, If-None-Match, If-Modified-Since and If-Range conditions. The
+// ETag is derived from the modification time and size of the file.
+func RespondWithVideoStream(w http.ResponseWriter, r *http.Request, info video_dto.VideoFileInfoDto) {
+	defer info.VideoFile.Close()
+
+	w.Header().Set("Content-Type", info.ContentType)
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime.UnixNano(), info.FileSize))
+
+	http.ServeContent(w, r, info.VideoName, info.ModTime, streamCounter{info.VideoFile})
+}
+
+// streamCounter adds the bytes read from the video to the streamed bytes.
+type streamCounter struct {
+	io.ReadSeeker
+}
+
+func (c streamCounter) Read(p []byte) (int, error) {
+	n, err := c.ReadSeeker.Read(p)
+	metrics.StreamedBytes.Add(float64(n))
+	return n, err
 }
 
 func RespondWithVideo(w http.ResponseWriter, info video_dto.VideoFileInfoDto) {
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	ScopeDownload = "download"
+	ScopeRead     = "read"
+	ScopeManage   = "manage"
+
+	// APITokenPrefix tells personal API tokens apart from session tokens.
+	APITokenPrefix = "vdt_"
+
+	// APITokenLastUsedResolution limits how often the last use of a token is
+	// written, so that a busy client does not cause a write on every request.
+	APITokenLastUsedResolution = time.Minute
+)
+
+// AllScopes is granted to login sessions.
+var AllScopes = []string{ScopeDownload, ScopeRead, ScopeManage}
+
+// APIToken is a long-lived personal access token, e.g. for one install of the
+// browser extension. Like sessions, only the hash of the token is stored.
+type APIToken struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	UserID     primitive.ObjectID `bson:"user_id"`
+	Name       string             `bson:"name"`
+	TokenHash  string             `bson:"token_hash"`
+	Scopes     []string           `bson:"scopes"`
+	CreatedAt  time.Time          `bson:"created_at"`
+	LastUsedAt time.Time          `bson:"last_used_at,omitempty"`
+}
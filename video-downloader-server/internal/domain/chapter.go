@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// Chapter is a titled section of a video. The End of the last chapter is
+// zero when the duration of the video is unknown, it then runs to the end.
+type Chapter struct {
+	Title string        `bson:"title"`
+	Start time.Duration `bson:"start"`
+	End   time.Duration `bson:"end"`
+}
+
+// CloseChapters ends every chapter where the next one starts and the last
+// one at duration. The chapters must be ordered by start.
+func CloseChapters(chapters []Chapter, duration time.Duration) {
+	for i := range chapters {
+		if i+1 < len(chapters) {
+			chapters[i].End = chapters[i+1].Start
+		} else {
+			chapters[i].End = duration
+		}
+	}
+}
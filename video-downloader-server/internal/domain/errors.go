@@ -2,12 +2,21 @@ package domain
 
 import "errors"
 
+// repository
+var (
+	ErrNoDocuments  = errors.New("no documents in result")
+	ErrDuplicateKey = errors.New("duplicate key")
+)
+
 // common service
 var (
 	ErrCreatingDir      = errors.New("error creating directory")
 	ErrGeneratingBytes  = errors.New("error generating random bytes")
 	ErrCreatingFile     = errors.New("error creating file for saving video")
 	ErrSavingDataToFile = errors.New("error saving data to file")
+	ErrCopyingFile      = errors.New("error copying file")
+	ErrListingFiles     = errors.New("error listing files")
+	ErrGettingFreeSpace = errors.New("error getting free disk space")
 )
 
 // preview service
@@ -16,6 +25,15 @@ var (
 	ErrParsingVideoDuration = errors.New("error parsing video duration")
 	ErrGeneratingPreview    = errors.New("error generating preview")
 	ErrDeletingPreview      = errors.New("error deleting preview")
+	ErrPreviewNotFound      = errors.New("preview not found")
+	ErrScoringPreview       = errors.New("error scoring preview frame")
+)
+
+// storyboard service
+var (
+	ErrGeneratingStoryboard   = errors.New("error generating storyboard")
+	ErrStoryboardNotGenerated = errors.New("storyboard is not generated yet")
+	ErrStoryboardFileNotFound = errors.New("storyboard file not found")
 )
 
 // general strategy
@@ -40,12 +58,6 @@ var (
 	ErrGettingRealVideoPath = errors.New("error getting real video path by id")
 	ErrVideoNotFound        = errors.New("video not found")
 	ErrGettingFileInfo      = errors.New("err getting file info")
-	ErrInvalidRangeHeader   = errors.New("invalid range header")
-	ErrInvalidRangeFormat   = errors.New("invalid range format")
-	ErrInvalidBytesFormat   = errors.New("invalid bytes format")
-	ErrInvalidRangeStart    = errors.New("invalid start of range")
-	ErrInvalidRangeEnd      = errors.New("invalid end of range")
-	ErrInvalidRange         = errors.New("invalid range")
 	ErrCheckingVideo        = errors.New("error checking video existence")
 	ErrRenamingVideo        = errors.New("error renaming video")
 	ErrMovingVideo          = errors.New("error moving video")
@@ -53,6 +65,8 @@ var (
 	ErrDeletingVideoFromDB  = errors.New("error deleting video from db")
 	ErrGettingPaths         = errors.New("error getting real videos and previews paths")
 	ErrGettingVideos        = errors.New("errors getting videos by folder id")
+	ErrCopyingVideo         = errors.New("error copying video")
+	ErrResolvingVideoName   = errors.New("error resolving video name conflict")
 
 	ErrVideoAlreadyExist = errors.New("video with this name already exist")
 )
@@ -69,4 +83,142 @@ var (
 	ErrGettingAllNestedFolders  = errors.New("error getting all nested folders")
 	ErrDeletingAllNestedFolders = errors.New("error deleting all nested folders")
 	ErrGettingNestedFolders     = errors.New("error getting nested folders")
+	ErrMovingAcrossOwners       = errors.New("can't move between folders of different owners")
+)
+
+// sharing service
+var (
+	ErrFolderAccessDenied = errors.New("your role in this folder does not allow this")
+	ErrCheckingAccess     = errors.New("error checking folder access")
+	ErrGrantingAccess     = errors.New("error granting folder access")
+	ErrRevokingAccess     = errors.New("error revoking folder access")
+	ErrGettingMembers     = errors.New("error getting folder members")
+	ErrMemberNotFound     = errors.New("user is not a member of this folder")
+	ErrSharingWithOwner   = errors.New("the folder owner already has full access")
+	ErrUserNotFound       = errors.New("user with this username not found")
+	ErrDeletingMembers    = errors.New("error deleting folder members")
+)
+
+// share links service
+var (
+	ErrCreatingShareLink    = errors.New("error creating share link")
+	ErrGettingShareLinks    = errors.New("error getting share links")
+	ErrShareLinkNotFound    = errors.New("share link not found or expired")
+	ErrShareLinkUsedUp      = errors.New("share link has no views left")
+	ErrRevokingShareLink    = errors.New("error revoking share link")
+	ErrInvalidSharePassword = errors.New("share link password is missing or wrong")
+	ErrCountingShareView    = errors.New("error counting share link view")
+)
+
+// hls service
+var (
+	ErrProbingVideo     = errors.New("error probing video streams")
+	ErrNoVideoStream    = errors.New("file has no video stream")
+	ErrGeneratingHls    = errors.New("error generating hls renditions")
+	ErrHlsNotGenerated  = errors.New("hls renditions are not generated yet")
+	ErrHlsFileNotFound  = errors.New("hls file not found")
+	ErrDeletingHlsFiles = errors.New("error deleting hls files")
+)
+
+// subtitles service
+var (
+	ErrSubtitleNotFound          = errors.New("subtitle not found")
+	ErrUnsupportedSubtitleFormat = errors.New("unsupported subtitle format, upload a .srt or .vtt file")
+	ErrInvalidSubtitle           = errors.New("invalid subtitle file")
+	ErrSavingSubtitle            = errors.New("error saving subtitle")
+	ErrDownloadingSubtitles      = errors.New("error downloading subtitles")
+	ErrDeletingSubtitles         = errors.New("error deleting subtitles")
+)
+
+// chapters
+var (
+	ErrNoChapters          = errors.New("video has no chapters")
+	ErrExtractingChapters  = errors.New("error extracting chapters")
+	ErrSavingChapters      = errors.New("error saving chapters")
+	ErrDownloadingChapters = errors.New("error downloading chapters")
+	ErrSplittingVideo      = errors.New("error splitting video")
+)
+
+// transcode service
+var (
+	ErrUnknownTranscodeProfile = errors.New("unknown transcode profile")
+	ErrTranscodingVideo        = errors.New("error transcoding video")
+)
+
+// migrations
+var (
+	ErrCheckingMigration = errors.New("error checking migration version")
+	ErrApplyingMigration = errors.New("error applying migration")
+	ErrSavingMigration   = errors.New("error saving applied migration version")
+)
+
+// fsck service
+var (
+	ErrGettingAllVideos   = errors.New("error getting all videos")
+	ErrGettingAllFolders  = errors.New("error getting all folders")
+	ErrRepairingVideo     = errors.New("error repairing video")
+	ErrRepairingFolder    = errors.New("error repairing folder")
+	ErrDeletingOrphanFile = errors.New("error deleting orphaned file")
+)
+
+// gc service
+var (
+	ErrCollectingGarbage = errors.New("error collecting orphaned files")
+)
+
+// intents service
+var (
+	ErrRecordingIntent    = errors.New("error recording intent")
+	ErrApplyingIntent     = errors.New("error applying intent")
+	ErrCompensatingIntent = errors.New("error compensating intent")
+	ErrGettingIntents     = errors.New("error getting unfinished intents")
+	ErrUnknownIntent      = errors.New("unknown intent type")
+)
+
+// jobs service
+var (
+	ErrJobNotFound  = errors.New("job not found")
+	ErrJobQueueFull = errors.New("job queue is full, try again later")
+	ErrTooManyJobs  = errors.New("too many active jobs, wait for some to finish")
+	ErrShuttingDown = errors.New("server is shutting down")
+)
+
+// health service
+var (
+	ErrPingingStorage           = errors.New("error pinging storage")
+	ErrDirNotWritable           = errors.New("directory is not writable")
+	ErrNotEnoughSpace           = errors.New("not enough free disk space")
+	ErrFindingExecutable        = errors.New("executable not found")
+	ErrGettingExecutableVersion = errors.New("error getting executable version")
+)
+
+// auth service
+var (
+	ErrHashingPassword     = errors.New("error hashing password")
+	ErrCreatingUser        = errors.New("error creating user")
+	ErrUserAlreadyExist    = errors.New("user with this username already exist")
+	ErrGettingUser         = errors.New("error getting user")
+	ErrInvalidCredentials  = errors.New("invalid username or password")
+	ErrCreatingSession     = errors.New("error creating session")
+	ErrDeletingSession     = errors.New("error deleting session")
+	ErrUnauthorized        = errors.New("missing, invalid or expired session")
+	ErrForbidden           = errors.New("not allowed for this user")
+	ErrSignupDisabled      = errors.New("sign up is disabled")
+	ErrCountingUsers       = errors.New("error counting users")
+	ErrClaimingUnownedData = errors.New("error assigning unowned videos and folders")
+	ErrMissingScope        = errors.New("token does not have the required scope")
+	ErrCreatingAPIToken    = errors.New("error creating api token")
+	ErrGettingAPITokens    = errors.New("error getting api tokens")
+	ErrAPITokenNotFound    = errors.New("api token with this ID not found")
+	ErrRevokingAPIToken    = errors.New("error revoking api token")
+)
+
+// rate limiting
+var (
+	ErrTooManyRequests = errors.New("too many requests, try again later")
+)
+
+// tracing
+var (
+	ErrCreatingSpanExporter = errors.New("error creating span exporter")
 )
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Folder roles, from the weakest. Viewers browse and watch, contributors also
+// add and edit videos and subfolders, owners also move and delete folders and
+// manage the members.
+const (
+	FolderViewer      = "viewer"
+	FolderContributor = "contributor"
+	FolderOwner       = "owner"
+)
+
+// MaxFolderDepth bounds the walk up the parent_dir_id tree when resolving
+// inherited roles, so that a corrupted cycle can't hang a request.
+const MaxFolderDepth = 64
+
+var folderRoleRanks = map[string]int{
+	FolderViewer:      1,
+	FolderContributor: 2,
+	FolderOwner:       3,
+}
+
+// FolderRoleAtLeast reports whether role grants everything min does.
+func FolderRoleAtLeast(role, min string) bool {
+	return folderRoleRanks[role] >= folderRoleRanks[min]
+}
+
+// FolderMember grants a user other than the owner a role on a folder and
+// every folder below it.
+type FolderMember struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	FolderID  primitive.ObjectID `bson:"folder_id"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	Role      string             `bson:"role"`
+	GrantedBy primitive.ObjectID `bson:"granted_by"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
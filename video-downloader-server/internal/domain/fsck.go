@@ -0,0 +1,9 @@
+package domain
+
+import "time"
+
+const (
+	// FsckMinFileAge protects files of in-progress downloads, which exist on disk
+	// before their document is saved, from being reported as orphaned.
+	FsckMinFileAge = time.Hour
+)
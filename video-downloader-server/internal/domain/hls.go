@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"path/filepath"
+	"regexp"
+)
+
+const (
+	HlsMasterPlaylist  = "master.m3u8"
+	HlsVariantPlaylist = "index.m3u8"
+	HlsSegmentPattern  = "segment_%04d.ts"
+
+	// HlsTmpMarker ends the name of the directory a rendition ladder is
+	// generated in before it is renamed into place.
+	HlsTmpMarker = ".hls-tmp-"
+
+	HlsPlaylistContentType = "application/vnd.apple.mpegurl"
+	HlsSegmentContentType  = "video/mp2t"
+)
+
+// HlsRendition is one step of the HLS ladder. Bitrates are in kbit/s.
+type HlsRendition struct {
+	Name         string
+	Height       int
+	VideoBitrate int
+	AudioBitrate int
+}
+
+// HlsRenditions is every rendition the ladder can be configured with, from
+// the highest to the lowest.
+var HlsRenditions = []HlsRendition{
+	{Name: "2160p", Height: 2160, VideoBitrate: 14000, AudioBitrate: 192},
+	{Name: "1440p", Height: 1440, VideoBitrate: 8000, AudioBitrate: 192},
+	{Name: "1080p", Height: 1080, VideoBitrate: 5000, AudioBitrate: 128},
+	{Name: "720p", Height: 720, VideoBitrate: 2800, AudioBitrate: 128},
+	{Name: "480p", Height: 480, VideoBitrate: 1400, AudioBitrate: 96},
+	{Name: "360p", Height: 360, VideoBitrate: 800, AudioBitrate: 96},
+	{Name: "240p", Height: 240, VideoBitrate: 400, AudioBitrate: 64},
+}
+
+// validHlsFile matches the files of a generated ladder: the master playlist
+// and the playlist and segments of each rendition.
+var validHlsFile = regexp.MustCompile(`^(master\.m3u8|[0-9]{3,4}p/(index\.m3u8|segment_[0-9]{4,}\.ts))$`)
+
+// ValidHlsFile reports whether name is the path of an HLS file relative to
+// the ladder directory.
+func ValidHlsFile(name string) bool {
+	return validHlsFile.MatchString(name)
+}
+
+// HlsContentType returns the media type of an HLS file.
+func HlsContentType(name string) string {
+	if filepath.Ext(name) == ".ts" {
+		return HlsSegmentContentType
+	}
+
+	return HlsPlaylistContentType
+}
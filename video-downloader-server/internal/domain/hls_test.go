@@ -0,0 +1,56 @@
+package domain
+
+import "testing"
+
+func TestValidHlsFile(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"master.m3u8", true},
+		{"720p/index.m3u8", true},
+		{"2160p/index.m3u8", true},
+		{"720p/segment_0000.ts", true},
+		{"720p/segment_12345.ts", true},
+		{"", false},
+		{"index.m3u8", false},
+		{"720p/master.m3u8", false},
+		{"720p/segment_000.ts", false},
+		{"720p/segment_0000.mp4", false},
+		{"72p/index.m3u8", false},
+		{"720/index.m3u8", false},
+		{"720p/../index.m3u8", false},
+		{"../720p/index.m3u8", false},
+		{"/master.m3u8", false},
+		{"720p/sub/index.m3u8", false},
+		{"master.m3u8.bak", false},
+		{"a" + HlsTmpMarker + "1/master.m3u8", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidHlsFile(tt.name); got != tt.want {
+				t.Errorf("ValidHlsFile(%q) = %t, want %t", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHlsContentType(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"master.m3u8", HlsPlaylistContentType},
+		{"720p/index.m3u8", HlsPlaylistContentType},
+		{"720p/segment_0000.ts", HlsSegmentContentType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HlsContentType(tt.name); got != tt.want {
+				t.Errorf("HlsContentType(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	IntentDelete      = "delete"
+	IntentCreateVideo = "create_video"
+
+	IntentMaxAttempts  = 3
+	IntentRetryBackoff = 100 * time.Millisecond
+)
+
+// Intent records a change that spans the database and the filesystem so that
+// it can be replayed or compensated if the process dies halfway through.
+type Intent struct {
+	ID           primitive.ObjectID   `bson:"_id,omitempty"`
+	Type         string               `bson:"type"`
+	Video        *Video               `bson:"video,omitempty"`
+	VideoIDs     []primitive.ObjectID `bson:"video_ids,omitempty"`
+	FolderIDs    []primitive.ObjectID `bson:"folder_ids,omitempty"`
+	VideoPaths   []string             `bson:"video_paths,omitempty"`
+	PreviewPaths []string             `bson:"preview_paths,omitempty"`
+	Attempts     int                  `bson:"attempts"`
+	LastError    string               `bson:"last_error,omitempty"`
+	CreatedAt    time.Time            `bson:"created_at"`
+}
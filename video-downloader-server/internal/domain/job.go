@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	JobDownload   = "download"
+	JobHls        = "hls"
+	JobTranscode  = "transcode"
+	JobSplit      = "split"
+	JobStoryboard = "storyboard"
+
+	JobQueued   = "queued"
+	JobRunning  = "running"
+	JobDone     = "done"
+	JobFailed   = "failed"
+	JobCanceled = "canceled"
+
+	// CommandWaitDelay is how long a canceled subprocess gets to exit after
+	// SIGTERM before it is killed.
+	CommandWaitDelay = 5 * time.Second
+)
+
+// Job is a background task run by the jobs worker pool. Jobs are kept in
+// memory only: on shutdown running jobs get the grace period to finish and
+// queued ones are canceled.
+type Job struct {
+	ID         primitive.ObjectID
+	OwnerID    primitive.ObjectID
+	Type       string
+	Status     string
+	Source     string
+	VideoID    primitive.ObjectID
+	Error      string
+	Progress   float64
+	NextJobID  primitive.ObjectID
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// JobFunc does the work of a job and returns the ID of the resulting video.
+type JobFunc func(ctx context.Context) (primitive.ObjectID, error)
+
+// JobReporter lets a running job publish how far it has come and the job it
+// has handed its result on to.
+type JobReporter interface {
+	SetProgress(fraction float64)
+	SetNextJob(jobID primitive.ObjectID)
+}
+
+type jobReporterKey struct{}
+
+type noopJobReporter struct{}
+
+func (noopJobReporter) SetProgress(float64)           {}
+func (noopJobReporter) SetNextJob(primitive.ObjectID) {}
+
+// WithJobReporter returns a copy of ctx carrying reporter.
+func WithJobReporter(ctx context.Context, reporter JobReporter) context.Context {
+	return context.WithValue(ctx, jobReporterKey{}, reporter)
+}
+
+// JobReporterFromContext returns the reporter of the job running with ctx, or
+// one that discards everything outside of a job.
+func JobReporterFromContext(ctx context.Context) JobReporter {
+	if reporter, ok := ctx.Value(jobReporterKey{}).(JobReporter); ok {
+		return reporter
+	}
+
+	return noopJobReporter{}
+}
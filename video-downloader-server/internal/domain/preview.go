@@ -1,9 +1,39 @@
 package domain
 
+import (
+	"path/filepath"
+	"strings"
+)
+
 const (
-	CommonPreviewDir = "previews"
-	PreviewFormat    = ".jpeg"
+	PreviewFormat      = ".jpeg"
+	PreviewContentType = "image/jpeg"
+
+	// StoryboardSprite and StoryboardTrack are the files of the storyboard
+	// of a video: a sheet of thumbnails taken at regular intervals and the
+	// WebVTT track mapping each interval to its thumbnail in the sheet.
+	StoryboardSprite = "storyboard.jpg"
+	StoryboardTrack  = "storyboard.vtt"
 
-	MinTimeFraction = 0.1
-	MaxTimeFraction = 0.8
+	// StoryboardTmpMarker is part of the name of a storyboard file while it
+	// is being written, before it is renamed into place.
+	StoryboardTmpMarker = ".tmp-"
 )
+
+// StoryboardPath returns the path of the name file of the storyboard of the
+// video whose preview is at previewPath. Storyboards are stored next to the
+// preview, e.g. ab/cd/name.storyboard.vtt for ab/cd/name.jpeg.
+func StoryboardPath(previewPath string, name string) string {
+	return strings.TrimSuffix(previewPath, filepath.Ext(previewPath)) + "." + name
+}
+
+// StoryboardPaths returns the paths of every file of the storyboard of the
+// video whose preview is at previewPath.
+func StoryboardPaths(previewPath string) []string {
+	return []string{StoryboardPath(previewPath, StoryboardSprite), StoryboardPath(previewPath, StoryboardTrack)}
+}
+
+// ValidStoryboardFile reports whether name is a file of a storyboard.
+func ValidStoryboardFile(name string) bool {
+	return name == StoryboardSprite || name == StoryboardTrack
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	// ShareLinkTokenBytes is the length of the random part of a share link.
+	ShareLinkTokenBytes = 24
+	// ShareViewGrantTTL is how long after its last request a counted view
+	// keeps letting its viewer seek in the video.
+	ShareViewGrantTTL = 30 * time.Minute
+)
+
+// ShareLink lets anyone holding its token stream or download one video
+// without an account. Only the hash of the token is stored. A zero ExpiresAt
+// never expires and a zero MaxViews allows any number of views.
+type ShareLink struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	VideoID      primitive.ObjectID `bson:"video_id"`
+	OwnerID      primitive.ObjectID `bson:"owner_id"`
+	CreatedBy    primitive.ObjectID `bson:"created_by"`
+	TokenHash    string             `bson:"token_hash"`
+	PasswordHash string             `bson:"password_hash,omitempty"`
+	ExpiresAt    time.Time          `bson:"expires_at,omitempty"`
+	MaxViews     int                `bson:"max_views"`
+	Views        int                `bson:"views"`
+	CreatedAt    time.Time          `bson:"created_at"`
+}
+
+// Expired reports whether the link stopped working at now.
+func (l ShareLink) Expired(now time.Time) bool {
+	return !l.ExpiresAt.IsZero() && !now.Before(l.ExpiresAt)
+}
+
+// UsedUp reports whether the link has no views left.
+func (l ShareLink) UsedUp() bool {
+	return l.MaxViews > 0 && l.Views >= l.MaxViews
+}
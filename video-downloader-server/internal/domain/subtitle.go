@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	SubtitleFormat      = ".vtt"
+	SrtSubtitleFormat   = ".srt"
+	SubtitleContentType = "text/vtt; charset=utf-8"
+
+	// WebVTTHeader starts every WebVTT file.
+	WebVTTHeader = "WEBVTT"
+
+	// MaxSubtitleSize bounds the size of a downloaded or uploaded track.
+	MaxSubtitleSize = 5 << 20
+
+	maxSubtitleLangLength = 35
+)
+
+// utf8BOM may start a text file written on Windows.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// Subtitle is a WebVTT caption track of a video. Auto tracks were generated
+// by speech recognition rather than written by someone.
+type Subtitle struct {
+	Lang  string `bson:"lang"`
+	Label string `bson:"label"`
+	Auto  bool   `bson:"auto"`
+}
+
+// validSubtitleLang matches BCP 47 like language tags such as en, pt-BR and
+// zh-Hans, which never contain a dot.
+var validSubtitleLang = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// ValidSubtitleLang reports whether lang can name a subtitle track.
+func ValidSubtitleLang(lang string) bool {
+	return len(lang) <= maxSubtitleLangLength && validSubtitleLang.MatchString(lang)
+}
+
+// SubtitlePath returns the path of the lang track of the video at realPath.
+// Tracks are stored next to the video, e.g. ab/cd/name.en.vtt for
+// ab/cd/name.mp4.
+func SubtitlePath(realPath string, lang string) string {
+	return strings.TrimSuffix(realPath, filepath.Ext(realPath)) + "." + lang + SubtitleFormat
+}
+
+// SubtitleLang returns the language of file when it is a track of the video
+// at realPath.
+func SubtitleLang(realPath string, file string) (string, bool) {
+	prefix := strings.TrimSuffix(realPath, filepath.Ext(realPath)) + "."
+	if !strings.HasPrefix(file, prefix) || !strings.HasSuffix(file, SubtitleFormat) {
+		return "", false
+	}
+
+	lang := strings.TrimSuffix(strings.TrimPrefix(file, prefix), SubtitleFormat)
+	if !ValidSubtitleLang(lang) {
+		return "", false
+	}
+
+	return lang, true
+}
+
+// ValidWebVTT reports whether data starts like a WebVTT file.
+func ValidWebVTT(data []byte) bool {
+	data = TrimBOM(data)
+	if !bytes.HasPrefix(data, []byte(WebVTTHeader)) {
+		return false
+	}
+
+	// the header is followed by a space, a tab or the end of the line
+	rest := data[len(WebVTTHeader):]
+	return len(rest) == 0 || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\n' || rest[0] == '\r'
+}
+
+// TrimBOM returns data without its UTF-8 byte order mark.
+func TrimBOM(data []byte) []byte {
+	return bytes.TrimPrefix(data, utf8BOM)
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	MinTranscodeHeight = 144
+	MaxTranscodeHeight = 4320
+)
+
+// TranscodeCodec is how a family of transcode profiles encodes: the
+// container, the video and audio encoders and their extra ffmpeg options.
+type TranscodeCodec struct {
+	Container  string
+	VideoCodec string
+	AudioCodec string
+	Args       []string
+}
+
+// TranscodeCodecs is every codec a profile can be named after.
+var TranscodeCodecs = map[string]TranscodeCodec{
+	"h264": {Container: ".mp4", VideoCodec: "libx264", AudioCodec: "aac", Args: []string{"-preset", "medium", "-crf", "23", "-pix_fmt", "yuv420p", "-movflags", "+faststart"}},
+	"h265": {Container: ".mp4", VideoCodec: "libx265", AudioCodec: "aac", Args: []string{"-preset", "medium", "-crf", "28", "-pix_fmt", "yuv420p", "-tag:v", "hvc1", "-movflags", "+faststart"}},
+	"webm": {Container: ".webm", VideoCodec: "libvpx-vp9", AudioCodec: "libopus", Args: []string{"-crf", "33", "-b:v", "0", "-row-mt", "1"}},
+}
+
+// TranscodeProfile is a named output format, e.g. h264-720p. Videos are
+// scaled down to Height but never up.
+type TranscodeProfile struct {
+	Name   string
+	Height int
+	TranscodeCodec
+}
+
+// ParseTranscodeProfile parses a profile name made of a codec from
+// TranscodeCodecs and a height, e.g. h265-1080p or webm-480p.
+func ParseTranscodeProfile(name string) (TranscodeProfile, error) {
+	codecName, heightStr, ok := strings.Cut(name, "-")
+	if !ok || !strings.HasSuffix(heightStr, "p") {
+		return TranscodeProfile{}, fmt.Errorf("%w (profile: %s)", ErrUnknownTranscodeProfile, name)
+	}
+
+	codec, ok := TranscodeCodecs[codecName]
+	if !ok {
+		return TranscodeProfile{}, fmt.Errorf("%w (profile: %s)", ErrUnknownTranscodeProfile, name)
+	}
+
+	height, err := strconv.Atoi(strings.TrimSuffix(heightStr, "p"))
+	if err != nil || height < MinTranscodeHeight || height > MaxTranscodeHeight || height%2 != 0 {
+		return TranscodeProfile{}, fmt.Errorf("%w (profile: %s)", ErrUnknownTranscodeProfile, name)
+	}
+
+	return TranscodeProfile{Name: name, Height: height, TranscodeCodec: codec}, nil
+}
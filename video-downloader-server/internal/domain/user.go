@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+
+	SessionTokenBytes = 32
+
+	// MaxPasswordLength is the longest password bcrypt can hash.
+	MaxPasswordLength = 72
+)
+
+type User struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	Username     string             `bson:"username"`
+	PasswordHash string             `bson:"password_hash"`
+	Role         string             `bson:"role"`
+	CreatedAt    time.Time          `bson:"created_at"`
+}
+
+// Session is a login of a user. Only the SHA-256 hash of the token is stored,
+// so a leaked database does not leak usable tokens.
+type Session struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	TokenHash string             `bson:"token_hash"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	CreatedAt time.Time          `bson:"created_at"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+}
@@ -1,20 +1,63 @@
 package domain
 
 import (
+	"fmt"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"path/filepath"
+	"strings"
 )
 
 const (
-	CommonVideoDir         = "videos"
-	VideoFormat            = ".mp4"
-	YouTubeVideoType       = "youtube"
-	DefaultRangePercentage = 0.05
+	VideoFormat      = ".mp4"
+	YouTubeVideoType = "youtube"
+	TmpVideoMarker   = "_video_"
+	TmpAudioMarker   = "_audio"
+
+	ConflictPolicyError     = "error"
+	ConflictPolicySuffix    = "suffix"
+	ConflictPolicyOverwrite = "overwrite"
+
+	defaultContentType = "application/octet-stream"
 )
 
+// videoContentTypes maps the extensions of the containers a video can be
+// saved in to their media type.
+var videoContentTypes = map[string]string{
+	".mp4":  "video/mp4",
+	".m4v":  "video/x-m4v",
+	".mov":  "video/quicktime",
+	".webm": "video/webm",
+	".mkv":  "video/x-matroska",
+	".avi":  "video/x-msvideo",
+	".flv":  "video/x-flv",
+	".ts":   "video/mp2t",
+	".3gp":  "video/3gpp",
+	".ogv":  "video/ogg",
+}
+
 type Video struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	OwnerID     primitive.ObjectID `bson:"owner_id"`
 	VideoName   string             `bson:"video_name"`
 	FolderID    primitive.ObjectID `bson:"folder_id"`
 	RealPath    string             `bson:"real_path"`
 	PreviewPath string             `bson:"preview_path"`
+	Subtitles   []Subtitle         `bson:"subtitles,omitempty"`
+	Chapters    []Chapter          `bson:"chapters,omitempty"`
+}
+
+// VideoContentType returns the media type of a video file by the extension
+// of name, application/octet-stream for unknown containers.
+func VideoContentType(name string) string {
+	if contentType, ok := videoContentTypes[strings.ToLower(filepath.Ext(name))]; ok {
+		return contentType
+	}
+
+	return defaultContentType
+}
+
+// SuffixedVideoName returns the n-th name the suffix conflict policy tries
+// for a copy of name, e.g. "name (2)".
+func SuffixedVideoName(name string, n int) string {
+	return fmt.Sprintf("%s (%d)", name, n)
 }
@@ -0,0 +1,55 @@
+// Package logger carries a request or job scoped logrus entry in a context so
+// that every log line can be tied back to the request or job that caused it.
+package logger
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"os"
+)
+
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+
+	RequestIDField = "request_id"
+	JobIDField     = "job_id"
+	VideoIDField   = "video_id"
+	FolderIDField  = "folder_id"
+	StrategyField  = "strategy"
+	TraceIDField   = "trace_id"
+	UserIDField    = "user_id"
+)
+
+type contextKey struct{}
+
+// Setup configures the global logger that all scoped loggers derive from.
+func Setup(format string, level log.Level) {
+	if format == JSONFormat {
+		log.SetFormatter(&log.JSONFormatter{})
+	} else {
+		log.SetFormatter(&log.TextFormatter{})
+	}
+
+	log.SetOutput(os.Stderr)
+	log.SetLevel(level)
+}
+
+// FromContext returns the logger stored in ctx or the global one.
+func FromContext(ctx context.Context) *log.Entry {
+	if entry, ok := ctx.Value(contextKey{}).(*log.Entry); ok {
+		return entry
+	}
+
+	return log.NewEntry(log.StandardLogger())
+}
+
+// WithContext returns a copy of ctx that carries entry.
+func WithContext(ctx context.Context, entry *log.Entry) context.Context {
+	return context.WithValue(ctx, contextKey{}, entry)
+}
+
+// WithFields returns a copy of ctx whose logger has fields added.
+func WithFields(ctx context.Context, fields log.Fields) context.Context {
+	return WithContext(ctx, FromContext(ctx).WithFields(fields))
+}
@@ -0,0 +1,170 @@
+// Package metrics holds the Prometheus collectors of the server. They are
+// registered in the default registry and exposed by promhttp.Handler.
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const (
+	namespace = "video_downloader"
+
+	OutcomeDone     = "done"
+	OutcomeFailed   = "failed"
+	OutcomeCanceled = "canceled"
+
+	FfmpegMerge      = "merge"
+	FfmpegPreview    = "preview"
+	FfmpegProbe      = "probe"
+	FfmpegHls        = "hls"
+	FfmpegTranscode  = "transcode"
+	FfmpegSplit      = "split"
+	FfmpegStoryboard = "storyboard"
+
+	errCollectingLibraryStats = "error collecting library stats"
+)
+
+var (
+	HttpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	HttpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	Downloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "downloads_total",
+		Help:      "Download jobs by strategy and outcome.",
+	}, []string{"strategy", "outcome"})
+
+	DownloadDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "download_duration_seconds",
+		Help:      "Duration of download jobs by strategy and outcome.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	}, []string{"strategy", "outcome"})
+
+	DownloadedBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "downloaded_bytes_total",
+		Help:      "Bytes of successfully downloaded videos by strategy.",
+	}, []string{"strategy"})
+
+	FfmpegDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ffmpeg_duration_seconds",
+		Help:      "Duration of ffmpeg and ffprobe invocations by operation.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 14),
+	}, []string{"operation"})
+
+	FfmpegFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ffmpeg_failures_total",
+		Help:      "Failed ffmpeg and ffprobe invocations by operation.",
+	}, []string{"operation"})
+
+	StreamedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "streamed_bytes_total",
+		Help:      "Bytes sent by /videos/stream and share link streams.",
+	})
+
+	RateLimitedRequests = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests rejected with 429 by the rate limiter.",
+	})
+)
+
+// ObserveDownload records a finished download job. ctxErr is the error of the
+// job context, a failure caused by cancellation is recorded as canceled.
+func ObserveDownload(strategy string, start time.Time, err, ctxErr error) {
+	outcome := OutcomeDone
+
+	switch {
+	case err == nil:
+	case ctxErr != nil:
+		outcome = OutcomeCanceled
+	default:
+		outcome = OutcomeFailed
+	}
+
+	Downloads.WithLabelValues(strategy, outcome).Inc()
+	DownloadDuration.WithLabelValues(strategy, outcome).Observe(time.Since(start).Seconds())
+}
+
+// ObserveFfmpeg records an ffmpeg or ffprobe invocation that started at start.
+func ObserveFfmpeg(operation string, start time.Time, err error) {
+	FfmpegDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		FfmpegFailures.WithLabelValues(operation).Inc()
+	}
+}
+
+// LibraryStats returns the number of videos and their total size in bytes.
+type LibraryStats func(ctx context.Context) (int, int64, error)
+
+// RegisterLibrary exposes the library size and video count gauges. Stats are
+// collected on scrape and cached for ttl since they walk the whole library.
+func RegisterLibrary(stats LibraryStats, ttl, timeout time.Duration) {
+	prometheus.MustRegister(&libraryCollector{
+		stats:   stats,
+		ttl:     ttl,
+		timeout: timeout,
+		videos: prometheus.NewDesc(prometheus.BuildFQName(namespace, "library", "videos"),
+			"Number of videos in the library.", nil, nil),
+		size: prometheus.NewDesc(prometheus.BuildFQName(namespace, "library", "size_bytes"),
+			"Total size of the video files in the library.", nil, nil),
+	})
+}
+
+type libraryCollector struct {
+	stats   LibraryStats
+	ttl     time.Duration
+	timeout time.Duration
+	videos  *prometheus.Desc
+	size    *prometheus.Desc
+
+	mu          sync.Mutex
+	collectedAt time.Time
+	videosCount int
+	sizeBytes   int64
+}
+
+func (c *libraryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.videos
+	ch <- c.size
+}
+
+func (c *libraryCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.collectedAt) > c.ttl {
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		videosCount, sizeBytes, err := c.stats(ctx)
+		cancel()
+
+		if err != nil {
+			log.WithError(err).Error(errCollectingLibraryStats)
+		} else {
+			c.videosCount, c.sizeBytes, c.collectedAt = videosCount, sizeBytes, time.Now()
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.videos, prometheus.GaugeValue, float64(c.videosCount))
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(c.sizeBytes))
+}
@@ -0,0 +1,220 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sort"
+	"time"
+	"video-downloader-server/internal/domain"
+)
+
+const (
+	migrationsCollection = "migrations"
+
+	// error codes of dropping an index that, or whose collection, does not
+	// exist
+	indexNotFoundCode     = 27
+	namespaceNotFoundCode = 26
+
+	migrationApplied      = "migration has been applied"
+	duplicateVideoRenamed = "video with a duplicate name in its folder renamed"
+)
+
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+type appliedMigration struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// Run applies every migration from the list whose version is not yet recorded
+// in the migrations collection, in ascending version order. Every migration
+// must finish within migrationTimeout and every read or write of the
+// migrations collection within opTimeout.
+func Run(ctx context.Context, db *mongo.Database, opTimeout, migrationTimeout time.Duration) error {
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	coll := db.Collection(migrationsCollection)
+
+	for _, m := range migrations {
+		applied, err := isApplied(ctx, coll, m.Version, opTimeout)
+		if err != nil {
+			return fmt.Errorf("%w (version: %d): %s", domain.ErrCheckingMigration, m.Version, err)
+		}
+
+		if applied {
+			continue
+		}
+
+		if err := up(ctx, db, m, migrationTimeout); err != nil {
+			return fmt.Errorf("%w (version: %d, description: %s): %s", domain.ErrApplyingMigration, m.Version, m.Description, err)
+		}
+
+		if err := record(ctx, coll, m, opTimeout); err != nil {
+			return fmt.Errorf("%w (version: %d): %s", domain.ErrSavingMigration, m.Version, err)
+		}
+
+		log.WithField("version", m.Version).Info(migrationApplied + ": " + m.Description)
+	}
+
+	return nil
+}
+
+func isApplied(ctx context.Context, coll *mongo.Collection, version int, opTimeout time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, opTimeout)
+	defer cancel()
+
+	err := coll.FindOne(ctx, bson.M{"_id": version}).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func up(ctx context.Context, db *mongo.Database, m Migration, migrationTimeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, migrationTimeout)
+	defer cancel()
+
+	return m.Up(ctx, db)
+}
+
+func record(ctx context.Context, coll *mongo.Collection, m Migration, opTimeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, opTimeout)
+	defer cancel()
+
+	_, err := coll.InsertOne(ctx, appliedMigration{
+		Version:     m.Version,
+		Description: m.Description,
+		AppliedAt:   time.Now(),
+	})
+	return err
+}
+
+func createIndexes(collection string, models ...mongo.IndexModel) func(ctx context.Context, db *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection(collection).Indexes().CreateMany(ctx, models)
+		return err
+	}
+}
+
+func dropIndex(collection string, name string) func(ctx context.Context, db *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection(collection).Indexes().DropOne(ctx, name)
+		return err
+	}
+}
+
+// dropIndexIfExists drops the index like dropIndex, but also succeeds when
+// there is no such index.
+func dropIndexIfExists(collection string, name string) func(ctx context.Context, db *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		err := dropIndex(collection, name)(ctx, db)
+
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && (cmdErr.Code == indexNotFoundCode || cmdErr.Code == namespaceNotFoundCode) {
+			return nil
+		}
+
+		return err
+	}
+}
+
+// chain runs the steps in order and stops at the first error.
+func chain(steps ...func(ctx context.Context, db *mongo.Database) error) func(ctx context.Context, db *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		for _, step := range steps {
+			if err := step(ctx, db); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// setDefault is a data migration helper that sets field to value on every
+// document of collection where the field is missing.
+func setDefault(collection string, field string, value interface{}) func(ctx context.Context, db *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection(collection).UpdateMany(ctx, bson.M{field: bson.M{"$exists": false}}, bson.M{"$set": bson.M{field: value}})
+		return err
+	}
+}
+
+func uniqueIndex() *options.IndexOptions {
+	return options.Index().SetUnique(true)
+}
+
+type duplicateVideos struct {
+	Key struct {
+		FolderID  primitive.ObjectID `bson:"folder_id"`
+		VideoName string             `bson:"video_name"`
+	} `bson:"_id"`
+	IDs []primitive.ObjectID `bson:"ids"`
+}
+
+// renameDuplicateVideos is a data migration that makes video names unique
+// inside their folder before the unique index is built. The oldest video
+// keeps its name, the others get the first free " (n)" suffix, as with the
+// suffix conflict policy.
+func renameDuplicateVideos(ctx context.Context, db *mongo.Database) error {
+	coll := db.Collection("videos")
+
+	cursor, err := coll.Aggregate(ctx, mongo.Pipeline{
+		{{"$sort", bson.D{{"_id", 1}}}},
+		{{"$group", bson.D{
+			{"_id", bson.D{{"folder_id", "$folder_id"}, {"video_name", "$video_name"}}},
+			{"ids", bson.D{{"$push", "$_id"}}},
+		}}},
+		{{"$match", bson.D{{"ids.1", bson.D{{"$exists", true}}}}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	var groups []duplicateVideos
+	if err := cursor.All(ctx, &groups); err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		n := 2
+		for _, videoID := range group.IDs[1:] {
+			var videoName string
+			for ; ; n++ {
+				videoName = domain.SuffixedVideoName(group.Key.VideoName, n)
+
+				count, err := coll.CountDocuments(ctx, bson.M{"folder_id": group.Key.FolderID, "video_name": videoName})
+				if err != nil {
+					return err
+				}
+
+				if count == 0 {
+					break
+				}
+			}
+
+			if _, err := coll.UpdateOne(ctx, bson.M{"_id": videoID}, bson.M{"$set": bson.M{"video_name": videoName}}); err != nil {
+				return err
+			}
+
+			log.WithFields(log.Fields{"video_id": videoID, "video_name": videoName}).Warn(duplicateVideoRenamed)
+		}
+	}
+
+	return nil
+}
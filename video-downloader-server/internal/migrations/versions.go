@@ -0,0 +1,94 @@
+package migrations
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrations must only ever be appended to: applied versions are never re-run.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "index videos by folder and unique video name inside folder",
+		Up: chain(
+			renameDuplicateVideos,
+			createIndexes("videos",
+				mongo.IndexModel{Keys: bson.D{{"folder_id", 1}, {"video_name", 1}}, Options: uniqueIndex()},
+			),
+		),
+	},
+	{
+		Version:     2,
+		Description: "index folders by parent dir and name",
+		Up: createIndexes("folders",
+			mongo.IndexModel{Keys: bson.D{{"parent_dir_id", 1}, {"folder_name", 1}}},
+		),
+	},
+	{
+		Version:     3,
+		Description: "unique user name",
+		Up: createIndexes("users",
+			mongo.IndexModel{Keys: bson.D{{"username", 1}}, Options: uniqueIndex()},
+		),
+	},
+	{
+		Version:     4,
+		Description: "unique session token hash and expire sessions",
+		Up: createIndexes("sessions",
+			mongo.IndexModel{Keys: bson.D{{"token_hash", 1}}, Options: uniqueIndex()},
+			mongo.IndexModel{Keys: bson.D{{"expires_at", 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		),
+	},
+	{
+		Version:     5,
+		Description: "set default owner on videos and folders",
+		Up: chain(
+			setDefault("videos", "owner_id", primitive.NilObjectID),
+			setDefault("folders", "owner_id", primitive.NilObjectID),
+		),
+	},
+	{
+		Version:     6,
+		Description: "scope unique video name and folder indexes to the owner",
+		Up: chain(
+			dropIndex("videos", "folder_id_1_video_name_1"),
+			createIndexes("videos",
+				mongo.IndexModel{Keys: bson.D{{"owner_id", 1}, {"folder_id", 1}, {"video_name", 1}}, Options: uniqueIndex()},
+			),
+			createIndexes("folders",
+				mongo.IndexModel{Keys: bson.D{{"owner_id", 1}, {"parent_dir_id", 1}, {"folder_name", 1}}},
+			),
+		),
+	},
+	{
+		Version:     7,
+		Description: "unique api token hash and api tokens by user",
+		Up: createIndexes("api_tokens",
+			mongo.IndexModel{Keys: bson.D{{"token_hash", 1}}, Options: uniqueIndex()},
+			mongo.IndexModel{Keys: bson.D{{"user_id", 1}}},
+		),
+	},
+	{
+		Version:     8,
+		Description: "unique folder member and folder members by user",
+		Up: createIndexes("folder_members",
+			mongo.IndexModel{Keys: bson.D{{"folder_id", 1}, {"user_id", 1}}, Options: uniqueIndex()},
+			mongo.IndexModel{Keys: bson.D{{"user_id", 1}}},
+		),
+	},
+	{
+		Version:     9,
+		Description: "unique share link token hash and share links by video",
+		Up: createIndexes("share_links",
+			mongo.IndexModel{Keys: bson.D{{"token_hash", 1}}, Options: uniqueIndex()},
+			mongo.IndexModel{Keys: bson.D{{"owner_id", 1}, {"video_id", 1}}},
+		),
+	},
+	{
+		Version:     10,
+		Description: "drop the folder name index, the parent dir and name index covers it",
+		Up:          dropIndexIfExists("folders", "folder_name_1"),
+	},
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+	"video-downloader-server/internal/domain"
+)
+
+type APITokensBoltRepo struct {
+	db *bbolt.DB
+}
+
+func NewAPITokensBoltRepo(db *bbolt.DB) *APITokensBoltRepo {
+	return &APITokensBoltRepo{
+		db: db,
+	}
+}
+
+func (r *APITokensBoltRepo) Create(ctx context.Context, token domain.APIToken) (primitive.ObjectID, error) {
+	token.ID = primitive.NewObjectID()
+
+	err := r.db.Update(func(tx *bbolt.Tx) error {
+		return boltPut(tx, apiTokensCollection, token.ID, token)
+	})
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return token.ID, nil
+}
+
+func (r *APITokensBoltRepo) GetByTokenHash(ctx context.Context, tokenHash string) (domain.APIToken, error) {
+	var tokens []domain.APIToken
+
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		tokens, err = boltFind(tx, apiTokensCollection, func(token domain.APIToken) bool {
+			return token.TokenHash == tokenHash
+		})
+		return err
+	})
+	if err != nil {
+		return domain.APIToken{}, err
+	}
+
+	if len(tokens) == 0 {
+		return domain.APIToken{}, domain.ErrNoDocuments
+	}
+
+	return tokens[0], nil
+}
+
+func (r *APITokensBoltRepo) GetByUser(ctx context.Context, userID primitive.ObjectID) ([]domain.APIToken, error) {
+	var tokens []domain.APIToken
+
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		tokens, err = boltFind(tx, apiTokensCollection, func(token domain.APIToken) bool {
+			return token.UserID == userID
+		})
+		return err
+	})
+
+	return tokens, err
+}
+
+func (r *APITokensBoltRepo) Delete(ctx context.Context, userID primitive.ObjectID, tokenID primitive.ObjectID) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		token, err := boltGet[domain.APIToken](tx, apiTokensCollection, tokenID)
+		if err != nil {
+			return err
+		}
+
+		if token.UserID != userID {
+			return domain.ErrNoDocuments
+		}
+
+		return boltDelete(tx, apiTokensCollection, tokenID)
+	})
+}
+
+func (r *APITokensBoltRepo) SetLastUsed(ctx context.Context, tokenID primitive.ObjectID, lastUsedAt time.Time) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		token, err := boltGet[domain.APIToken](tx, apiTokensCollection, tokenID)
+		if errors.Is(err, domain.ErrNoDocuments) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		token.LastUsedAt = lastUsedAt
+
+		return boltPut(tx, apiTokensCollection, tokenID, token)
+	})
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"sync"
+	"time"
+	"video-downloader-server/internal/domain"
+)
+
+type APITokensMemoryRepo struct {
+	mu     sync.RWMutex
+	tokens map[primitive.ObjectID]domain.APIToken
+}
+
+func NewAPITokensMemoryRepo() *APITokensMemoryRepo {
+	return &APITokensMemoryRepo{
+		tokens: make(map[primitive.ObjectID]domain.APIToken),
+	}
+}
+
+func (r *APITokensMemoryRepo) Create(ctx context.Context, token domain.APIToken) (primitive.ObjectID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.tokens {
+		if existing.TokenHash == token.TokenHash {
+			return primitive.NilObjectID, domain.ErrDuplicateKey
+		}
+	}
+
+	token.ID = primitive.NewObjectID()
+	r.tokens[token.ID] = token
+
+	return token.ID, nil
+}
+
+func (r *APITokensMemoryRepo) GetByTokenHash(ctx context.Context, tokenHash string) (domain.APIToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+
+	return domain.APIToken{}, domain.ErrNoDocuments
+}
+
+func (r *APITokensMemoryRepo) GetByUser(ctx context.Context, userID primitive.ObjectID) ([]domain.APIToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var tokens []domain.APIToken
+	for _, token := range r.tokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].ID.Hex() < tokens[j].ID.Hex()
+	})
+
+	return tokens, nil
+}
+
+func (r *APITokensMemoryRepo) Delete(ctx context.Context, userID primitive.ObjectID, tokenID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[tokenID]
+	if !ok || token.UserID != userID {
+		return domain.ErrNoDocuments
+	}
+
+	delete(r.tokens, tokenID)
+
+	return nil
+}
+
+func (r *APITokensMemoryRepo) SetLastUsed(ctx context.Context, tokenID primitive.ObjectID, lastUsedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if token, ok := r.tokens[tokenID]; ok {
+		token.LastUsedAt = lastUsedAt
+		r.tokens[tokenID] = token
+	}
+
+	return nil
+}
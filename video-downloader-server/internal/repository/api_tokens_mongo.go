@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+	"video-downloader-server/internal/domain"
+)
+
+const (
+	apiTokensCollection = "api_tokens"
+)
+
+type APITokensMongoRepo struct {
+	db        *mongo.Collection
+	opTimeout time.Duration
+}
+
+func NewAPITokensMongoRepo(db *mongo.Database, opTimeout time.Duration) *APITokensMongoRepo {
+	return &APITokensMongoRepo{
+		db:        db.Collection(apiTokensCollection),
+		opTimeout: opTimeout,
+	}
+}
+
+func (r *APITokensMongoRepo) Create(ctx context.Context, token domain.APIToken) (primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	res, err := r.db.InsertOne(ctx, token)
+	if err != nil {
+		return primitive.NilObjectID, convertMongoErr(err)
+	}
+
+	return res.InsertedID.(primitive.ObjectID), nil
+}
+
+func (r *APITokensMongoRepo) GetByTokenHash(ctx context.Context, tokenHash string) (domain.APIToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	var token domain.APIToken
+	if err := r.db.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token); err != nil {
+		return domain.APIToken{}, convertMongoErr(err)
+	}
+
+	return token, nil
+}
+
+func (r *APITokensMongoRepo) GetByUser(ctx context.Context, userID primitive.ObjectID) ([]domain.APIToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	cursor, err := r.db.Find(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return nil, convertMongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var tokens []domain.APIToken
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, convertMongoErr(err)
+	}
+
+	return tokens, nil
+}
+
+func (r *APITokensMongoRepo) Delete(ctx context.Context, userID primitive.ObjectID, tokenID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	res, err := r.db.DeleteOne(ctx, bson.M{"_id": tokenID, "user_id": userID})
+	if err != nil {
+		return convertMongoErr(err)
+	}
+
+	if res.DeletedCount == 0 {
+		return domain.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *APITokensMongoRepo) SetLastUsed(ctx context.Context, tokenID primitive.ObjectID, lastUsedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	_, err := r.db.UpdateOne(ctx, bson.M{"_id": tokenID}, bson.M{"$set": bson.M{"last_used_at": lastUsedAt}})
+	return convertMongoErr(err)
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+	"video-downloader-server/internal/domain"
+)
+
+const (
+	boltOpenTimeout = 5 * time.Second
+)
+
+// OpenBolt opens the embedded database file at path, creating it together with
+// every collection bucket if needed. Documents are stored BSON encoded under
+// their ObjectID, so iteration order is insertion order.
+func OpenBolt(path string) (*bbolt.DB, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, bucket := range []string{videosCollection, foldersCollection, intentsCollection, usersCollection, sessionsCollection, apiTokensCollection, folderMembersCollection, shareLinksCollection} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func boltPut(tx *bbolt.Tx, bucket string, id primitive.ObjectID, doc interface{}) error {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return tx.Bucket([]byte(bucket)).Put(id[:], data)
+}
+
+func boltGet[T any](tx *bbolt.Tx, bucket string, id primitive.ObjectID) (T, error) {
+	var doc T
+
+	data := tx.Bucket([]byte(bucket)).Get(id[:])
+	if data == nil {
+		return doc, domain.ErrNoDocuments
+	}
+
+	err := bson.Unmarshal(data, &doc)
+	return doc, err
+}
+
+func boltDelete(tx *bbolt.Tx, bucket string, id primitive.ObjectID) error {
+	return tx.Bucket([]byte(bucket)).Delete(id[:])
+}
+
+func boltFind[T any](tx *bbolt.Tx, bucket string, match func(T) bool) ([]T, error) {
+	var docs []T
+
+	err := tx.Bucket([]byte(bucket)).ForEach(func(_, data []byte) error {
+		var doc T
+		if err := bson.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+
+		if match(doc) {
+			docs = append(docs, doc)
+		}
+
+		return nil
+	})
+
+	return docs, err
+}
+
+func all[T any](T) bool {
+	return true
+}
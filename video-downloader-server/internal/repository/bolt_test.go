@@ -0,0 +1,29 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"testing"
+	"video-downloader-server/internal/repository"
+	"video-downloader-server/internal/repository/repotest"
+)
+
+func TestBolt(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repos {
+		db, err := repository.OpenBolt(filepath.Join(t.TempDir(), "videos.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return repotest.Repos{
+			Videos:        repository.NewVideosBoltRepo(db),
+			Folders:       repository.NewFoldersBoltRepo(db),
+			Intents:       repository.NewIntentsBoltRepo(db),
+			Users:         repository.NewUsersBoltRepo(db),
+			Sessions:      repository.NewSessionsBoltRepo(db),
+			APITokens:     repository.NewAPITokensBoltRepo(db),
+			FolderMembers: repository.NewFolderMembersBoltRepo(db),
+			ShareLinks:    repository.NewShareLinksBoltRepo(db),
+		}
+	})
+}
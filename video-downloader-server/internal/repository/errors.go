@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/mongo"
+	"video-downloader-server/internal/domain"
+)
+
+// convertMongoErr maps driver errors to the backend independent repository errors.
+func convertMongoErr(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("%w: %s", domain.ErrNoDocuments, err)
+	}
+
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %s", domain.ErrDuplicateKey, err)
+	}
+
+	return err
+}
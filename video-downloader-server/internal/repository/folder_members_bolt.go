@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"video-downloader-server/internal/domain"
+)
+
+type FolderMembersBoltRepo struct {
+	db *bbolt.DB
+}
+
+func NewFolderMembersBoltRepo(db *bbolt.DB) *FolderMembersBoltRepo {
+	return &FolderMembersBoltRepo{
+		db: db,
+	}
+}
+
+// Upsert grants member.Role on member.FolderID to member.UserID, replacing
+// the role the user had there.
+func (r *FolderMembersBoltRepo) Upsert(ctx context.Context, member domain.FolderMember) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		existing, err := boltFind(tx, folderMembersCollection, withMember(member.FolderID, member.UserID))
+		if err != nil {
+			return err
+		}
+
+		if len(existing) > 0 {
+			existing[0].Role = member.Role
+			existing[0].GrantedBy = member.GrantedBy
+			return boltPut(tx, folderMembersCollection, existing[0].ID, existing[0])
+		}
+
+		member.ID = primitive.NewObjectID()
+		return boltPut(tx, folderMembersCollection, member.ID, member)
+	})
+}
+
+func (r *FolderMembersBoltRepo) Delete(ctx context.Context, folderID primitive.ObjectID, userID primitive.ObjectID) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		members, err := boltFind(tx, folderMembersCollection, withMember(folderID, userID))
+		if err != nil {
+			return err
+		}
+
+		if len(members) == 0 {
+			return domain.ErrNoDocuments
+		}
+
+		return boltDelete(tx, folderMembersCollection, members[0].ID)
+	})
+}
+
+func (r *FolderMembersBoltRepo) GetByFolder(ctx context.Context, folderID primitive.ObjectID) ([]domain.FolderMember, error) {
+	return r.find(func(member domain.FolderMember) bool {
+		return member.FolderID == folderID
+	})
+}
+
+func (r *FolderMembersBoltRepo) GetByUser(ctx context.Context, userID primitive.ObjectID) ([]domain.FolderMember, error) {
+	return r.find(func(member domain.FolderMember) bool {
+		return member.UserID == userID
+	})
+}
+
+func (r *FolderMembersBoltRepo) DeleteByFolders(ctx context.Context, foldersID []primitive.ObjectID) error {
+	deleted := make(map[primitive.ObjectID]struct{}, len(foldersID))
+	for _, folderID := range foldersID {
+		deleted[folderID] = struct{}{}
+	}
+
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		members, err := boltFind(tx, folderMembersCollection, func(member domain.FolderMember) bool {
+			_, ok := deleted[member.FolderID]
+			return ok
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, member := range members {
+			if err := boltDelete(tx, folderMembersCollection, member.ID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *FolderMembersBoltRepo) find(match func(domain.FolderMember) bool) ([]domain.FolderMember, error) {
+	var members []domain.FolderMember
+
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		members, err = boltFind(tx, folderMembersCollection, match)
+		return err
+	})
+
+	return members, err
+}
+
+func withMember(folderID primitive.ObjectID, userID primitive.ObjectID) func(domain.FolderMember) bool {
+	return func(member domain.FolderMember) bool {
+		return member.FolderID == folderID && member.UserID == userID
+	}
+}
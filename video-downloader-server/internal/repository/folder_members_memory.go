@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"sync"
+	"video-downloader-server/internal/domain"
+)
+
+type FolderMembersMemoryRepo struct {
+	mu      sync.RWMutex
+	members map[primitive.ObjectID]domain.FolderMember
+}
+
+func NewFolderMembersMemoryRepo() *FolderMembersMemoryRepo {
+	return &FolderMembersMemoryRepo{
+		members: make(map[primitive.ObjectID]domain.FolderMember),
+	}
+}
+
+// Upsert grants member.Role on member.FolderID to member.UserID, replacing
+// the role the user had there.
+func (r *FolderMembersMemoryRepo) Upsert(ctx context.Context, member domain.FolderMember) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	match := withMember(member.FolderID, member.UserID)
+	for memberID, existing := range r.members {
+		if match(existing) {
+			existing.Role = member.Role
+			existing.GrantedBy = member.GrantedBy
+			r.members[memberID] = existing
+			return nil
+		}
+	}
+
+	member.ID = primitive.NewObjectID()
+	r.members[member.ID] = member
+
+	return nil
+}
+
+func (r *FolderMembersMemoryRepo) Delete(ctx context.Context, folderID primitive.ObjectID, userID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	match := withMember(folderID, userID)
+	for memberID, member := range r.members {
+		if match(member) {
+			delete(r.members, memberID)
+			return nil
+		}
+	}
+
+	return domain.ErrNoDocuments
+}
+
+func (r *FolderMembersMemoryRepo) GetByFolder(ctx context.Context, folderID primitive.ObjectID) ([]domain.FolderMember, error) {
+	return r.filter(func(member domain.FolderMember) bool {
+		return member.FolderID == folderID
+	}), nil
+}
+
+func (r *FolderMembersMemoryRepo) GetByUser(ctx context.Context, userID primitive.ObjectID) ([]domain.FolderMember, error) {
+	return r.filter(func(member domain.FolderMember) bool {
+		return member.UserID == userID
+	}), nil
+}
+
+func (r *FolderMembersMemoryRepo) DeleteByFolders(ctx context.Context, foldersID []primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, folderID := range foldersID {
+		for memberID, member := range r.members {
+			if member.FolderID == folderID {
+				delete(r.members, memberID)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *FolderMembersMemoryRepo) filter(match func(domain.FolderMember) bool) []domain.FolderMember {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var members []domain.FolderMember
+	for _, member := range r.members {
+		if match(member) {
+			members = append(members, member)
+		}
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].ID.Hex() < members[j].ID.Hex()
+	})
+
+	return members
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+	"video-downloader-server/internal/domain"
+)
+
+const (
+	folderMembersCollection = "folder_members"
+)
+
+type FolderMembersMongoRepo struct {
+	db        *mongo.Collection
+	opTimeout time.Duration
+}
+
+func NewFolderMembersMongoRepo(db *mongo.Database, opTimeout time.Duration) *FolderMembersMongoRepo {
+	return &FolderMembersMongoRepo{
+		db:        db.Collection(folderMembersCollection),
+		opTimeout: opTimeout,
+	}
+}
+
+// Upsert grants member.Role on member.FolderID to member.UserID, replacing
+// the role the user had there.
+func (r *FolderMembersMongoRepo) Upsert(ctx context.Context, member domain.FolderMember) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	_, err := r.db.UpdateOne(ctx,
+		bson.M{"folder_id": member.FolderID, "user_id": member.UserID},
+		bson.M{
+			"$set":         bson.M{"role": member.Role, "granted_by": member.GrantedBy},
+			"$setOnInsert": bson.M{"created_at": member.CreatedAt},
+		},
+		options.Update().SetUpsert(true),
+	)
+
+	return convertMongoErr(err)
+}
+
+func (r *FolderMembersMongoRepo) Delete(ctx context.Context, folderID primitive.ObjectID, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	res, err := r.db.DeleteOne(ctx, bson.M{"folder_id": folderID, "user_id": userID})
+	if err != nil {
+		return convertMongoErr(err)
+	}
+
+	if res.DeletedCount == 0 {
+		return domain.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *FolderMembersMongoRepo) GetByFolder(ctx context.Context, folderID primitive.ObjectID) ([]domain.FolderMember, error) {
+	return r.find(ctx, bson.M{"folder_id": folderID})
+}
+
+func (r *FolderMembersMongoRepo) GetByUser(ctx context.Context, userID primitive.ObjectID) ([]domain.FolderMember, error) {
+	return r.find(ctx, bson.M{"user_id": userID})
+}
+
+func (r *FolderMembersMongoRepo) DeleteByFolders(ctx context.Context, foldersID []primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	_, err := r.db.DeleteMany(ctx, bson.M{"folder_id": bson.M{"$in": foldersID}})
+	return convertMongoErr(err)
+}
+
+func (r *FolderMembersMongoRepo) find(ctx context.Context, filter bson.M) ([]domain.FolderMember, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	cursor, err := r.db.Find(ctx, filter, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, convertMongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var members []domain.FolderMember
+	if err := cursor.All(ctx, &members); err != nil {
+		return nil, convertMongoErr(err)
+	}
+
+	return members, nil
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"video-downloader-server/internal/domain"
+)
+
+type FoldersBoltRepo struct {
+	db *bbolt.DB
+}
+
+func NewFoldersBoltRepo(db *bbolt.DB) *FoldersBoltRepo {
+	return &FoldersBoltRepo{
+		db: db,
+	}
+}
+
+func (r *FoldersBoltRepo) CheckExistenceByID(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) error {
+	_, err := r.get(ownerID, folderID)
+	return err
+}
+
+func (r *FoldersBoltRepo) CheckExistenceByName(ctx context.Context, ownerID primitive.ObjectID, folderName string, parentDirID primitive.ObjectID) error {
+	folders, err := r.find(func(folder domain.Folder) bool {
+		return folder.OwnerID == ownerID && folder.FolderName == folderName && (parentDirID == primitive.NilObjectID || folder.ParentDirID == parentDirID)
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(folders) == 0 {
+		return domain.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *FoldersBoltRepo) Create(ctx context.Context, ownerID primitive.ObjectID, folderName string, parentDirID primitive.ObjectID) (primitive.ObjectID, error) {
+	folder := domain.Folder{
+		ID:          primitive.NewObjectID(),
+		OwnerID:     ownerID,
+		FolderName:  folderName,
+		ParentDirID: parentDirID,
+	}
+
+	err := r.db.Update(func(tx *bbolt.Tx) error {
+		return boltPut(tx, foldersCollection, folder.ID, folder)
+	})
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return folder.ID, nil
+}
+
+func (r *FoldersBoltRepo) GetParentDirID(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) (primitive.ObjectID, error) {
+	folder, err := r.get(ownerID, folderID)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return folder.ParentDirID, nil
+}
+
+func (r *FoldersBoltRepo) UpdateName(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID, newFolderName string) error {
+	return r.update(ownerID, folderID, func(folder *domain.Folder) {
+		folder.FolderName = newFolderName
+	})
+}
+
+func (r *FoldersBoltRepo) GetName(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) (string, error) {
+	folder, err := r.get(ownerID, folderID)
+	if err != nil {
+		return "", err
+	}
+
+	return folder.FolderName, nil
+}
+
+func (r *FoldersBoltRepo) Move(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID, parentDirID primitive.ObjectID) error {
+	return r.update(ownerID, folderID, func(folder *domain.Folder) {
+		folder.ParentDirID = parentDirID
+	})
+}
+
+func (r *FoldersBoltRepo) GetAllNestedFolders(ctx context.Context, ownerID primitive.ObjectID, parentDirID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	folders, err := r.find(ownedFolder(ownerID, all[domain.Folder]))
+	if err != nil {
+		return nil, err
+	}
+
+	return nestedFolders(folders, parentDirID), nil
+}
+
+func (r *FoldersBoltRepo) DeleteAllNestedFolders(ctx context.Context, foldersID []primitive.ObjectID) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		for _, folderID := range foldersID {
+			if err := boltDelete(tx, foldersCollection, folderID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *FoldersBoltRepo) GetNestedFolders(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) ([]domain.Folder, error) {
+	return r.find(func(folder domain.Folder) bool {
+		return folder.OwnerID == ownerID && folder.ParentDirID == folderID
+	})
+}
+
+func (r *FoldersBoltRepo) GetAll(ctx context.Context) ([]domain.Folder, error) {
+	return r.find(all[domain.Folder])
+}
+
+func (r *FoldersBoltRepo) UnsetParent(ctx context.Context, folderID primitive.ObjectID) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		folder, err := boltGet[domain.Folder](tx, foldersCollection, folderID)
+		if err != nil {
+			return nil
+		}
+
+		folder.ParentDirID = primitive.NilObjectID
+
+		return boltPut(tx, foldersCollection, folderID, folder)
+	})
+}
+
+// Lookup returns the folder whatever its owner, for access checks.
+func (r *FoldersBoltRepo) Lookup(ctx context.Context, folderID primitive.ObjectID) (domain.Folder, error) {
+	var folder domain.Folder
+
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		folder, err = boltGet[domain.Folder](tx, foldersCollection, folderID)
+		return err
+	})
+
+	return folder, err
+}
+
+// ClaimUnowned gives every folder without an owner to ownerID.
+func (r *FoldersBoltRepo) ClaimUnowned(ctx context.Context, ownerID primitive.ObjectID) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		folders, err := boltFind(tx, foldersCollection, ownedFolder(primitive.NilObjectID, all[domain.Folder]))
+		if err != nil {
+			return err
+		}
+
+		for _, folder := range folders {
+			folder.OwnerID = ownerID
+			if err := boltPut(tx, foldersCollection, folder.ID, folder); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *FoldersBoltRepo) get(ownerID primitive.ObjectID, folderID primitive.ObjectID) (domain.Folder, error) {
+	var folder domain.Folder
+
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		folder, err = boltGet[domain.Folder](tx, foldersCollection, folderID)
+		return err
+	})
+	if err != nil {
+		return domain.Folder{}, err
+	}
+
+	if folder.OwnerID != ownerID {
+		return domain.Folder{}, domain.ErrNoDocuments
+	}
+
+	return folder, nil
+}
+
+func (r *FoldersBoltRepo) find(match func(domain.Folder) bool) ([]domain.Folder, error) {
+	var folders []domain.Folder
+
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		folders, err = boltFind(tx, foldersCollection, match)
+		return err
+	})
+
+	return folders, err
+}
+
+func (r *FoldersBoltRepo) update(ownerID primitive.ObjectID, folderID primitive.ObjectID, apply func(folder *domain.Folder)) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		folder, err := boltGet[domain.Folder](tx, foldersCollection, folderID)
+		if err != nil || folder.OwnerID != ownerID {
+			return nil
+		}
+
+		apply(&folder)
+
+		return boltPut(tx, foldersCollection, folderID, folder)
+	})
+}
@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"sync"
+	"video-downloader-server/internal/domain"
+)
+
+type FoldersMemoryRepo struct {
+	mu      sync.RWMutex
+	folders map[primitive.ObjectID]domain.Folder
+}
+
+func NewFoldersMemoryRepo() *FoldersMemoryRepo {
+	return &FoldersMemoryRepo{
+		folders: make(map[primitive.ObjectID]domain.Folder),
+	}
+}
+
+func (r *FoldersMemoryRepo) CheckExistenceByID(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if folder, ok := r.folders[folderID]; !ok || folder.OwnerID != ownerID {
+		return domain.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *FoldersMemoryRepo) CheckExistenceByName(ctx context.Context, ownerID primitive.ObjectID, folderName string, parentDirID primitive.ObjectID) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, folder := range r.folders {
+		if folder.OwnerID != ownerID || folder.FolderName != folderName {
+			continue
+		}
+
+		if parentDirID == primitive.NilObjectID || folder.ParentDirID == parentDirID {
+			return nil
+		}
+	}
+
+	return domain.ErrNoDocuments
+}
+
+func (r *FoldersMemoryRepo) Create(ctx context.Context, ownerID primitive.ObjectID, folderName string, parentDirID primitive.ObjectID) (primitive.ObjectID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	folder := domain.Folder{
+		ID:          primitive.NewObjectID(),
+		OwnerID:     ownerID,
+		FolderName:  folderName,
+		ParentDirID: parentDirID,
+	}
+	r.folders[folder.ID] = folder
+
+	return folder.ID, nil
+}
+
+func (r *FoldersMemoryRepo) GetParentDirID(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) (primitive.ObjectID, error) {
+	folder, err := r.get(ownerID, folderID)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return folder.ParentDirID, nil
+}
+
+func (r *FoldersMemoryRepo) UpdateName(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID, newFolderName string) error {
+	return r.update(ownerID, folderID, func(folder *domain.Folder) {
+		folder.FolderName = newFolderName
+	})
+}
+
+func (r *FoldersMemoryRepo) GetName(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) (string, error) {
+	folder, err := r.get(ownerID, folderID)
+	if err != nil {
+		return "", err
+	}
+
+	return folder.FolderName, nil
+}
+
+func (r *FoldersMemoryRepo) Move(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID, parentDirID primitive.ObjectID) error {
+	return r.update(ownerID, folderID, func(folder *domain.Folder) {
+		folder.ParentDirID = parentDirID
+	})
+}
+
+func (r *FoldersMemoryRepo) GetAllNestedFolders(ctx context.Context, ownerID primitive.ObjectID, parentDirID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var folders []domain.Folder
+	for _, folder := range r.sorted() {
+		if folder.OwnerID == ownerID {
+			folders = append(folders, folder)
+		}
+	}
+
+	return nestedFolders(folders, parentDirID), nil
+}
+
+func (r *FoldersMemoryRepo) DeleteAllNestedFolders(ctx context.Context, foldersID []primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, folderID := range foldersID {
+		delete(r.folders, folderID)
+	}
+
+	return nil
+}
+
+func (r *FoldersMemoryRepo) GetNestedFolders(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) ([]domain.Folder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var folders []domain.Folder
+	for _, folder := range r.sorted() {
+		if folder.OwnerID == ownerID && folder.ParentDirID == folderID {
+			folders = append(folders, folder)
+		}
+	}
+
+	return folders, nil
+}
+
+func (r *FoldersMemoryRepo) GetAll(ctx context.Context) ([]domain.Folder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.sorted(), nil
+}
+
+func (r *FoldersMemoryRepo) UnsetParent(ctx context.Context, folderID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if folder, ok := r.folders[folderID]; ok {
+		folder.ParentDirID = primitive.NilObjectID
+		r.folders[folderID] = folder
+	}
+
+	return nil
+}
+
+// Lookup returns the folder whatever its owner, for access checks.
+func (r *FoldersMemoryRepo) Lookup(ctx context.Context, folderID primitive.ObjectID) (domain.Folder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	folder, ok := r.folders[folderID]
+	if !ok {
+		return domain.Folder{}, domain.ErrNoDocuments
+	}
+
+	return folder, nil
+}
+
+// ClaimUnowned gives every folder without an owner to ownerID.
+func (r *FoldersMemoryRepo) ClaimUnowned(ctx context.Context, ownerID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for folderID, folder := range r.folders {
+		if folder.OwnerID == primitive.NilObjectID {
+			folder.OwnerID = ownerID
+			r.folders[folderID] = folder
+		}
+	}
+
+	return nil
+}
+
+func (r *FoldersMemoryRepo) get(ownerID primitive.ObjectID, folderID primitive.ObjectID) (domain.Folder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	folder, ok := r.folders[folderID]
+	if !ok || folder.OwnerID != ownerID {
+		return domain.Folder{}, domain.ErrNoDocuments
+	}
+
+	return folder, nil
+}
+
+func (r *FoldersMemoryRepo) update(ownerID primitive.ObjectID, folderID primitive.ObjectID, apply func(folder *domain.Folder)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	folder, ok := r.folders[folderID]
+	if !ok || folder.OwnerID != ownerID {
+		return nil
+	}
+
+	apply(&folder)
+	r.folders[folderID] = folder
+
+	return nil
+}
+
+func (r *FoldersMemoryRepo) sorted() []domain.Folder {
+	folders := make([]domain.Folder, 0, len(r.folders))
+	for _, folder := range r.folders {
+		folders = append(folders, folder)
+	}
+
+	sort.Slice(folders, func(i, j int) bool {
+		return folders[i].ID.Hex() < folders[j].ID.Hex()
+	})
+
+	return folders
+}
+
+// nestedFolders returns the ids of every folder below parentDirID, like the
+// $graphLookup pipeline of the mongo implementation.
+func nestedFolders(folders []domain.Folder, parentDirID primitive.ObjectID) []primitive.ObjectID {
+	children := make(map[primitive.ObjectID][]primitive.ObjectID)
+	for _, folder := range folders {
+		if folder.ParentDirID != primitive.NilObjectID {
+			children[folder.ParentDirID] = append(children[folder.ParentDirID], folder.ID)
+		}
+	}
+
+	var res []primitive.ObjectID
+	visited := map[primitive.ObjectID]struct{}{parentDirID: {}}
+	queue := append([]primitive.ObjectID(nil), children[parentDirID]...)
+
+	for len(queue) > 0 {
+		folderID := queue[0]
+		queue = queue[1:]
+
+		if _, ok := visited[folderID]; ok {
+			continue
+		}
+		visited[folderID] = struct{}{}
+
+		res = append(res, folderID)
+		queue = append(queue, children[folderID]...)
+	}
+
+	return res
+}
+
+func ownedFolder(ownerID primitive.ObjectID, match func(domain.Folder) bool) func(domain.Folder) bool {
+	return func(folder domain.Folder) bool {
+		return folder.OwnerID == ownerID && match(folder)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
 	"video-downloader-server/internal/domain"
 )
 
@@ -13,32 +14,43 @@ const (
 	foldersCollection = "folders"
 )
 
-type FoldersRepo struct {
-	db *mongo.Collection
+type FoldersMongoRepo struct {
+	db        *mongo.Collection
+	opTimeout time.Duration
 }
 
-func NewFoldersRepo(db *mongo.Database) *FoldersRepo {
-	return &FoldersRepo{
-		db: db.Collection(foldersCollection),
+func NewFoldersMongoRepo(db *mongo.Database, opTimeout time.Duration) *FoldersMongoRepo {
+	return &FoldersMongoRepo{
+		db:        db.Collection(foldersCollection),
+		opTimeout: opTimeout,
 	}
 }
 
-func (r *FoldersRepo) CheckExistenceByID(ctx context.Context, folderID primitive.ObjectID) error {
-	return r.db.FindOne(ctx, bson.M{"_id": folderID}).Err()
+func (r *FoldersMongoRepo) CheckExistenceByID(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	return convertMongoErr(r.db.FindOne(ctx, bson.M{"_id": folderID, "owner_id": ownerID}).Err())
 }
 
-func (r *FoldersRepo) CheckExistenceByName(ctx context.Context, folderName string, parentDirID primitive.ObjectID) error {
-	filter := bson.M{"folder_name": folderName}
+func (r *FoldersMongoRepo) CheckExistenceByName(ctx context.Context, ownerID primitive.ObjectID, folderName string, parentDirID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	filter := bson.M{"folder_name": folderName, "owner_id": ownerID}
 
 	if parentDirID != primitive.NilObjectID {
 		filter["parent_dir_id"] = parentDirID
 	}
 
-	return r.db.FindOne(ctx, filter).Err()
+	return convertMongoErr(r.db.FindOne(ctx, filter).Err())
 }
 
-func (r *FoldersRepo) Create(ctx context.Context, folderName string, parentDirID primitive.ObjectID) (primitive.ObjectID, error) {
-	doc := bson.M{"folder_name": folderName}
+func (r *FoldersMongoRepo) Create(ctx context.Context, ownerID primitive.ObjectID, folderName string, parentDirID primitive.ObjectID) (primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	doc := bson.M{"folder_name": folderName, "owner_id": ownerID}
 
 	if parentDirID != primitive.NilObjectID {
 		doc["parent_dir_id"] = parentDirID
@@ -46,46 +58,61 @@ func (r *FoldersRepo) Create(ctx context.Context, folderName string, parentDirID
 
 	res, err := r.db.InsertOne(ctx, doc)
 	if err != nil {
-		return primitive.NilObjectID, err
+		return primitive.NilObjectID, convertMongoErr(err)
 	}
 
 	return res.InsertedID.(primitive.ObjectID), nil
 }
 
-func (r *FoldersRepo) GetParentDirID(ctx context.Context, folderID primitive.ObjectID) (primitive.ObjectID, error) {
+func (r *FoldersMongoRepo) GetParentDirID(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) (primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
 	var folder domain.Folder
 
-	if err := r.db.FindOne(ctx, bson.M{"_id": folderID}, options.FindOne().SetProjection(bson.M{"parent_dir_id": 1, "_id": 0})).Decode(&folder); err != nil {
-		return primitive.NilObjectID, err
+	if err := r.db.FindOne(ctx, bson.M{"_id": folderID, "owner_id": ownerID}, options.FindOne().SetProjection(bson.M{"parent_dir_id": 1, "_id": 0})).Decode(&folder); err != nil {
+		return primitive.NilObjectID, convertMongoErr(err)
 	}
 
 	return folder.ParentDirID, nil
 }
 
-func (r *FoldersRepo) UpdateName(ctx context.Context, folderID primitive.ObjectID, newFolderName string) error {
-	_, err := r.db.UpdateOne(ctx, bson.M{"_id": folderID}, bson.M{"$set": bson.M{"folder_name": newFolderName}})
-	return err
+func (r *FoldersMongoRepo) UpdateName(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID, newFolderName string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	_, err := r.db.UpdateOne(ctx, bson.M{"_id": folderID, "owner_id": ownerID}, bson.M{"$set": bson.M{"folder_name": newFolderName}})
+	return convertMongoErr(err)
 }
 
-func (r *FoldersRepo) GetName(ctx context.Context, folderID primitive.ObjectID) (string, error) {
+func (r *FoldersMongoRepo) GetName(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
 	var folder domain.Folder
 
-	if err := r.db.FindOne(ctx, bson.M{"_id": folderID}, options.FindOne().SetProjection(bson.M{"folder_name": 1, "_id": 0})).Decode(&folder); err != nil {
-		return "", err
+	if err := r.db.FindOne(ctx, bson.M{"_id": folderID, "owner_id": ownerID}, options.FindOne().SetProjection(bson.M{"folder_name": 1, "_id": 0})).Decode(&folder); err != nil {
+		return "", convertMongoErr(err)
 	}
 
 	return folder.FolderName, nil
 }
 
-func (r *FoldersRepo) Move(ctx context.Context, folderID primitive.ObjectID, parentDirID primitive.ObjectID) error {
-	_, err := r.db.UpdateOne(ctx, bson.M{"_id": folderID}, bson.M{"$set": bson.M{"parent_dir_id": parentDirID}})
-	return err
+func (r *FoldersMongoRepo) Move(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID, parentDirID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	_, err := r.db.UpdateOne(ctx, bson.M{"_id": folderID, "owner_id": ownerID}, bson.M{"$set": bson.M{"parent_dir_id": parentDirID}})
+	return convertMongoErr(err)
 }
 
-func (r *FoldersRepo) GetAllNestedFolders(ctx context.Context, parentDirID primitive.ObjectID) ([]primitive.ObjectID, error) {
+func (r *FoldersMongoRepo) GetAllNestedFolders(ctx context.Context, ownerID primitive.ObjectID, parentDirID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
 	pipeline := mongo.Pipeline{
 		{
-			{"$match", bson.D{{"parent_dir_id", parentDirID}}},
+			{"$match", bson.D{{"parent_dir_id", parentDirID}, {"owner_id", ownerID}}},
 		},
 		{
 			{"$graphLookup", bson.D{
@@ -93,6 +120,7 @@ func (r *FoldersRepo) GetAllNestedFolders(ctx context.Context, parentDirID primi
 				{"startWith", "$_id"},
 				{"connectFromField", "_id"},
 				{"connectToField", "parent_dir_id"},
+				{"restrictSearchWithMatch", bson.D{{"owner_id", ownerID}}},
 				{"as", "nestedFolders"},
 			}},
 		},
@@ -124,7 +152,7 @@ func (r *FoldersRepo) GetAllNestedFolders(ctx context.Context, parentDirID primi
 
 	cursor, err := r.db.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, err
+		return nil, convertMongoErr(err)
 	}
 	defer cursor.Close(ctx)
 
@@ -133,27 +161,33 @@ func (r *FoldersRepo) GetAllNestedFolders(ctx context.Context, parentDirID primi
 
 	for cursor.Next(ctx) {
 		if err := cursor.Decode(&folder); err != nil {
-			return nil, err
+			return nil, convertMongoErr(err)
 		}
 		results = append(results, folder.ID)
 	}
 
 	if err := cursor.Err(); err != nil {
-		return nil, err
+		return nil, convertMongoErr(err)
 	}
 
 	return results, nil
 }
 
-func (r *FoldersRepo) DeleteAllNestedFolders(ctx context.Context, foldersID []primitive.ObjectID) error {
+func (r *FoldersMongoRepo) DeleteAllNestedFolders(ctx context.Context, foldersID []primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
 	_, err := r.db.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": foldersID}})
-	return err
+	return convertMongoErr(err)
 }
 
-func (r *FoldersRepo) GetNestedFolders(ctx context.Context, folderID primitive.ObjectID) ([]domain.Folder, error) {
-	cursor, err := r.db.Find(ctx, bson.M{"parent_dir_id": folderID})
+func (r *FoldersMongoRepo) GetNestedFolders(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) ([]domain.Folder, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	cursor, err := r.db.Find(ctx, bson.M{"parent_dir_id": folderID, "owner_id": ownerID})
 	if err != nil {
-		return nil, err
+		return nil, convertMongoErr(err)
 	}
 	defer cursor.Close(ctx)
 
@@ -161,14 +195,63 @@ func (r *FoldersRepo) GetNestedFolders(ctx context.Context, folderID primitive.O
 	var folders []domain.Folder
 	for cursor.Next(ctx) {
 		if err := cursor.Decode(&folder); err != nil {
-			return nil, err
+			return nil, convertMongoErr(err)
 		}
 		folders = append(folders, folder)
 	}
 
 	if err := cursor.Err(); err != nil {
-		return nil, err
+		return nil, convertMongoErr(err)
+	}
+
+	return folders, nil
+}
+
+func (r *FoldersMongoRepo) GetAll(ctx context.Context) ([]domain.Folder, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	cursor, err := r.db.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, convertMongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var folders []domain.Folder
+	if err := cursor.All(ctx, &folders); err != nil {
+		return nil, convertMongoErr(err)
 	}
 
 	return folders, nil
 }
+
+func (r *FoldersMongoRepo) UnsetParent(ctx context.Context, folderID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	_, err := r.db.UpdateOne(ctx, bson.M{"_id": folderID}, bson.M{"$unset": bson.M{"parent_dir_id": ""}})
+	return convertMongoErr(err)
+}
+
+// Lookup returns the folder whatever its owner, for access checks.
+func (r *FoldersMongoRepo) Lookup(ctx context.Context, folderID primitive.ObjectID) (domain.Folder, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	var folder domain.Folder
+
+	if err := r.db.FindOne(ctx, bson.M{"_id": folderID}).Decode(&folder); err != nil {
+		return domain.Folder{}, convertMongoErr(err)
+	}
+
+	return folder, nil
+}
+
+// ClaimUnowned gives every folder without an owner to ownerID.
+func (r *FoldersMongoRepo) ClaimUnowned(ctx context.Context, ownerID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	_, err := r.db.UpdateMany(ctx, bson.M{"owner_id": bson.M{"$in": bson.A{nil, primitive.NilObjectID}}}, bson.M{"$set": bson.M{"owner_id": ownerID}})
+	return convertMongoErr(err)
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"video-downloader-server/internal/domain"
+)
+
+type IntentsBoltRepo struct {
+	db *bbolt.DB
+}
+
+func NewIntentsBoltRepo(db *bbolt.DB) *IntentsBoltRepo {
+	return &IntentsBoltRepo{
+		db: db,
+	}
+}
+
+func (r *IntentsBoltRepo) Create(ctx context.Context, intent domain.Intent) (primitive.ObjectID, error) {
+	intent.ID = primitive.NewObjectID()
+
+	err := r.db.Update(func(tx *bbolt.Tx) error {
+		return boltPut(tx, intentsCollection, intent.ID, intent)
+	})
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return intent.ID, nil
+}
+
+func (r *IntentsBoltRepo) GetAll(ctx context.Context) ([]domain.Intent, error) {
+	var intents []domain.Intent
+
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		intents, err = boltFind(tx, intentsCollection, all[domain.Intent])
+		return err
+	})
+
+	return intents, err
+}
+
+func (r *IntentsBoltRepo) RecordFailure(ctx context.Context, intentID primitive.ObjectID, errMsg string) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		intent, err := boltGet[domain.Intent](tx, intentsCollection, intentID)
+		if err != nil {
+			return nil
+		}
+
+		intent.Attempts++
+		intent.LastError = errMsg
+
+		return boltPut(tx, intentsCollection, intentID, intent)
+	})
+}
+
+func (r *IntentsBoltRepo) Delete(ctx context.Context, intentID primitive.ObjectID) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		return boltDelete(tx, intentsCollection, intentID)
+	})
+}
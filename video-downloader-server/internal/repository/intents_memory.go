@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"sync"
+	"video-downloader-server/internal/domain"
+)
+
+type IntentsMemoryRepo struct {
+	mu      sync.Mutex
+	intents map[primitive.ObjectID]domain.Intent
+}
+
+func NewIntentsMemoryRepo() *IntentsMemoryRepo {
+	return &IntentsMemoryRepo{
+		intents: make(map[primitive.ObjectID]domain.Intent),
+	}
+}
+
+func (r *IntentsMemoryRepo) Create(ctx context.Context, intent domain.Intent) (primitive.ObjectID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	intent.ID = primitive.NewObjectID()
+	r.intents[intent.ID] = intent
+
+	return intent.ID, nil
+}
+
+func (r *IntentsMemoryRepo) GetAll(ctx context.Context) ([]domain.Intent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	intents := make([]domain.Intent, 0, len(r.intents))
+	for _, intent := range r.intents {
+		intents = append(intents, intent)
+	}
+
+	sort.Slice(intents, func(i, j int) bool {
+		return intents[i].CreatedAt.Before(intents[j].CreatedAt)
+	})
+
+	return intents, nil
+}
+
+func (r *IntentsMemoryRepo) RecordFailure(ctx context.Context, intentID primitive.ObjectID, errMsg string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if intent, ok := r.intents[intentID]; ok {
+		intent.Attempts++
+		intent.LastError = errMsg
+		r.intents[intentID] = intent
+	}
+
+	return nil
+}
+
+func (r *IntentsMemoryRepo) Delete(ctx context.Context, intentID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.intents, intentID)
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+	"video-downloader-server/internal/domain"
+)
+
+const (
+	intentsCollection = "intents"
+)
+
+type IntentsMongoRepo struct {
+	db        *mongo.Collection
+	opTimeout time.Duration
+}
+
+func NewIntentsMongoRepo(db *mongo.Database, opTimeout time.Duration) *IntentsMongoRepo {
+	return &IntentsMongoRepo{
+		db:        db.Collection(intentsCollection),
+		opTimeout: opTimeout,
+	}
+}
+
+func (r *IntentsMongoRepo) Create(ctx context.Context, intent domain.Intent) (primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	res, err := r.db.InsertOne(ctx, intent)
+	if err != nil {
+		return primitive.NilObjectID, convertMongoErr(err)
+	}
+
+	return res.InsertedID.(primitive.ObjectID), nil
+}
+
+func (r *IntentsMongoRepo) GetAll(ctx context.Context) ([]domain.Intent, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	cursor, err := r.db.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return nil, convertMongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var intents []domain.Intent
+	if err := cursor.All(ctx, &intents); err != nil {
+		return nil, convertMongoErr(err)
+	}
+
+	return intents, nil
+}
+
+func (r *IntentsMongoRepo) RecordFailure(ctx context.Context, intentID primitive.ObjectID, errMsg string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	_, err := r.db.UpdateOne(ctx, bson.M{"_id": intentID}, bson.M{"$inc": bson.M{"attempts": 1}, "$set": bson.M{"last_error": errMsg}})
+	return convertMongoErr(err)
+}
+
+func (r *IntentsMongoRepo) Delete(ctx context.Context, intentID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	_, err := r.db.DeleteOne(ctx, bson.M{"_id": intentID})
+	return convertMongoErr(err)
+}
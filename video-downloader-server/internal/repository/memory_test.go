@@ -0,0 +1,22 @@
+package repository_test
+
+import (
+	"testing"
+	"video-downloader-server/internal/repository"
+	"video-downloader-server/internal/repository/repotest"
+)
+
+func TestMemory(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repos {
+		return repotest.Repos{
+			Videos:        repository.NewVideosMemoryRepo(),
+			Folders:       repository.NewFoldersMemoryRepo(),
+			Intents:       repository.NewIntentsMemoryRepo(),
+			Users:         repository.NewUsersMemoryRepo(),
+			Sessions:      repository.NewSessionsMemoryRepo(),
+			APITokens:     repository.NewAPITokensMemoryRepo(),
+			FolderMembers: repository.NewFolderMembersMemoryRepo(),
+			ShareLinks:    repository.NewShareLinksMemoryRepo(),
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package repository_test
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"testing"
+	"time"
+	"video-downloader-server/internal/migrations"
+	"video-downloader-server/internal/repository"
+	"video-downloader-server/internal/repository/repotest"
+)
+
+// mongoTestURIEnv names the MongoDB the Mongo backend is tested against,
+// the test is skipped when it is not set. Every test gets its own database,
+// which is dropped afterwards.
+const mongoTestURIEnv = "MONGO_TEST_URI"
+
+const mongoTestOpTimeout = 10 * time.Second
+
+func TestMongo(t *testing.T) {
+	uri := os.Getenv(mongoTestURIEnv)
+	if uri == "" {
+		t.Skipf("%s is not set", mongoTestURIEnv)
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetServerSelectionTimeout(mongoTestOpTimeout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(ctx) })
+
+	repotest.Run(t, func(t *testing.T) repotest.Repos {
+		db := client.Database("repotest_" + primitive.NewObjectID().Hex())
+		t.Cleanup(func() { db.Drop(ctx) })
+
+		if err := migrations.Run(ctx, db, mongoTestOpTimeout, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+
+		return repotest.Repos{
+			Videos:        repository.NewVideosMongoRepo(db, mongoTestOpTimeout),
+			Folders:       repository.NewFoldersMongoRepo(db, mongoTestOpTimeout),
+			Intents:       repository.NewIntentsMongoRepo(db, mongoTestOpTimeout),
+			Users:         repository.NewUsersMongoRepo(db, mongoTestOpTimeout),
+			Sessions:      repository.NewSessionsMongoRepo(db, mongoTestOpTimeout),
+			APITokens:     repository.NewAPITokensMongoRepo(db, mongoTestOpTimeout),
+			FolderMembers: repository.NewFolderMembersMongoRepo(db, mongoTestOpTimeout),
+			ShareLinks:    repository.NewShareLinksMongoRepo(db, mongoTestOpTimeout),
+		}
+	})
+}
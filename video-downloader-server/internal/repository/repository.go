@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+	"video-downloader-server/internal/domain"
+)
+
+// Videos is implemented by every storage backend for the videos collection.
+// Methods taking an ownerID only see the videos of that user, the others are
+// used by the maintenance services and see every video.
+type Videos interface {
+	Create(ctx context.Context, video domain.Video) (primitive.ObjectID, error)
+	GetByID(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID) (domain.Video, error)
+	GetByName(ctx context.Context, ownerID primitive.ObjectID, videoName string, folderID primitive.ObjectID) (domain.Video, error)
+	GetRealPath(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID) (string, error)
+	Rename(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, newVideoName string) error
+	Move(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, folderID primitive.ObjectID, videoName string) error
+	SetSubtitles(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, subtitles []domain.Subtitle) error
+	SetChapters(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, chapters []domain.Chapter) error
+	Delete(ctx context.Context, videoID primitive.ObjectID) error
+	GetPathsByFolders(ctx context.Context, ownerID primitive.ObjectID, foldersID []primitive.ObjectID) ([]string, []string, error)
+	DeleteVideos(ctx context.Context, foldersID []primitive.ObjectID) error
+	GetVideos(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) ([]domain.Video, error)
+	GetAll(ctx context.Context) ([]domain.Video, error)
+	Lookup(ctx context.Context, videoID primitive.ObjectID) (domain.Video, error)
+	ClaimUnowned(ctx context.Context, ownerID primitive.ObjectID) error
+}
+
+// Folders is implemented by every storage backend for the folders collection.
+// Methods taking an ownerID only see the folders of that user, the others are
+// used by the maintenance services and see every folder.
+type Folders interface {
+	CheckExistenceByID(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) error
+	CheckExistenceByName(ctx context.Context, ownerID primitive.ObjectID, folderName string, parentDirID primitive.ObjectID) error
+	Create(ctx context.Context, ownerID primitive.ObjectID, folderName string, parentDirID primitive.ObjectID) (primitive.ObjectID, error)
+	GetParentDirID(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) (primitive.ObjectID, error)
+	UpdateName(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID, newFolderName string) error
+	GetName(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) (string, error)
+	Move(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID, parentDirID primitive.ObjectID) error
+	GetAllNestedFolders(ctx context.Context, ownerID primitive.ObjectID, parentDirID primitive.ObjectID) ([]primitive.ObjectID, error)
+	DeleteAllNestedFolders(ctx context.Context, foldersID []primitive.ObjectID) error
+	GetNestedFolders(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) ([]domain.Folder, error)
+	GetAll(ctx context.Context) ([]domain.Folder, error)
+	UnsetParent(ctx context.Context, folderID primitive.ObjectID) error
+	Lookup(ctx context.Context, folderID primitive.ObjectID) (domain.Folder, error)
+	ClaimUnowned(ctx context.Context, ownerID primitive.ObjectID) error
+}
+
+// Intents is implemented by every storage backend for the intents collection.
+type Intents interface {
+	Create(ctx context.Context, intent domain.Intent) (primitive.ObjectID, error)
+	GetAll(ctx context.Context) ([]domain.Intent, error)
+	RecordFailure(ctx context.Context, intentID primitive.ObjectID, errMsg string) error
+	Delete(ctx context.Context, intentID primitive.ObjectID) error
+}
+
+// Users is implemented by every storage backend for the users collection.
+type Users interface {
+	Create(ctx context.Context, user domain.User) (primitive.ObjectID, error)
+	GetByID(ctx context.Context, userID primitive.ObjectID) (domain.User, error)
+	GetByUsername(ctx context.Context, username string) (domain.User, error)
+	Count(ctx context.Context) (int64, error)
+}
+
+// FolderMembers is implemented by every storage backend for the folder members
+// collection, the sharing grants of folders.
+type FolderMembers interface {
+	Upsert(ctx context.Context, member domain.FolderMember) error
+	Delete(ctx context.Context, folderID primitive.ObjectID, userID primitive.ObjectID) error
+	GetByFolder(ctx context.Context, folderID primitive.ObjectID) ([]domain.FolderMember, error)
+	GetByUser(ctx context.Context, userID primitive.ObjectID) ([]domain.FolderMember, error)
+	DeleteByFolders(ctx context.Context, foldersID []primitive.ObjectID) error
+}
+
+// Sessions is implemented by every storage backend for the sessions collection.
+type Sessions interface {
+	Create(ctx context.Context, session domain.Session) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (domain.Session, error)
+	Delete(ctx context.Context, tokenHash string) error
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
+
+// APITokens is implemented by every storage backend for the api tokens
+// collection. Methods taking a userID only see the tokens of that user.
+type APITokens interface {
+	Create(ctx context.Context, token domain.APIToken) (primitive.ObjectID, error)
+	GetByTokenHash(ctx context.Context, tokenHash string) (domain.APIToken, error)
+	GetByUser(ctx context.Context, userID primitive.ObjectID) ([]domain.APIToken, error)
+	Delete(ctx context.Context, userID primitive.ObjectID, tokenID primitive.ObjectID) error
+	SetLastUsed(ctx context.Context, tokenID primitive.ObjectID, lastUsedAt time.Time) error
+}
+
+// ShareLinks is implemented by every storage backend for the share links
+// collection. Methods taking an ownerID only see the links of that owner.
+type ShareLinks interface {
+	Create(ctx context.Context, link domain.ShareLink) (primitive.ObjectID, error)
+	GetByTokenHash(ctx context.Context, tokenHash string) (domain.ShareLink, error)
+	GetByVideo(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID) ([]domain.ShareLink, error)
+	Delete(ctx context.Context, ownerID primitive.ObjectID, linkID primitive.ObjectID) error
+	// AddView counts a view of the link, it fails with ErrNoDocuments when
+	// the link is gone or has no views left.
+	AddView(ctx context.Context, linkID primitive.ObjectID) error
+}
+
+var (
+	_ Videos = (*VideosMongoRepo)(nil)
+	_ Videos = (*VideosBoltRepo)(nil)
+	_ Videos = (*VideosMemoryRepo)(nil)
+
+	_ Folders = (*FoldersMongoRepo)(nil)
+	_ Folders = (*FoldersBoltRepo)(nil)
+	_ Folders = (*FoldersMemoryRepo)(nil)
+
+	_ Intents = (*IntentsMongoRepo)(nil)
+	_ Intents = (*IntentsBoltRepo)(nil)
+	_ Intents = (*IntentsMemoryRepo)(nil)
+
+	_ FolderMembers = (*FolderMembersMongoRepo)(nil)
+	_ FolderMembers = (*FolderMembersBoltRepo)(nil)
+	_ FolderMembers = (*FolderMembersMemoryRepo)(nil)
+
+	_ Users = (*UsersMongoRepo)(nil)
+	_ Users = (*UsersBoltRepo)(nil)
+	_ Users = (*UsersMemoryRepo)(nil)
+
+	_ Sessions = (*SessionsMongoRepo)(nil)
+	_ Sessions = (*SessionsBoltRepo)(nil)
+	_ Sessions = (*SessionsMemoryRepo)(nil)
+
+	_ APITokens = (*APITokensMongoRepo)(nil)
+	_ APITokens = (*APITokensBoltRepo)(nil)
+	_ APITokens = (*APITokensMemoryRepo)(nil)
+
+	_ ShareLinks = (*ShareLinksMongoRepo)(nil)
+	_ ShareLinks = (*ShareLinksBoltRepo)(nil)
+	_ ShareLinks = (*ShareLinksMemoryRepo)(nil)
+)
@@ -0,0 +1,659 @@
+// Package repotest is a conformance suite that every storage backend of the
+// repository package must pass. Backends call Run from their own test with a
+// factory that returns empty repositories.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"testing"
+	"time"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/repository"
+)
+
+type Repos struct {
+	Videos        repository.Videos
+	Folders       repository.Folders
+	Intents       repository.Intents
+	Users         repository.Users
+	Sessions      repository.Sessions
+	APITokens     repository.APITokens
+	FolderMembers repository.FolderMembers
+	ShareLinks    repository.ShareLinks
+}
+
+// Factory returns empty repositories for a single test.
+type Factory func(t *testing.T) Repos
+
+func Run(t *testing.T, newRepos Factory) {
+	t.Run("Videos", func(t *testing.T) { testVideos(t, newRepos) })
+	t.Run("VideoNameUniqueness", func(t *testing.T) { testVideoNameUniqueness(t, newRepos) })
+	t.Run("VideosByFolders", func(t *testing.T) { testVideosByFolders(t, newRepos) })
+	t.Run("VideosListing", func(t *testing.T) { testVideosListing(t, newRepos) })
+	t.Run("Folders", func(t *testing.T) { testFolders(t, newRepos) })
+	t.Run("NestedFolders", func(t *testing.T) { testNestedFolders(t, newRepos) })
+	t.Run("Intents", func(t *testing.T) { testIntents(t, newRepos) })
+	t.Run("OwnerIsolation", func(t *testing.T) { testOwnerIsolation(t, newRepos) })
+	t.Run("ClaimUnowned", func(t *testing.T) { testClaimUnowned(t, newRepos) })
+	t.Run("Users", func(t *testing.T) { testUsers(t, newRepos) })
+	t.Run("Sessions", func(t *testing.T) { testSessions(t, newRepos) })
+	t.Run("APITokens", func(t *testing.T) { testAPITokens(t, newRepos) })
+	t.Run("FolderMembers", func(t *testing.T) { testFolderMembers(t, newRepos) })
+	t.Run("ShareLinks", func(t *testing.T) { testShareLinks(t, newRepos) })
+}
+
+func testVideos(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repo := newRepos(t).Videos
+	ownerID := primitive.NewObjectID()
+	folderID := primitive.NewObjectID()
+
+	videoID, err := repo.Create(ctx, domain.Video{OwnerID: ownerID, VideoName: "a", FolderID: folderID, RealPath: "ab/cd/a.mp4", PreviewPath: "ab/cd/a.jpeg"})
+	mustNot(t, err)
+
+	video, err := repo.GetByID(ctx, ownerID, videoID)
+	mustNot(t, err)
+	if video.VideoName != "a" || video.FolderID != folderID || video.RealPath != "ab/cd/a.mp4" || video.PreviewPath != "ab/cd/a.jpeg" {
+		t.Fatalf("GetByID returned %+v", video)
+	}
+
+	realPath, err := repo.GetRealPath(ctx, ownerID, videoID)
+	mustNot(t, err)
+	if realPath != "ab/cd/a.mp4" {
+		t.Fatalf("GetRealPath = %q", realPath)
+	}
+
+	mustNot(t, repo.Rename(ctx, ownerID, videoID, "b"))
+	if _, err := repo.GetByName(ctx, ownerID, "b", folderID); err != nil {
+		t.Fatalf("GetByName after rename: %s", err)
+	}
+
+	otherFolderID := primitive.NewObjectID()
+	mustNot(t, repo.Move(ctx, ownerID, videoID, otherFolderID, "b"))
+	if video, _ := repo.GetByID(ctx, ownerID, videoID); video.FolderID != otherFolderID || video.VideoName != "b" {
+		t.Fatalf("Move stored folder %s and name %q", video.FolderID, video.VideoName)
+	}
+
+	subtitles := []domain.Subtitle{{Lang: "en", Label: "English"}, {Lang: "de", Label: "Deutsch", Auto: true}}
+	mustNot(t, repo.SetSubtitles(ctx, ownerID, videoID, subtitles))
+	mustNot(t, repo.SetSubtitles(ctx, primitive.NewObjectID(), videoID, nil))
+	if video, _ := repo.GetByID(ctx, ownerID, videoID); len(video.Subtitles) != 2 || video.Subtitles[0] != subtitles[0] || video.Subtitles[1] != subtitles[1] {
+		t.Fatalf("SetSubtitles stored %+v", video.Subtitles)
+	}
+
+	chapters := []domain.Chapter{{Title: "Intro", End: time.Minute}, {Title: "Outro", Start: time.Minute, End: 2 * time.Minute}}
+	mustNot(t, repo.SetChapters(ctx, ownerID, videoID, chapters))
+	mustNot(t, repo.SetChapters(ctx, primitive.NewObjectID(), videoID, nil))
+	if video, _ := repo.GetByID(ctx, ownerID, videoID); len(video.Chapters) != 2 || video.Chapters[0] != chapters[0] || video.Chapters[1] != chapters[1] {
+		t.Fatalf("SetChapters stored %+v", video.Chapters)
+	}
+
+	mustNot(t, repo.Delete(ctx, videoID))
+	if _, err := repo.GetByID(ctx, ownerID, videoID); !errors.Is(err, domain.ErrNoDocuments) {
+		t.Fatalf("GetByID after delete: want ErrNoDocuments, got %v", err)
+	}
+
+	if _, err := repo.GetByName(ctx, ownerID, "b", otherFolderID); !errors.Is(err, domain.ErrNoDocuments) {
+		t.Fatalf("GetByName after delete: want ErrNoDocuments, got %v", err)
+	}
+}
+
+func testVideoNameUniqueness(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repo := newRepos(t).Videos
+	ownerID := primitive.NewObjectID()
+	folderID := primitive.NewObjectID()
+	otherFolderID := primitive.NewObjectID()
+
+	_, err := repo.Create(ctx, domain.Video{OwnerID: ownerID, VideoName: "a", FolderID: folderID})
+	mustNot(t, err)
+
+	if _, err := repo.Create(ctx, domain.Video{OwnerID: ownerID, VideoName: "a", FolderID: folderID}); !errors.Is(err, domain.ErrDuplicateKey) {
+		t.Fatalf("Create duplicate: want ErrDuplicateKey, got %v", err)
+	}
+
+	otherID, err := repo.Create(ctx, domain.Video{OwnerID: ownerID, VideoName: "a", FolderID: otherFolderID})
+	mustNot(t, err)
+
+	if err := repo.Move(ctx, ownerID, otherID, folderID, "a"); !errors.Is(err, domain.ErrDuplicateKey) {
+		t.Fatalf("Move onto duplicate: want ErrDuplicateKey, got %v", err)
+	}
+
+	// a failed move changes neither the folder nor the name
+	if video, _ := repo.GetByID(ctx, ownerID, otherID); video.FolderID != otherFolderID || video.VideoName != "a" {
+		t.Fatalf("failed Move stored folder %s and name %q", video.FolderID, video.VideoName)
+	}
+
+	mustNot(t, repo.Move(ctx, ownerID, otherID, folderID, "a (2)"))
+	if video, _ := repo.GetByID(ctx, ownerID, otherID); video.FolderID != folderID || video.VideoName != "a (2)" {
+		t.Fatalf("Move under a new name stored folder %s and name %q", video.FolderID, video.VideoName)
+	}
+
+	secondID, err := repo.Create(ctx, domain.Video{OwnerID: ownerID, VideoName: "b", FolderID: folderID})
+	mustNot(t, err)
+
+	if err := repo.Rename(ctx, ownerID, secondID, "a"); !errors.Is(err, domain.ErrDuplicateKey) {
+		t.Fatalf("Rename onto duplicate: want ErrDuplicateKey, got %v", err)
+	}
+
+	mustNot(t, repo.Rename(ctx, ownerID, secondID, "b"))
+}
+
+func testVideosByFolders(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repo := newRepos(t).Videos
+	ownerID := primitive.NewObjectID()
+	first, second, third := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	for i, folderID := range []primitive.ObjectID{first, first, second, third} {
+		name := string(rune('a' + i))
+		_, err := repo.Create(ctx, domain.Video{OwnerID: ownerID, VideoName: name, FolderID: folderID, RealPath: name + ".mp4", PreviewPath: name + ".jpeg"})
+		mustNot(t, err)
+	}
+
+	videos, err := repo.GetVideos(ctx, ownerID, first)
+	mustNot(t, err)
+	if len(videos) != 2 || videos[0].VideoName != "a" || videos[1].VideoName != "b" {
+		t.Fatalf("GetVideos returned %+v", videos)
+	}
+
+	realPaths, previewPaths, err := repo.GetPathsByFolders(ctx, ownerID, []primitive.ObjectID{first, second})
+	mustNot(t, err)
+	sort.Strings(realPaths)
+	sort.Strings(previewPaths)
+	if len(realPaths) != 3 || realPaths[2] != "c.mp4" || len(previewPaths) != 3 || previewPaths[0] != "a.jpeg" {
+		t.Fatalf("GetPathsByFolders returned %v %v", realPaths, previewPaths)
+	}
+
+	mustNot(t, repo.DeleteVideos(ctx, []primitive.ObjectID{first, second}))
+
+	all, err := repo.GetAll(ctx)
+	mustNot(t, err)
+	if len(all) != 1 || all[0].FolderID != third {
+		t.Fatalf("GetAll after DeleteVideos returned %+v", all)
+	}
+}
+
+// testVideosListing lists videos with and without subtitles and chapters in
+// one folder, each must come back with its own.
+func testVideosListing(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repo := newRepos(t).Videos
+	ownerID := primitive.NewObjectID()
+	folderID := primitive.NewObjectID()
+
+	subtitles := map[string][]domain.Subtitle{
+		"a": {{Lang: "en", Label: "English"}},
+		"c": {{Lang: "de", Label: "Deutsch", Auto: true}},
+	}
+	chapters := map[string][]domain.Chapter{
+		"a": {{Title: "Intro", End: time.Minute}},
+		"d": {{Title: "Outro", Start: time.Minute, End: 2 * time.Minute}},
+	}
+	names := []string{"a", "b", "c", "d"}
+
+	for _, name := range names {
+		videoID, err := repo.Create(ctx, domain.Video{OwnerID: ownerID, VideoName: name, FolderID: folderID, RealPath: name + ".mp4"})
+		mustNot(t, err)
+
+		if subtitles[name] != nil {
+			mustNot(t, repo.SetSubtitles(ctx, ownerID, videoID, subtitles[name]))
+		}
+
+		if chapters[name] != nil {
+			mustNot(t, repo.SetChapters(ctx, ownerID, videoID, chapters[name]))
+		}
+	}
+
+	videos, err := repo.GetVideos(ctx, ownerID, folderID)
+	mustNot(t, err)
+	if len(videos) != len(names) {
+		t.Fatalf("GetVideos returned %d videos, want %d", len(videos), len(names))
+	}
+
+	for _, video := range videos {
+		wantSubtitles := subtitles[video.VideoName]
+		if len(video.Subtitles) != len(wantSubtitles) || len(wantSubtitles) > 0 && video.Subtitles[0] != wantSubtitles[0] {
+			t.Errorf("GetVideos returned subtitles %+v for %q, want %+v", video.Subtitles, video.VideoName, wantSubtitles)
+		}
+
+		wantChapters := chapters[video.VideoName]
+		if len(video.Chapters) != len(wantChapters) || len(wantChapters) > 0 && video.Chapters[0] != wantChapters[0] {
+			t.Errorf("GetVideos returned chapters %+v for %q, want %+v", video.Chapters, video.VideoName, wantChapters)
+		}
+	}
+}
+
+func testFolders(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repo := newRepos(t).Folders
+	ownerID := primitive.NewObjectID()
+
+	rootID, err := repo.Create(ctx, ownerID, "root", primitive.NilObjectID)
+	mustNot(t, err)
+
+	childID, err := repo.Create(ctx, ownerID, "child", rootID)
+	mustNot(t, err)
+
+	mustNot(t, repo.CheckExistenceByID(ctx, ownerID, childID))
+	if err := repo.CheckExistenceByID(ctx, ownerID, primitive.NewObjectID()); !errors.Is(err, domain.ErrNoDocuments) {
+		t.Fatalf("CheckExistenceByID of missing folder: want ErrNoDocuments, got %v", err)
+	}
+
+	mustNot(t, repo.CheckExistenceByName(ctx, ownerID, "child", rootID))
+	mustNot(t, repo.CheckExistenceByName(ctx, ownerID, "child", primitive.NilObjectID))
+	if err := repo.CheckExistenceByName(ctx, ownerID, "child", childID); !errors.Is(err, domain.ErrNoDocuments) {
+		t.Fatalf("CheckExistenceByName in other folder: want ErrNoDocuments, got %v", err)
+	}
+
+	parentDirID, err := repo.GetParentDirID(ctx, ownerID, childID)
+	mustNot(t, err)
+	if parentDirID != rootID {
+		t.Fatalf("GetParentDirID = %s, want %s", parentDirID, rootID)
+	}
+
+	if parentDirID, _ := repo.GetParentDirID(ctx, ownerID, rootID); parentDirID != primitive.NilObjectID {
+		t.Fatalf("GetParentDirID of root = %s", parentDirID)
+	}
+
+	mustNot(t, repo.UpdateName(ctx, ownerID, childID, "renamed"))
+	if name, _ := repo.GetName(ctx, ownerID, childID); name != "renamed" {
+		t.Fatalf("GetName after rename = %q", name)
+	}
+
+	otherRootID, err := repo.Create(ctx, ownerID, "other", primitive.NilObjectID)
+	mustNot(t, err)
+
+	mustNot(t, repo.Move(ctx, ownerID, childID, otherRootID))
+	nested, err := repo.GetNestedFolders(ctx, ownerID, otherRootID)
+	mustNot(t, err)
+	if len(nested) != 1 || nested[0].ID != childID {
+		t.Fatalf("GetNestedFolders after move returned %+v", nested)
+	}
+
+	mustNot(t, repo.UnsetParent(ctx, childID))
+	if parentDirID, _ := repo.GetParentDirID(ctx, ownerID, childID); parentDirID != primitive.NilObjectID {
+		t.Fatalf("GetParentDirID after UnsetParent = %s", parentDirID)
+	}
+
+	folders, err := repo.GetAll(ctx)
+	mustNot(t, err)
+	if len(folders) != 3 {
+		t.Fatalf("GetAll returned %d folders, want 3", len(folders))
+	}
+}
+
+func testNestedFolders(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repo := newRepos(t).Folders
+	ownerID := primitive.NewObjectID()
+
+	rootID, err := repo.Create(ctx, ownerID, "root", primitive.NilObjectID)
+	mustNot(t, err)
+	childID, err := repo.Create(ctx, ownerID, "child", rootID)
+	mustNot(t, err)
+	grandchildID, err := repo.Create(ctx, ownerID, "grandchild", childID)
+	mustNot(t, err)
+	siblingID, err := repo.Create(ctx, ownerID, "sibling", rootID)
+	mustNot(t, err)
+	unrelatedID, err := repo.Create(ctx, ownerID, "unrelated", primitive.NilObjectID)
+	mustNot(t, err)
+
+	nested, err := repo.GetAllNestedFolders(ctx, ownerID, rootID)
+	mustNot(t, err)
+	assertSameIDs(t, nested, []primitive.ObjectID{childID, grandchildID, siblingID})
+
+	mustNot(t, repo.DeleteAllNestedFolders(ctx, append(nested, rootID)))
+
+	folders, err := repo.GetAll(ctx)
+	mustNot(t, err)
+	if len(folders) != 1 || folders[0].ID != unrelatedID {
+		t.Fatalf("GetAll after DeleteAllNestedFolders returned %+v", folders)
+	}
+}
+
+func testIntents(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repo := newRepos(t).Intents
+	now := time.Now()
+
+	firstID, err := repo.Create(ctx, domain.Intent{Type: domain.IntentDelete, VideoPaths: []string{"a.mp4"}, CreatedAt: now})
+	mustNot(t, err)
+	secondID, err := repo.Create(ctx, domain.Intent{Type: domain.IntentCreateVideo, Video: &domain.Video{VideoName: "b"}, CreatedAt: now.Add(time.Second)})
+	mustNot(t, err)
+
+	mustNot(t, repo.RecordFailure(ctx, firstID, "boom"))
+
+	intents, err := repo.GetAll(ctx)
+	mustNot(t, err)
+	if len(intents) != 2 || intents[0].ID != firstID || intents[1].ID != secondID {
+		t.Fatalf("GetAll returned %+v", intents)
+	}
+
+	if intents[0].Attempts != 1 || intents[0].LastError != "boom" || len(intents[0].VideoPaths) != 1 {
+		t.Fatalf("RecordFailure not stored: %+v", intents[0])
+	}
+
+	if intents[1].Video == nil || intents[1].Video.VideoName != "b" {
+		t.Fatalf("intent video not stored: %+v", intents[1])
+	}
+
+	mustNot(t, repo.Delete(ctx, firstID))
+	intents, err = repo.GetAll(ctx)
+	mustNot(t, err)
+	if len(intents) != 1 || intents[0].ID != secondID {
+		t.Fatalf("GetAll after delete returned %+v", intents)
+	}
+}
+
+func testOwnerIsolation(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repos := newRepos(t)
+	ownerID, strangerID := primitive.NewObjectID(), primitive.NewObjectID()
+
+	folderID, err := repos.Folders.Create(ctx, ownerID, "root", primitive.NilObjectID)
+	mustNot(t, err)
+	videoID, err := repos.Videos.Create(ctx, domain.Video{OwnerID: ownerID, VideoName: "a", FolderID: folderID})
+	mustNot(t, err)
+
+	if _, err := repos.Videos.Create(ctx, domain.Video{OwnerID: strangerID, VideoName: "a", FolderID: folderID}); err != nil {
+		t.Fatalf("Create same name for other owner: %s", err)
+	}
+
+	if _, err := repos.Videos.GetByID(ctx, strangerID, videoID); !errors.Is(err, domain.ErrNoDocuments) {
+		t.Fatalf("GetByID of other owner: want ErrNoDocuments, got %v", err)
+	}
+
+	if err := repos.Folders.CheckExistenceByID(ctx, strangerID, folderID); !errors.Is(err, domain.ErrNoDocuments) {
+		t.Fatalf("CheckExistenceByID of other owner: want ErrNoDocuments, got %v", err)
+	}
+
+	mustNot(t, repos.Videos.Rename(ctx, strangerID, videoID, "b"))
+	mustNot(t, repos.Folders.UpdateName(ctx, strangerID, folderID, "b"))
+
+	if video, _ := repos.Videos.GetByID(ctx, ownerID, videoID); video.VideoName != "a" {
+		t.Fatalf("Rename by other owner changed the name to %q", video.VideoName)
+	}
+
+	if name, _ := repos.Folders.GetName(ctx, ownerID, folderID); name != "root" {
+		t.Fatalf("UpdateName by other owner changed the name to %q", name)
+	}
+
+	videos, err := repos.Videos.GetVideos(ctx, strangerID, folderID)
+	mustNot(t, err)
+	if len(videos) != 1 || videos[0].OwnerID != strangerID {
+		t.Fatalf("GetVideos of other owner returned %+v", videos)
+	}
+
+	if video, err := repos.Videos.Lookup(ctx, videoID); err != nil || video.OwnerID != ownerID {
+		t.Fatalf("Lookup returned %+v, %v", video, err)
+	}
+
+	if folder, err := repos.Folders.Lookup(ctx, folderID); err != nil || folder.OwnerID != ownerID || folder.FolderName != "root" {
+		t.Fatalf("Lookup returned %+v, %v", folder, err)
+	}
+
+	if _, err := repos.Folders.Lookup(ctx, primitive.NewObjectID()); !errors.Is(err, domain.ErrNoDocuments) {
+		t.Fatalf("Lookup of missing folder: want ErrNoDocuments, got %v", err)
+	}
+}
+
+func testClaimUnowned(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repos := newRepos(t)
+	ownerID, otherID := primitive.NewObjectID(), primitive.NewObjectID()
+
+	folderID, err := repos.Folders.Create(ctx, primitive.NilObjectID, "legacy", primitive.NilObjectID)
+	mustNot(t, err)
+	videoID, err := repos.Videos.Create(ctx, domain.Video{VideoName: "legacy", FolderID: folderID})
+	mustNot(t, err)
+	otherVideoID, err := repos.Videos.Create(ctx, domain.Video{OwnerID: otherID, VideoName: "other"})
+	mustNot(t, err)
+
+	mustNot(t, repos.Videos.ClaimUnowned(ctx, ownerID))
+	mustNot(t, repos.Folders.ClaimUnowned(ctx, ownerID))
+
+	mustNot(t, repos.Folders.CheckExistenceByID(ctx, ownerID, folderID))
+	if _, err := repos.Videos.GetByID(ctx, ownerID, videoID); err != nil {
+		t.Fatalf("GetByID of claimed video: %s", err)
+	}
+
+	if _, err := repos.Videos.GetByID(ctx, otherID, otherVideoID); err != nil {
+		t.Fatalf("ClaimUnowned took an owned video: %s", err)
+	}
+}
+
+func testUsers(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repo := newRepos(t).Users
+
+	count, err := repo.Count(ctx)
+	mustNot(t, err)
+	if count != 0 {
+		t.Fatalf("Count of empty repo = %d", count)
+	}
+
+	userID, err := repo.Create(ctx, domain.User{Username: "alice", PasswordHash: "hash", Role: domain.RoleAdmin})
+	mustNot(t, err)
+
+	if _, err := repo.Create(ctx, domain.User{Username: "alice"}); !errors.Is(err, domain.ErrDuplicateKey) {
+		t.Fatalf("Create duplicate username: want ErrDuplicateKey, got %v", err)
+	}
+
+	user, err := repo.GetByUsername(ctx, "alice")
+	mustNot(t, err)
+	if user.ID != userID || user.PasswordHash != "hash" || user.Role != domain.RoleAdmin {
+		t.Fatalf("GetByUsername returned %+v", user)
+	}
+
+	if user, _ := repo.GetByID(ctx, userID); user.Username != "alice" {
+		t.Fatalf("GetByID returned %+v", user)
+	}
+
+	if _, err := repo.GetByUsername(ctx, "bob"); !errors.Is(err, domain.ErrNoDocuments) {
+		t.Fatalf("GetByUsername of missing user: want ErrNoDocuments, got %v", err)
+	}
+
+	if count, _ := repo.Count(ctx); count != 1 {
+		t.Fatalf("Count = %d, want 1", count)
+	}
+}
+
+func testSessions(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repo := newRepos(t).Sessions
+	userID := primitive.NewObjectID()
+	now := time.Now()
+
+	mustNot(t, repo.Create(ctx, domain.Session{TokenHash: "live", UserID: userID, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}))
+	mustNot(t, repo.Create(ctx, domain.Session{TokenHash: "expired", UserID: userID, CreatedAt: now, ExpiresAt: now.Add(-time.Hour)}))
+
+	session, err := repo.GetByTokenHash(ctx, "live")
+	mustNot(t, err)
+	if session.UserID != userID {
+		t.Fatalf("GetByTokenHash returned %+v", session)
+	}
+
+	mustNot(t, repo.DeleteExpired(ctx, now))
+	if _, err := repo.GetByTokenHash(ctx, "expired"); !errors.Is(err, domain.ErrNoDocuments) {
+		t.Fatalf("GetByTokenHash after DeleteExpired: want ErrNoDocuments, got %v", err)
+	}
+
+	mustNot(t, repo.Delete(ctx, "live"))
+	if _, err := repo.GetByTokenHash(ctx, "live"); !errors.Is(err, domain.ErrNoDocuments) {
+		t.Fatalf("GetByTokenHash after Delete: want ErrNoDocuments, got %v", err)
+	}
+}
+
+func testAPITokens(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repo := newRepos(t).APITokens
+	userID := primitive.NewObjectID()
+	otherID := primitive.NewObjectID()
+	now := time.Now().Truncate(time.Millisecond)
+
+	firstID, err := repo.Create(ctx, domain.APIToken{UserID: userID, Name: "laptop", TokenHash: "first", Scopes: []string{domain.ScopeDownload}, CreatedAt: now})
+	mustNot(t, err)
+	secondID, err := repo.Create(ctx, domain.APIToken{UserID: userID, Name: "desktop", TokenHash: "second", Scopes: domain.AllScopes, CreatedAt: now.Add(time.Second)})
+	mustNot(t, err)
+
+	token, err := repo.GetByTokenHash(ctx, "first")
+	mustNot(t, err)
+	if token.ID != firstID || token.UserID != userID || len(token.Scopes) != 1 || !token.LastUsedAt.IsZero() {
+		t.Fatalf("GetByTokenHash returned %+v", token)
+	}
+
+	tokens, err := repo.GetByUser(ctx, userID)
+	mustNot(t, err)
+	if len(tokens) != 2 || tokens[0].ID != firstID || tokens[1].ID != secondID {
+		t.Fatalf("GetByUser returned %+v", tokens)
+	}
+
+	if tokens, _ := repo.GetByUser(ctx, otherID); len(tokens) != 0 {
+		t.Fatalf("GetByUser for another user returned %+v", tokens)
+	}
+
+	mustNot(t, repo.SetLastUsed(ctx, firstID, now))
+	token, err = repo.GetByTokenHash(ctx, "first")
+	mustNot(t, err)
+	if !token.LastUsedAt.Equal(now) {
+		t.Fatalf("LastUsedAt = %s, want %s", token.LastUsedAt, now)
+	}
+
+	if err := repo.Delete(ctx, otherID, firstID); !errors.Is(err, domain.ErrNoDocuments) {
+		t.Fatalf("Delete by another user: want ErrNoDocuments, got %v", err)
+	}
+
+	mustNot(t, repo.Delete(ctx, userID, firstID))
+	if _, err := repo.GetByTokenHash(ctx, "first"); !errors.Is(err, domain.ErrNoDocuments) {
+		t.Fatalf("GetByTokenHash after Delete: want ErrNoDocuments, got %v", err)
+	}
+}
+
+func testFolderMembers(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repo := newRepos(t).FolderMembers
+	folderID, otherFolderID := primitive.NewObjectID(), primitive.NewObjectID()
+	userID, otherID := primitive.NewObjectID(), primitive.NewObjectID()
+
+	mustNot(t, repo.Upsert(ctx, domain.FolderMember{FolderID: folderID, UserID: userID, Role: domain.FolderViewer}))
+	mustNot(t, repo.Upsert(ctx, domain.FolderMember{FolderID: folderID, UserID: userID, Role: domain.FolderContributor}))
+	mustNot(t, repo.Upsert(ctx, domain.FolderMember{FolderID: folderID, UserID: otherID, Role: domain.FolderViewer}))
+	mustNot(t, repo.Upsert(ctx, domain.FolderMember{FolderID: otherFolderID, UserID: userID, Role: domain.FolderOwner}))
+
+	members, err := repo.GetByFolder(ctx, folderID)
+	mustNot(t, err)
+	if len(members) != 2 {
+		t.Fatalf("GetByFolder returned %+v", members)
+	}
+
+	members, err = repo.GetByUser(ctx, userID)
+	mustNot(t, err)
+	roles := map[primitive.ObjectID]string{}
+	for _, member := range members {
+		roles[member.FolderID] = member.Role
+	}
+	if len(members) != 2 || roles[folderID] != domain.FolderContributor || roles[otherFolderID] != domain.FolderOwner {
+		t.Fatalf("GetByUser returned %+v", members)
+	}
+
+	if err := repo.Delete(ctx, otherFolderID, otherID); !errors.Is(err, domain.ErrNoDocuments) {
+		t.Fatalf("Delete of missing member: want ErrNoDocuments, got %v", err)
+	}
+
+	mustNot(t, repo.Delete(ctx, folderID, otherID))
+	if members, _ := repo.GetByUser(ctx, otherID); len(members) != 0 {
+		t.Fatalf("GetByUser after Delete returned %+v", members)
+	}
+
+	mustNot(t, repo.DeleteByFolders(ctx, []primitive.ObjectID{folderID, otherFolderID}))
+	if members, _ := repo.GetByUser(ctx, userID); len(members) != 0 {
+		t.Fatalf("GetByUser after DeleteByFolders returned %+v", members)
+	}
+}
+
+func testShareLinks(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	repo := newRepos(t).ShareLinks
+	ownerID, otherID := primitive.NewObjectID(), primitive.NewObjectID()
+	videoID := primitive.NewObjectID()
+	now := time.Now().Truncate(time.Millisecond)
+
+	limitedID, err := repo.Create(ctx, domain.ShareLink{VideoID: videoID, OwnerID: ownerID, TokenHash: "limited", MaxViews: 2, ExpiresAt: now.Add(time.Hour), CreatedAt: now})
+	mustNot(t, err)
+	unlimitedID, err := repo.Create(ctx, domain.ShareLink{VideoID: videoID, OwnerID: ownerID, TokenHash: "unlimited", CreatedAt: now.Add(time.Second)})
+	mustNot(t, err)
+
+	if _, err := repo.Create(ctx, domain.ShareLink{VideoID: videoID, OwnerID: ownerID, TokenHash: "limited"}); !errors.Is(err, domain.ErrDuplicateKey) {
+		t.Fatalf("Create duplicate token hash: want ErrDuplicateKey, got %v", err)
+	}
+
+	link, err := repo.GetByTokenHash(ctx, "limited")
+	mustNot(t, err)
+	if link.ID != limitedID || link.MaxViews != 2 || !link.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("GetByTokenHash returned %+v", link)
+	}
+
+	links, err := repo.GetByVideo(ctx, ownerID, videoID)
+	mustNot(t, err)
+	if len(links) != 2 || links[0].ID != limitedID || links[1].ID != unlimitedID {
+		t.Fatalf("GetByVideo returned %+v", links)
+	}
+
+	if links, _ := repo.GetByVideo(ctx, otherID, videoID); len(links) != 0 {
+		t.Fatalf("GetByVideo of other owner returned %+v", links)
+	}
+
+	mustNot(t, repo.AddView(ctx, limitedID))
+	mustNot(t, repo.AddView(ctx, limitedID))
+	if err := repo.AddView(ctx, limitedID); !errors.Is(err, domain.ErrNoDocuments) {
+		t.Fatalf("AddView past MaxViews: want ErrNoDocuments, got %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		mustNot(t, repo.AddView(ctx, unlimitedID))
+	}
+	if link, _ := repo.GetByTokenHash(ctx, "unlimited"); link.Views != 3 {
+		t.Fatalf("Views = %d, want 3", link.Views)
+	}
+
+	if err := repo.Delete(ctx, otherID, limitedID); !errors.Is(err, domain.ErrNoDocuments) {
+		t.Fatalf("Delete by other owner: want ErrNoDocuments, got %v", err)
+	}
+
+	mustNot(t, repo.Delete(ctx, ownerID, limitedID))
+	if err := repo.AddView(ctx, limitedID); !errors.Is(err, domain.ErrNoDocuments) {
+		t.Fatalf("AddView after Delete: want ErrNoDocuments, got %v", err)
+	}
+}
+
+func assertSameIDs(t *testing.T, got, want []primitive.ObjectID) {
+	t.Helper()
+
+	sortIDs := func(ids []primitive.ObjectID) {
+		sort.Slice(ids, func(i, j int) bool { return ids[i].Hex() < ids[j].Hex() })
+	}
+	sortIDs(got)
+	sortIDs(want)
+
+	if len(got) != len(want) {
+		t.Fatalf("got ids %v, want %v", got, want)
+	}
+
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got ids %v, want %v", got, want)
+		}
+	}
+}
+
+func mustNot(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
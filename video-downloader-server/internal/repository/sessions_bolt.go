@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+	"video-downloader-server/internal/domain"
+)
+
+type SessionsBoltRepo struct {
+	db *bbolt.DB
+}
+
+func NewSessionsBoltRepo(db *bbolt.DB) *SessionsBoltRepo {
+	return &SessionsBoltRepo{
+		db: db,
+	}
+}
+
+func (r *SessionsBoltRepo) Create(ctx context.Context, session domain.Session) error {
+	session.ID = primitive.NewObjectID()
+
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		return boltPut(tx, sessionsCollection, session.ID, session)
+	})
+}
+
+func (r *SessionsBoltRepo) GetByTokenHash(ctx context.Context, tokenHash string) (domain.Session, error) {
+	var sessions []domain.Session
+
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		sessions, err = boltFind(tx, sessionsCollection, withTokenHash(tokenHash))
+		return err
+	})
+	if err != nil {
+		return domain.Session{}, err
+	}
+
+	if len(sessions) == 0 {
+		return domain.Session{}, domain.ErrNoDocuments
+	}
+
+	return sessions[0], nil
+}
+
+func (r *SessionsBoltRepo) Delete(ctx context.Context, tokenHash string) error {
+	return r.deleteMatching(withTokenHash(tokenHash))
+}
+
+func (r *SessionsBoltRepo) DeleteExpired(ctx context.Context, now time.Time) error {
+	return r.deleteMatching(func(session domain.Session) bool {
+		return !session.ExpiresAt.After(now)
+	})
+}
+
+func (r *SessionsBoltRepo) deleteMatching(match func(domain.Session) bool) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		sessions, err := boltFind(tx, sessionsCollection, match)
+		if err != nil {
+			return err
+		}
+
+		for _, session := range sessions {
+			if err := boltDelete(tx, sessionsCollection, session.ID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func withTokenHash(tokenHash string) func(domain.Session) bool {
+	return func(session domain.Session) bool {
+		return session.TokenHash == tokenHash
+	}
+}
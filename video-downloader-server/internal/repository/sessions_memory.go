@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sync"
+	"time"
+	"video-downloader-server/internal/domain"
+)
+
+type SessionsMemoryRepo struct {
+	mu       sync.RWMutex
+	sessions map[string]domain.Session
+}
+
+func NewSessionsMemoryRepo() *SessionsMemoryRepo {
+	return &SessionsMemoryRepo{
+		sessions: make(map[string]domain.Session),
+	}
+}
+
+func (r *SessionsMemoryRepo) Create(ctx context.Context, session domain.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sessions[session.TokenHash]; ok {
+		return domain.ErrDuplicateKey
+	}
+
+	session.ID = primitive.NewObjectID()
+	r.sessions[session.TokenHash] = session
+
+	return nil
+}
+
+func (r *SessionsMemoryRepo) GetByTokenHash(ctx context.Context, tokenHash string) (domain.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	session, ok := r.sessions[tokenHash]
+	if !ok {
+		return domain.Session{}, domain.ErrNoDocuments
+	}
+
+	return session, nil
+}
+
+func (r *SessionsMemoryRepo) Delete(ctx context.Context, tokenHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.sessions, tokenHash)
+
+	return nil
+}
+
+func (r *SessionsMemoryRepo) DeleteExpired(ctx context.Context, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for tokenHash, session := range r.sessions {
+		if !session.ExpiresAt.After(now) {
+			delete(r.sessions, tokenHash)
+		}
+	}
+
+	return nil
+}
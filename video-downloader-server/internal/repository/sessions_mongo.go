@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+	"video-downloader-server/internal/domain"
+)
+
+const (
+	sessionsCollection = "sessions"
+)
+
+type SessionsMongoRepo struct {
+	db        *mongo.Collection
+	opTimeout time.Duration
+}
+
+func NewSessionsMongoRepo(db *mongo.Database, opTimeout time.Duration) *SessionsMongoRepo {
+	return &SessionsMongoRepo{
+		db:        db.Collection(sessionsCollection),
+		opTimeout: opTimeout,
+	}
+}
+
+func (r *SessionsMongoRepo) Create(ctx context.Context, session domain.Session) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	_, err := r.db.InsertOne(ctx, session)
+	return convertMongoErr(err)
+}
+
+func (r *SessionsMongoRepo) GetByTokenHash(ctx context.Context, tokenHash string) (domain.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	var session domain.Session
+	if err := r.db.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&session); err != nil {
+		return domain.Session{}, convertMongoErr(err)
+	}
+
+	return session, nil
+}
+
+func (r *SessionsMongoRepo) Delete(ctx context.Context, tokenHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	_, err := r.db.DeleteOne(ctx, bson.M{"token_hash": tokenHash})
+	return convertMongoErr(err)
+}
+
+// DeleteExpired removes the sessions that expired before now. The TTL index
+// does the same in the background, this only makes it deterministic.
+func (r *SessionsMongoRepo) DeleteExpired(ctx context.Context, now time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	_, err := r.db.DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lte": now}})
+	return convertMongoErr(err)
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"video-downloader-server/internal/domain"
+)
+
+type ShareLinksBoltRepo struct {
+	db *bbolt.DB
+}
+
+func NewShareLinksBoltRepo(db *bbolt.DB) *ShareLinksBoltRepo {
+	return &ShareLinksBoltRepo{
+		db: db,
+	}
+}
+
+func (r *ShareLinksBoltRepo) Create(ctx context.Context, link domain.ShareLink) (primitive.ObjectID, error) {
+	link.ID = primitive.NewObjectID()
+
+	err := r.db.Update(func(tx *bbolt.Tx) error {
+		existing, err := boltFind(tx, shareLinksCollection, func(other domain.ShareLink) bool {
+			return other.TokenHash == link.TokenHash
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(existing) > 0 {
+			return domain.ErrDuplicateKey
+		}
+
+		return boltPut(tx, shareLinksCollection, link.ID, link)
+	})
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return link.ID, nil
+}
+
+func (r *ShareLinksBoltRepo) GetByTokenHash(ctx context.Context, tokenHash string) (domain.ShareLink, error) {
+	var links []domain.ShareLink
+
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		links, err = boltFind(tx, shareLinksCollection, func(link domain.ShareLink) bool {
+			return link.TokenHash == tokenHash
+		})
+		return err
+	})
+	if err != nil {
+		return domain.ShareLink{}, err
+	}
+
+	if len(links) == 0 {
+		return domain.ShareLink{}, domain.ErrNoDocuments
+	}
+
+	return links[0], nil
+}
+
+func (r *ShareLinksBoltRepo) GetByVideo(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID) ([]domain.ShareLink, error) {
+	var links []domain.ShareLink
+
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		links, err = boltFind(tx, shareLinksCollection, func(link domain.ShareLink) bool {
+			return link.OwnerID == ownerID && link.VideoID == videoID
+		})
+		return err
+	})
+
+	return links, err
+}
+
+func (r *ShareLinksBoltRepo) Delete(ctx context.Context, ownerID primitive.ObjectID, linkID primitive.ObjectID) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		link, err := boltGet[domain.ShareLink](tx, shareLinksCollection, linkID)
+		if err != nil {
+			return err
+		}
+
+		if link.OwnerID != ownerID {
+			return domain.ErrNoDocuments
+		}
+
+		return boltDelete(tx, shareLinksCollection, linkID)
+	})
+}
+
+func (r *ShareLinksBoltRepo) AddView(ctx context.Context, linkID primitive.ObjectID) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		link, err := boltGet[domain.ShareLink](tx, shareLinksCollection, linkID)
+		if err != nil {
+			return err
+		}
+
+		if link.UsedUp() {
+			return domain.ErrNoDocuments
+		}
+
+		link.Views++
+
+		return boltPut(tx, shareLinksCollection, linkID, link)
+	})
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"sync"
+	"video-downloader-server/internal/domain"
+)
+
+type ShareLinksMemoryRepo struct {
+	mu    sync.RWMutex
+	links map[primitive.ObjectID]domain.ShareLink
+}
+
+func NewShareLinksMemoryRepo() *ShareLinksMemoryRepo {
+	return &ShareLinksMemoryRepo{
+		links: make(map[primitive.ObjectID]domain.ShareLink),
+	}
+}
+
+func (r *ShareLinksMemoryRepo) Create(ctx context.Context, link domain.ShareLink) (primitive.ObjectID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.links {
+		if existing.TokenHash == link.TokenHash {
+			return primitive.NilObjectID, domain.ErrDuplicateKey
+		}
+	}
+
+	link.ID = primitive.NewObjectID()
+	r.links[link.ID] = link
+
+	return link.ID, nil
+}
+
+func (r *ShareLinksMemoryRepo) GetByTokenHash(ctx context.Context, tokenHash string) (domain.ShareLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, link := range r.links {
+		if link.TokenHash == tokenHash {
+			return link, nil
+		}
+	}
+
+	return domain.ShareLink{}, domain.ErrNoDocuments
+}
+
+func (r *ShareLinksMemoryRepo) GetByVideo(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID) ([]domain.ShareLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var links []domain.ShareLink
+	for _, link := range r.links {
+		if link.OwnerID == ownerID && link.VideoID == videoID {
+			links = append(links, link)
+		}
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].ID.Hex() < links[j].ID.Hex()
+	})
+
+	return links, nil
+}
+
+func (r *ShareLinksMemoryRepo) Delete(ctx context.Context, ownerID primitive.ObjectID, linkID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	link, ok := r.links[linkID]
+	if !ok || link.OwnerID != ownerID {
+		return domain.ErrNoDocuments
+	}
+
+	delete(r.links, linkID)
+
+	return nil
+}
+
+func (r *ShareLinksMemoryRepo) AddView(ctx context.Context, linkID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	link, ok := r.links[linkID]
+	if !ok || link.UsedUp() {
+		return domain.ErrNoDocuments
+	}
+
+	link.Views++
+	r.links[linkID] = link
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+	"video-downloader-server/internal/domain"
+)
+
+const (
+	shareLinksCollection = "share_links"
+)
+
+type ShareLinksMongoRepo struct {
+	db        *mongo.Collection
+	opTimeout time.Duration
+}
+
+func NewShareLinksMongoRepo(db *mongo.Database, opTimeout time.Duration) *ShareLinksMongoRepo {
+	return &ShareLinksMongoRepo{
+		db:        db.Collection(shareLinksCollection),
+		opTimeout: opTimeout,
+	}
+}
+
+func (r *ShareLinksMongoRepo) Create(ctx context.Context, link domain.ShareLink) (primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	res, err := r.db.InsertOne(ctx, link)
+	if err != nil {
+		return primitive.NilObjectID, convertMongoErr(err)
+	}
+
+	return res.InsertedID.(primitive.ObjectID), nil
+}
+
+func (r *ShareLinksMongoRepo) GetByTokenHash(ctx context.Context, tokenHash string) (domain.ShareLink, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	var link domain.ShareLink
+	if err := r.db.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&link); err != nil {
+		return domain.ShareLink{}, convertMongoErr(err)
+	}
+
+	return link, nil
+}
+
+func (r *ShareLinksMongoRepo) GetByVideo(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID) ([]domain.ShareLink, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	cursor, err := r.db.Find(ctx, bson.M{"owner_id": ownerID, "video_id": videoID}, options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return nil, convertMongoErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var links []domain.ShareLink
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, convertMongoErr(err)
+	}
+
+	return links, nil
+}
+
+func (r *ShareLinksMongoRepo) Delete(ctx context.Context, ownerID primitive.ObjectID, linkID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	res, err := r.db.DeleteOne(ctx, bson.M{"_id": linkID, "owner_id": ownerID})
+	if err != nil {
+		return convertMongoErr(err)
+	}
+
+	if res.DeletedCount == 0 {
+		return domain.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *ShareLinksMongoRepo) AddView(ctx context.Context, linkID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	filter := bson.M{
+		"_id": linkID,
+		"$or": bson.A{
+			bson.M{"max_views": 0},
+			bson.M{"$expr": bson.M{"$lt": bson.A{"$views", "$max_views"}}},
+		},
+	}
+
+	res, err := r.db.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"views": 1}})
+	if err != nil {
+		return convertMongoErr(err)
+	}
+
+	if res.MatchedCount == 0 {
+		return domain.ErrNoDocuments
+	}
+
+	return nil
+}
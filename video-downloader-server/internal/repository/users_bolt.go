@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"video-downloader-server/internal/domain"
+)
+
+type UsersBoltRepo struct {
+	db *bbolt.DB
+}
+
+func NewUsersBoltRepo(db *bbolt.DB) *UsersBoltRepo {
+	return &UsersBoltRepo{
+		db: db,
+	}
+}
+
+func (r *UsersBoltRepo) Create(ctx context.Context, user domain.User) (primitive.ObjectID, error) {
+	user.ID = primitive.NewObjectID()
+
+	err := r.db.Update(func(tx *bbolt.Tx) error {
+		existing, err := boltFind(tx, usersCollection, withUsername(user.Username))
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			return domain.ErrDuplicateKey
+		}
+
+		return boltPut(tx, usersCollection, user.ID, user)
+	})
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return user.ID, nil
+}
+
+func (r *UsersBoltRepo) GetByID(ctx context.Context, userID primitive.ObjectID) (domain.User, error) {
+	var user domain.User
+
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		user, err = boltGet[domain.User](tx, usersCollection, userID)
+		return err
+	})
+
+	return user, err
+}
+
+func (r *UsersBoltRepo) GetByUsername(ctx context.Context, username string) (domain.User, error) {
+	var users []domain.User
+
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		users, err = boltFind(tx, usersCollection, withUsername(username))
+		return err
+	})
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	if len(users) == 0 {
+		return domain.User{}, domain.ErrNoDocuments
+	}
+
+	return users[0], nil
+}
+
+func (r *UsersBoltRepo) Count(ctx context.Context) (int64, error) {
+	var count int64
+
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		count = int64(tx.Bucket([]byte(usersCollection)).Stats().KeyN)
+		return nil
+	})
+
+	return count, err
+}
+
+func withUsername(username string) func(domain.User) bool {
+	return func(user domain.User) bool {
+		return user.Username == username
+	}
+}
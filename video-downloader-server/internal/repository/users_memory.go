@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sync"
+	"video-downloader-server/internal/domain"
+)
+
+type UsersMemoryRepo struct {
+	mu    sync.RWMutex
+	users map[primitive.ObjectID]domain.User
+}
+
+func NewUsersMemoryRepo() *UsersMemoryRepo {
+	return &UsersMemoryRepo{
+		users: make(map[primitive.ObjectID]domain.User),
+	}
+}
+
+func (r *UsersMemoryRepo) Create(ctx context.Context, user domain.User) (primitive.ObjectID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.Username == user.Username {
+			return primitive.NilObjectID, domain.ErrDuplicateKey
+		}
+	}
+
+	user.ID = primitive.NewObjectID()
+	r.users[user.ID] = user
+
+	return user.ID, nil
+}
+
+func (r *UsersMemoryRepo) GetByID(ctx context.Context, userID primitive.ObjectID) (domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return domain.User{}, domain.ErrNoDocuments
+	}
+
+	return user, nil
+}
+
+func (r *UsersMemoryRepo) GetByUsername(ctx context.Context, username string) (domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+
+	return domain.User{}, domain.ErrNoDocuments
+}
+
+func (r *UsersMemoryRepo) Count(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return int64(len(r.users)), nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+	"video-downloader-server/internal/domain"
+)
+
+const (
+	usersCollection = "users"
+)
+
+type UsersMongoRepo struct {
+	db        *mongo.Collection
+	opTimeout time.Duration
+}
+
+func NewUsersMongoRepo(db *mongo.Database, opTimeout time.Duration) *UsersMongoRepo {
+	return &UsersMongoRepo{
+		db:        db.Collection(usersCollection),
+		opTimeout: opTimeout,
+	}
+}
+
+func (r *UsersMongoRepo) Create(ctx context.Context, user domain.User) (primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	res, err := r.db.InsertOne(ctx, user)
+	if err != nil {
+		return primitive.NilObjectID, convertMongoErr(err)
+	}
+
+	return res.InsertedID.(primitive.ObjectID), nil
+}
+
+func (r *UsersMongoRepo) GetByID(ctx context.Context, userID primitive.ObjectID) (domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	var user domain.User
+	if err := r.db.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		return domain.User{}, convertMongoErr(err)
+	}
+
+	return user, nil
+}
+
+func (r *UsersMongoRepo) GetByUsername(ctx context.Context, username string) (domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	var user domain.User
+	if err := r.db.FindOne(ctx, bson.M{"username": username}).Decode(&user); err != nil {
+		return domain.User{}, convertMongoErr(err)
+	}
+
+	return user, nil
+}
+
+func (r *UsersMongoRepo) Count(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	count, err := r.db.CountDocuments(ctx, bson.M{})
+	return count, convertMongoErr(err)
+}
@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"video-downloader-server/internal/domain"
+)
+
+type VideosBoltRepo struct {
+	db *bbolt.DB
+}
+
+func NewVideosBoltRepo(db *bbolt.DB) *VideosBoltRepo {
+	return &VideosBoltRepo{
+		db: db,
+	}
+}
+
+func (r *VideosBoltRepo) Create(ctx context.Context, video domain.Video) (primitive.ObjectID, error) {
+	if video.ID == primitive.NilObjectID {
+		video.ID = primitive.NewObjectID()
+	}
+
+	err := r.db.Update(func(tx *bbolt.Tx) error {
+		if _, err := boltGet[domain.Video](tx, videosCollection, video.ID); err == nil {
+			return domain.ErrDuplicateKey
+		}
+
+		if err := r.checkNameFree(tx, video); err != nil {
+			return err
+		}
+
+		return boltPut(tx, videosCollection, video.ID, video)
+	})
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return video.ID, nil
+}
+
+func (r *VideosBoltRepo) GetByID(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID) (domain.Video, error) {
+	var video domain.Video
+
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		video, err = boltGet[domain.Video](tx, videosCollection, videoID)
+		return err
+	})
+	if err != nil {
+		return domain.Video{}, err
+	}
+
+	if video.OwnerID != ownerID {
+		return domain.Video{}, domain.ErrNoDocuments
+	}
+
+	return video, nil
+}
+
+func (r *VideosBoltRepo) GetByName(ctx context.Context, ownerID primitive.ObjectID, videoName string, folderID primitive.ObjectID) (domain.Video, error) {
+	videos, err := r.find(func(video domain.Video) bool {
+		return video.OwnerID == ownerID && video.VideoName == videoName && video.FolderID == folderID
+	})
+	if err != nil {
+		return domain.Video{}, err
+	}
+
+	if len(videos) == 0 {
+		return domain.Video{}, domain.ErrNoDocuments
+	}
+
+	return videos[0], nil
+}
+
+func (r *VideosBoltRepo) GetRealPath(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID) (string, error) {
+	video, err := r.GetByID(ctx, ownerID, videoID)
+	if err != nil {
+		return "", err
+	}
+
+	return video.RealPath, nil
+}
+
+func (r *VideosBoltRepo) Rename(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, newVideoName string) error {
+	return r.update(ownerID, videoID, func(video *domain.Video) {
+		video.VideoName = newVideoName
+	})
+}
+
+func (r *VideosBoltRepo) Move(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, folderID primitive.ObjectID, videoName string) error {
+	return r.update(ownerID, videoID, func(video *domain.Video) {
+		video.FolderID = folderID
+		video.VideoName = videoName
+	})
+}
+
+func (r *VideosBoltRepo) SetSubtitles(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, subtitles []domain.Subtitle) error {
+	return r.update(ownerID, videoID, func(video *domain.Video) {
+		video.Subtitles = subtitles
+	})
+}
+
+func (r *VideosBoltRepo) SetChapters(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, chapters []domain.Chapter) error {
+	return r.update(ownerID, videoID, func(video *domain.Video) {
+		video.Chapters = chapters
+	})
+}
+
+func (r *VideosBoltRepo) Delete(ctx context.Context, videoID primitive.ObjectID) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		return boltDelete(tx, videosCollection, videoID)
+	})
+}
+
+func (r *VideosBoltRepo) GetPathsByFolders(ctx context.Context, ownerID primitive.ObjectID, foldersID []primitive.ObjectID) ([]string, []string, error) {
+	videos, err := r.find(ownedVideo(ownerID, inFolders(foldersID)))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var realPaths, previewPaths []string
+	for _, video := range videos {
+		realPaths = append(realPaths, video.RealPath)
+		previewPaths = append(previewPaths, video.PreviewPath)
+	}
+
+	return realPaths, previewPaths, nil
+}
+
+func (r *VideosBoltRepo) DeleteVideos(ctx context.Context, foldersID []primitive.ObjectID) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		videos, err := boltFind(tx, videosCollection, inFolders(foldersID))
+		if err != nil {
+			return err
+		}
+
+		for _, video := range videos {
+			if err := boltDelete(tx, videosCollection, video.ID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *VideosBoltRepo) GetVideos(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) ([]domain.Video, error) {
+	return r.find(ownedVideo(ownerID, inFolders([]primitive.ObjectID{folderID})))
+}
+
+func (r *VideosBoltRepo) GetAll(ctx context.Context) ([]domain.Video, error) {
+	return r.find(all[domain.Video])
+}
+
+func (r *VideosBoltRepo) find(match func(domain.Video) bool) ([]domain.Video, error) {
+	var videos []domain.Video
+
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		videos, err = boltFind(tx, videosCollection, match)
+		return err
+	})
+
+	return videos, err
+}
+
+// Lookup returns the video whatever its owner, for access checks.
+func (r *VideosBoltRepo) Lookup(ctx context.Context, videoID primitive.ObjectID) (domain.Video, error) {
+	var video domain.Video
+
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		video, err = boltGet[domain.Video](tx, videosCollection, videoID)
+		return err
+	})
+
+	return video, err
+}
+
+// ClaimUnowned gives every video without an owner to ownerID.
+func (r *VideosBoltRepo) ClaimUnowned(ctx context.Context, ownerID primitive.ObjectID) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		videos, err := boltFind(tx, videosCollection, ownedVideo(primitive.NilObjectID, all[domain.Video]))
+		if err != nil {
+			return err
+		}
+
+		for _, video := range videos {
+			video.OwnerID = ownerID
+			if err := boltPut(tx, videosCollection, video.ID, video); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *VideosBoltRepo) update(ownerID primitive.ObjectID, videoID primitive.ObjectID, apply func(video *domain.Video)) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		video, err := boltGet[domain.Video](tx, videosCollection, videoID)
+		if err != nil || video.OwnerID != ownerID {
+			return nil
+		}
+
+		apply(&video)
+
+		if err := r.checkNameFree(tx, video); err != nil {
+			return err
+		}
+
+		return boltPut(tx, videosCollection, videoID, video)
+	})
+}
+
+func (r *VideosBoltRepo) checkNameFree(tx *bbolt.Tx, video domain.Video) error {
+	same, err := boltFind(tx, videosCollection, func(other domain.Video) bool {
+		return other.ID != video.ID && other.OwnerID == video.OwnerID && other.VideoName == video.VideoName && other.FolderID == video.FolderID
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(same) > 0 {
+		return domain.ErrDuplicateKey
+	}
+
+	return nil
+}
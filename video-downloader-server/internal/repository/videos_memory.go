@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"slices"
+	"sort"
+	"sync"
+	"video-downloader-server/internal/domain"
+)
+
+type VideosMemoryRepo struct {
+	mu     sync.RWMutex
+	videos map[primitive.ObjectID]domain.Video
+}
+
+func NewVideosMemoryRepo() *VideosMemoryRepo {
+	return &VideosMemoryRepo{
+		videos: make(map[primitive.ObjectID]domain.Video),
+	}
+}
+
+func (r *VideosMemoryRepo) Create(ctx context.Context, video domain.Video) (primitive.ObjectID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if video.ID == primitive.NilObjectID {
+		video.ID = primitive.NewObjectID()
+	}
+
+	if _, ok := r.videos[video.ID]; ok {
+		return primitive.NilObjectID, domain.ErrDuplicateKey
+	}
+
+	if r.nameTaken(video) {
+		return primitive.NilObjectID, domain.ErrDuplicateKey
+	}
+
+	video.Subtitles = slices.Clone(video.Subtitles)
+	video.Chapters = slices.Clone(video.Chapters)
+	r.videos[video.ID] = video
+
+	return video.ID, nil
+}
+
+func (r *VideosMemoryRepo) GetByID(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID) (domain.Video, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	video, ok := r.videos[videoID]
+	if !ok || video.OwnerID != ownerID {
+		return domain.Video{}, domain.ErrNoDocuments
+	}
+
+	return video, nil
+}
+
+func (r *VideosMemoryRepo) GetByName(ctx context.Context, ownerID primitive.ObjectID, videoName string, folderID primitive.ObjectID) (domain.Video, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, video := range r.videos {
+		if video.OwnerID == ownerID && video.VideoName == videoName && video.FolderID == folderID {
+			return video, nil
+		}
+	}
+
+	return domain.Video{}, domain.ErrNoDocuments
+}
+
+func (r *VideosMemoryRepo) GetRealPath(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID) (string, error) {
+	video, err := r.GetByID(ctx, ownerID, videoID)
+	if err != nil {
+		return "", err
+	}
+
+	return video.RealPath, nil
+}
+
+func (r *VideosMemoryRepo) Rename(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, newVideoName string) error {
+	return r.update(ownerID, videoID, func(video *domain.Video) {
+		video.VideoName = newVideoName
+	})
+}
+
+func (r *VideosMemoryRepo) Move(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, folderID primitive.ObjectID, videoName string) error {
+	return r.update(ownerID, videoID, func(video *domain.Video) {
+		video.FolderID = folderID
+		video.VideoName = videoName
+	})
+}
+
+func (r *VideosMemoryRepo) SetSubtitles(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, subtitles []domain.Subtitle) error {
+	return r.update(ownerID, videoID, func(video *domain.Video) {
+		video.Subtitles = slices.Clone(subtitles)
+	})
+}
+
+func (r *VideosMemoryRepo) SetChapters(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, chapters []domain.Chapter) error {
+	return r.update(ownerID, videoID, func(video *domain.Video) {
+		video.Chapters = slices.Clone(chapters)
+	})
+}
+
+func (r *VideosMemoryRepo) Delete(ctx context.Context, videoID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.videos, videoID)
+
+	return nil
+}
+
+func (r *VideosMemoryRepo) GetPathsByFolders(ctx context.Context, ownerID primitive.ObjectID, foldersID []primitive.ObjectID) ([]string, []string, error) {
+	var realPaths, previewPaths []string
+
+	for _, video := range r.filter(ownedVideo(ownerID, inFolders(foldersID))) {
+		realPaths = append(realPaths, video.RealPath)
+		previewPaths = append(previewPaths, video.PreviewPath)
+	}
+
+	return realPaths, previewPaths, nil
+}
+
+func (r *VideosMemoryRepo) DeleteVideos(ctx context.Context, foldersID []primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	match := inFolders(foldersID)
+	for videoID, video := range r.videos {
+		if match(video) {
+			delete(r.videos, videoID)
+		}
+	}
+
+	return nil
+}
+
+func (r *VideosMemoryRepo) GetVideos(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) ([]domain.Video, error) {
+	return r.filter(ownedVideo(ownerID, inFolders([]primitive.ObjectID{folderID}))), nil
+}
+
+func (r *VideosMemoryRepo) GetAll(ctx context.Context) ([]domain.Video, error) {
+	return r.filter(func(domain.Video) bool { return true }), nil
+}
+
+// Lookup returns the video whatever its owner, for access checks.
+func (r *VideosMemoryRepo) Lookup(ctx context.Context, videoID primitive.ObjectID) (domain.Video, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	video, ok := r.videos[videoID]
+	if !ok {
+		return domain.Video{}, domain.ErrNoDocuments
+	}
+
+	return video, nil
+}
+
+// ClaimUnowned gives every video without an owner to ownerID.
+func (r *VideosMemoryRepo) ClaimUnowned(ctx context.Context, ownerID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for videoID, video := range r.videos {
+		if video.OwnerID == primitive.NilObjectID {
+			video.OwnerID = ownerID
+			r.videos[videoID] = video
+		}
+	}
+
+	return nil
+}
+
+func (r *VideosMemoryRepo) update(ownerID primitive.ObjectID, videoID primitive.ObjectID, apply func(video *domain.Video)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	video, ok := r.videos[videoID]
+	if !ok || video.OwnerID != ownerID {
+		return nil
+	}
+
+	apply(&video)
+
+	if r.nameTaken(video) {
+		return domain.ErrDuplicateKey
+	}
+
+	r.videos[videoID] = video
+
+	return nil
+}
+
+func (r *VideosMemoryRepo) nameTaken(video domain.Video) bool {
+	for _, other := range r.videos {
+		if other.ID != video.ID && other.OwnerID == video.OwnerID && other.VideoName == video.VideoName && other.FolderID == video.FolderID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filter returns the matching videos in insertion order, like a mongo find without sort.
+func (r *VideosMemoryRepo) filter(match func(domain.Video) bool) []domain.Video {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var videos []domain.Video
+	for _, video := range r.videos {
+		if match(video) {
+			videos = append(videos, video)
+		}
+	}
+
+	sort.Slice(videos, func(i, j int) bool {
+		return videos[i].ID.Hex() < videos[j].ID.Hex()
+	})
+
+	return videos
+}
+
+func inFolders(foldersID []primitive.ObjectID) func(domain.Video) bool {
+	return func(video domain.Video) bool {
+		for _, folderID := range foldersID {
+			if video.FolderID == folderID {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+func ownedVideo(ownerID primitive.ObjectID, match func(domain.Video) bool) func(domain.Video) bool {
+	return func(video domain.Video) bool {
+		return video.OwnerID == ownerID && match(video)
+	}
+}
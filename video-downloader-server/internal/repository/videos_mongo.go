@@ -6,6 +6,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
 	"video-downloader-server/internal/domain"
 )
 
@@ -13,101 +14,200 @@ const (
 	videosCollection = "videos"
 )
 
-type VideosRepo struct {
-	db *mongo.Collection
+type VideosMongoRepo struct {
+	db        *mongo.Collection
+	opTimeout time.Duration
 }
 
-func NewVideosRepo(db *mongo.Database) *VideosRepo {
-	return &VideosRepo{
-		db: db.Collection(videosCollection),
+func NewVideosMongoRepo(db *mongo.Database, opTimeout time.Duration) *VideosMongoRepo {
+	return &VideosMongoRepo{
+		db:        db.Collection(videosCollection),
+		opTimeout: opTimeout,
 	}
 }
 
-func (r *VideosRepo) Create(ctx context.Context, video domain.Video) error {
-	_, err := r.db.InsertOne(ctx, video)
-	return err
+func (r *VideosMongoRepo) Create(ctx context.Context, video domain.Video) (primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	res, err := r.db.InsertOne(ctx, video)
+	if err != nil {
+		return primitive.NilObjectID, convertMongoErr(err)
+	}
+
+	return res.InsertedID.(primitive.ObjectID), nil
+}
+
+func (r *VideosMongoRepo) GetByID(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID) (domain.Video, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	var video domain.Video
+
+	if err := r.db.FindOne(ctx, bson.M{"_id": videoID, "owner_id": ownerID}).Decode(&video); err != nil {
+		return domain.Video{}, convertMongoErr(err)
+	}
+
+	return video, nil
+}
+
+func (r *VideosMongoRepo) GetByName(ctx context.Context, ownerID primitive.ObjectID, videoName string, folderID primitive.ObjectID) (domain.Video, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	var video domain.Video
+
+	if err := r.db.FindOne(ctx, bson.M{"video_name": videoName, "folder_id": folderID, "owner_id": ownerID}).Decode(&video); err != nil {
+		return domain.Video{}, convertMongoErr(err)
+	}
+
+	return video, nil
 }
 
-func (r *VideosRepo) GetRealPath(ctx context.Context, videoID primitive.ObjectID) (string, error) {
+func (r *VideosMongoRepo) GetRealPath(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
 	var video domain.Video
 
-	err := r.db.FindOne(ctx, bson.M{"_id": videoID}, options.FindOne().SetProjection(bson.M{"realPath": 1, "_id": 0})).Decode(&video)
+	err := r.db.FindOne(ctx, bson.M{"_id": videoID, "owner_id": ownerID}, options.FindOne().SetProjection(bson.M{"real_path": 1, "_id": 0})).Decode(&video)
 	if err != nil {
-		return "", err
+		return "", convertMongoErr(err)
 	}
 
 	return video.RealPath, nil
 }
 
-func (r *VideosRepo) CheckExistByID(ctx context.Context, videoID primitive.ObjectID) error {
-	return r.db.FindOne(ctx, bson.M{"_id": videoID}).Err()
+func (r *VideosMongoRepo) Rename(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, newVideoName string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	_, err := r.db.UpdateOne(ctx, bson.M{"_id": videoID, "owner_id": ownerID}, bson.M{"$set": bson.M{"video_name": newVideoName}})
+	return convertMongoErr(err)
+}
+
+func (r *VideosMongoRepo) Move(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, folderID primitive.ObjectID, videoName string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	_, err := r.db.UpdateOne(ctx, bson.M{"_id": videoID, "owner_id": ownerID}, bson.M{"$set": bson.M{"folder_id": folderID, "video_name": videoName}})
+	return convertMongoErr(err)
 }
 
-func (r *VideosRepo) Rename(ctx context.Context, videoID primitive.ObjectID, newVideoName string) error {
-	_, err := r.db.UpdateOne(ctx, bson.M{"_id": videoID}, bson.M{"$set": bson.M{"video_name": newVideoName}})
-	return err
+func (r *VideosMongoRepo) SetSubtitles(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, subtitles []domain.Subtitle) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	_, err := r.db.UpdateOne(ctx, bson.M{"_id": videoID, "owner_id": ownerID}, bson.M{"$set": bson.M{"subtitles": subtitles}})
+	return convertMongoErr(err)
 }
 
-func (r *VideosRepo) Move(ctx context.Context, videoID primitive.ObjectID, folderID primitive.ObjectID) error {
-	_, err := r.db.UpdateOne(ctx, bson.M{"_id": videoID}, bson.M{"$set": bson.M{"folder_id": folderID}})
-	return err
+func (r *VideosMongoRepo) SetChapters(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, chapters []domain.Chapter) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	_, err := r.db.UpdateOne(ctx, bson.M{"_id": videoID, "owner_id": ownerID}, bson.M{"$set": bson.M{"chapters": chapters}})
+	return convertMongoErr(err)
 }
 
-func (r *VideosRepo) Delete(ctx context.Context, videoID primitive.ObjectID) error {
+func (r *VideosMongoRepo) Delete(ctx context.Context, videoID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
 	_, err := r.db.DeleteMany(ctx, bson.M{"_id": videoID})
-	return err
+	return convertMongoErr(err)
 }
 
-func (r *VideosRepo) GetPathsByFolders(ctx context.Context, foldersID []primitive.ObjectID) ([]string, []string, error) {
-	cursor, err := r.db.Find(ctx, bson.M{"folder_id": bson.M{"$in": foldersID}}, options.Find().SetProjection(bson.M{"real_path": 1, "preview_path": 1, "_id": 0}))
+func (r *VideosMongoRepo) GetPathsByFolders(ctx context.Context, ownerID primitive.ObjectID, foldersID []primitive.ObjectID) ([]string, []string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	cursor, err := r.db.Find(ctx, bson.M{"folder_id": bson.M{"$in": foldersID}, "owner_id": ownerID}, options.Find().SetProjection(bson.M{"real_path": 1, "preview_path": 1, "_id": 0}))
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, convertMongoErr(err)
 	}
 	defer cursor.Close(ctx)
 
-	var video domain.Video
-	var realPaths, previewPaths []string
+	var videos []domain.Video
+	if err := cursor.All(ctx, &videos); err != nil {
+		return nil, nil, convertMongoErr(err)
+	}
 
-	for cursor.Next(ctx) {
-		if err := cursor.Decode(&video); err != nil {
-			return nil, nil, err
-		}
+	var realPaths, previewPaths []string
+	for _, video := range videos {
 		realPaths = append(realPaths, video.RealPath)
 		previewPaths = append(previewPaths, video.PreviewPath)
 	}
 
-	if err := cursor.Err(); err != nil {
-		return nil, nil, err
-	}
-
 	return realPaths, previewPaths, nil
 }
 
-func (r *VideosRepo) DeleteVideos(ctx context.Context, foldersID []primitive.ObjectID) error {
+func (r *VideosMongoRepo) DeleteVideos(ctx context.Context, foldersID []primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
 	_, err := r.db.DeleteMany(ctx, bson.M{"folder_id": bson.M{"$in": foldersID}})
-	return err
+	return convertMongoErr(err)
 }
 
-func (r *VideosRepo) GetVideos(ctx context.Context, folderID primitive.ObjectID) ([]domain.Video, error) {
-	cursor, err := r.db.Find(ctx, bson.M{"folder_id": folderID})
+func (r *VideosMongoRepo) GetVideos(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) ([]domain.Video, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	cursor, err := r.db.Find(ctx, bson.M{"folder_id": folderID, "owner_id": ownerID})
 	if err != nil {
-		return nil, err
+		return nil, convertMongoErr(err)
 	}
 	defer cursor.Close(ctx)
 
+	// cursor.All decodes every document into a fresh video, decoding into
+	// one reused video would carry its subtitles and chapters over to the
+	// next documents, which omit them when empty.
 	var videos []domain.Video
-	var video domain.Video
+	if err := cursor.All(ctx, &videos); err != nil {
+		return nil, convertMongoErr(err)
+	}
+
+	return videos, nil
+}
+
+func (r *VideosMongoRepo) GetAll(ctx context.Context) ([]domain.Video, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
 
-	for cursor.Next(ctx) {
-		if err := cursor.Decode(&video); err != nil {
-			return nil, err
-		}
-		videos = append(videos, video)
+	cursor, err := r.db.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, convertMongoErr(err)
 	}
+	defer cursor.Close(ctx)
 
-	if err := cursor.Err(); err != nil {
-		return nil, err
+	var videos []domain.Video
+	if err := cursor.All(ctx, &videos); err != nil {
+		return nil, convertMongoErr(err)
 	}
 
 	return videos, nil
 }
+
+// Lookup returns the video whatever its owner, for access checks.
+func (r *VideosMongoRepo) Lookup(ctx context.Context, videoID primitive.ObjectID) (domain.Video, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	var video domain.Video
+
+	if err := r.db.FindOne(ctx, bson.M{"_id": videoID}).Decode(&video); err != nil {
+		return domain.Video{}, convertMongoErr(err)
+	}
+
+	return video, nil
+}
+
+// ClaimUnowned gives every video without an owner to ownerID.
+func (r *VideosMongoRepo) ClaimUnowned(ctx context.Context, ownerID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	_, err := r.db.UpdateMany(ctx, bson.M{"owner_id": bson.M{"$in": bson.A{nil, primitive.NilObjectID}}}, bson.M{"$set": bson.M{"owner_id": ownerID}})
+	return convertMongoErr(err)
+}
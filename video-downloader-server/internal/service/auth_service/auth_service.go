@@ -0,0 +1,408 @@
+package auth_service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"time"
+	"video-downloader-server/internal/delivery/dto/auth_dto"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/logger"
+	"video-downloader-server/internal/tracing"
+)
+
+const (
+	adminCreated   = "initial admin account has been created"
+	unownedClaimed = "videos and folders without an owner have been given to the admin"
+
+	errSettingLastUsed = "error recording api token use"
+)
+
+type UsersRepo interface {
+	Create(ctx context.Context, user domain.User) (primitive.ObjectID, error)
+	GetByID(ctx context.Context, userID primitive.ObjectID) (domain.User, error)
+	GetByUsername(ctx context.Context, username string) (domain.User, error)
+	Count(ctx context.Context) (int64, error)
+}
+
+type SessionsRepo interface {
+	Create(ctx context.Context, session domain.Session) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (domain.Session, error)
+	Delete(ctx context.Context, tokenHash string) error
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
+
+type APITokensRepo interface {
+	Create(ctx context.Context, token domain.APIToken) (primitive.ObjectID, error)
+	GetByTokenHash(ctx context.Context, tokenHash string) (domain.APIToken, error)
+	GetByUser(ctx context.Context, userID primitive.ObjectID) ([]domain.APIToken, error)
+	Delete(ctx context.Context, userID primitive.ObjectID, tokenID primitive.ObjectID) error
+	SetLastUsed(ctx context.Context, tokenID primitive.ObjectID, lastUsedAt time.Time) error
+}
+
+type Claimer interface {
+	ClaimUnowned(ctx context.Context, ownerID primitive.ObjectID) error
+}
+
+// AuthService manages user accounts, their login sessions and their personal
+// API tokens. Tokens are random and opaque to clients, only their hash is
+// stored.
+type AuthService struct {
+	usersRepo     UsersRepo
+	sessionsRepo  SessionsRepo
+	apiTokensRepo APITokensRepo
+	videosRepo    Claimer
+	foldersRepo   Claimer
+
+	sessionTTL  time.Duration
+	bcryptCost  int
+	allowSignup bool
+
+	// dummyHash is compared against when the user does not exist, so that a
+	// failed login takes as long for a missing user as for a wrong password.
+	dummyHash []byte
+}
+
+func NewAuthService(usersRepo UsersRepo, sessionsRepo SessionsRepo, apiTokensRepo APITokensRepo, videosRepo Claimer, foldersRepo Claimer, sessionTTL time.Duration, bcryptCost int, allowSignup bool) *AuthService {
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), bcryptCost)
+
+	return &AuthService{
+		usersRepo:     usersRepo,
+		sessionsRepo:  sessionsRepo,
+		apiTokensRepo: apiTokensRepo,
+		videosRepo:    videosRepo,
+		foldersRepo:   foldersRepo,
+		sessionTTL:    sessionTTL,
+		bcryptCost:    bcryptCost,
+		allowSignup:   allowSignup,
+		dummyHash:     dummyHash,
+	}
+}
+
+// Bootstrap creates the admin account when there are no users yet and gives
+// it every video and folder saved before accounts existed. Without a
+// password nothing is created.
+func (s *AuthService) Bootstrap(ctx context.Context, adminUsername, adminPassword string) error {
+	count, err := s.usersRepo.Count(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %s", domain.ErrCountingUsers, err)
+	}
+
+	if count > 0 || adminPassword == "" {
+		return nil
+	}
+
+	admin, err := s.create(ctx, adminUsername, adminPassword, domain.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	log.WithField("username", admin.Username).Info(adminCreated)
+
+	if err := s.videosRepo.ClaimUnowned(ctx, admin.ID); err != nil {
+		return fmt.Errorf("%w (user id: %s): %s", domain.ErrClaimingUnownedData, admin.ID, err)
+	}
+
+	if err := s.foldersRepo.ClaimUnowned(ctx, admin.ID); err != nil {
+		return fmt.Errorf("%w (user id: %s): %s", domain.ErrClaimingUnownedData, admin.ID, err)
+	}
+	log.WithField("username", admin.Username).Info(unownedClaimed)
+
+	return nil
+}
+
+// Register creates a regular account, if sign up is allowed.
+func (s *AuthService) Register(ctx context.Context, registerInput auth_dto.RegisterDto) (_ auth_dto.UserDto, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Register")
+	defer tracing.End(span, &err)
+
+	if !s.allowSignup {
+		return auth_dto.UserDto{}, domain.ErrSignupDisabled
+	}
+
+	user, err := s.create(ctx, registerInput.Username, registerInput.Password, domain.RoleUser)
+	if err != nil {
+		return auth_dto.UserDto{}, err
+	}
+
+	return toUserDto(user), nil
+}
+
+// CreateUser creates an account with any role on behalf of an admin.
+func (s *AuthService) CreateUser(ctx context.Context, createUserInput auth_dto.CreateUserDto) (_ auth_dto.UserDto, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.CreateUser")
+	defer tracing.End(span, &err)
+
+	user, err := s.create(ctx, createUserInput.Username, createUserInput.Password, createUserInput.Role)
+	if err != nil {
+		return auth_dto.UserDto{}, err
+	}
+
+	return toUserDto(user), nil
+}
+
+// Login checks the credentials and starts a new session.
+func (s *AuthService) Login(ctx context.Context, loginInput auth_dto.LoginDto) (_ auth_dto.SessionDto, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Login")
+	defer tracing.End(span, &err)
+
+	user, err := s.usersRepo.GetByUsername(ctx, loginInput.Username)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNoDocuments) {
+			return auth_dto.SessionDto{}, fmt.Errorf("%w (username: %s): %s", domain.ErrGettingUser, loginInput.Username, err)
+		}
+
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(loginInput.Password))
+		return auth_dto.SessionDto{}, fmt.Errorf("%w (username: %s)", domain.ErrInvalidCredentials, loginInput.Username)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(loginInput.Password)); err != nil {
+		return auth_dto.SessionDto{}, fmt.Errorf("%w (username: %s)", domain.ErrInvalidCredentials, loginInput.Username)
+	}
+
+	token, tokenHash, err := newToken()
+	if err != nil {
+		return auth_dto.SessionDto{}, fmt.Errorf("%w (user id: %s): %s", domain.ErrCreatingSession, user.ID, err)
+	}
+
+	now := time.Now()
+	session := domain.Session{
+		TokenHash: tokenHash,
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.sessionTTL),
+	}
+
+	s.sessionsRepo.DeleteExpired(ctx, now)
+	if err := s.sessionsRepo.Create(ctx, session); err != nil {
+		return auth_dto.SessionDto{}, fmt.Errorf("%w (user id: %s): %s", domain.ErrCreatingSession, user.ID, err)
+	}
+
+	return auth_dto.SessionDto{
+		Token:     token,
+		ExpiresAt: session.ExpiresAt,
+		User:      toUserDto(user),
+	}, nil
+}
+
+// Logout ends the session of token. Unknown tokens are ignored.
+func (s *AuthService) Logout(ctx context.Context, token string) (err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Logout")
+	defer tracing.End(span, &err)
+
+	if err := s.sessionsRepo.Delete(ctx, hashToken(token)); err != nil {
+		return fmt.Errorf("%w: %s", domain.ErrDeletingSession, err)
+	}
+
+	return nil
+}
+
+// Authenticate returns the user of token and the scopes granted to it. Login
+// sessions are granted every scope, API tokens the scopes they were created
+// with. It fails with ErrUnauthorized when there is no such session or token,
+// or the session has expired.
+func (s *AuthService) Authenticate(ctx context.Context, token string) (_ domain.User, _ []string, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Authenticate")
+	defer tracing.End(span, &err)
+
+	if strings.HasPrefix(token, domain.APITokenPrefix) {
+		return s.authenticateAPIToken(ctx, token)
+	}
+
+	session, err := s.sessionsRepo.GetByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, domain.ErrNoDocuments) {
+			return domain.User{}, nil, domain.ErrUnauthorized
+		}
+
+		return domain.User{}, nil, fmt.Errorf("%w: %s", domain.ErrGettingUser, err)
+	}
+
+	if !session.ExpiresAt.After(time.Now()) {
+		return domain.User{}, nil, fmt.Errorf("%w: session expired at %s", domain.ErrUnauthorized, session.ExpiresAt)
+	}
+
+	user, err := s.getUser(ctx, session.UserID)
+	if err != nil {
+		return domain.User{}, nil, err
+	}
+
+	return user, domain.AllScopes, nil
+}
+
+// CreateToken creates an API token for userID. The plaintext token is only
+// returned here.
+func (s *AuthService) CreateToken(ctx context.Context, userID primitive.ObjectID, createTokenInput auth_dto.CreateTokenDto) (_ auth_dto.TokenDto, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.CreateToken")
+	defer tracing.End(span, &err)
+
+	secret, _, err := newToken()
+	if err != nil {
+		return auth_dto.TokenDto{}, fmt.Errorf("%w (user id: %s): %s", domain.ErrCreatingAPIToken, userID, err)
+	}
+	token := domain.APITokenPrefix + secret
+
+	apiToken := domain.APIToken{
+		UserID:    userID,
+		Name:      createTokenInput.Name,
+		TokenHash: hashToken(token),
+		Scopes:    createTokenInput.Scopes,
+		CreatedAt: time.Now(),
+	}
+
+	apiToken.ID, err = s.apiTokensRepo.Create(ctx, apiToken)
+	if err != nil {
+		return auth_dto.TokenDto{}, fmt.Errorf("%w (user id: %s, name: %s): %s", domain.ErrCreatingAPIToken, userID, createTokenInput.Name, err)
+	}
+
+	res := toTokenDto(apiToken)
+	res.Token = token
+
+	return res, nil
+}
+
+// ListTokens returns the API tokens of userID, without their plaintext.
+func (s *AuthService) ListTokens(ctx context.Context, userID primitive.ObjectID) (_ []auth_dto.TokenDto, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.ListTokens")
+	defer tracing.End(span, &err)
+
+	tokens, err := s.apiTokensRepo.GetByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w (user id: %s): %s", domain.ErrGettingAPITokens, userID, err)
+	}
+
+	res := make([]auth_dto.TokenDto, 0, len(tokens))
+	for _, token := range tokens {
+		res = append(res, toTokenDto(token))
+	}
+
+	return res, nil
+}
+
+// RevokeToken deletes the API token tokenID of userID, it stops working at once.
+func (s *AuthService) RevokeToken(ctx context.Context, userID primitive.ObjectID, tokenID primitive.ObjectID) (err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.RevokeToken")
+	defer tracing.End(span, &err)
+
+	if err := s.apiTokensRepo.Delete(ctx, userID, tokenID); err != nil {
+		if errors.Is(err, domain.ErrNoDocuments) {
+			return fmt.Errorf("%w (token id: %s)", domain.ErrAPITokenNotFound, tokenID)
+		}
+
+		return fmt.Errorf("%w (token id: %s): %s", domain.ErrRevokingAPIToken, tokenID, err)
+	}
+
+	return nil
+}
+
+func (s *AuthService) authenticateAPIToken(ctx context.Context, token string) (domain.User, []string, error) {
+	apiToken, err := s.apiTokensRepo.GetByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, domain.ErrNoDocuments) {
+			return domain.User{}, nil, domain.ErrUnauthorized
+		}
+
+		return domain.User{}, nil, fmt.Errorf("%w: %s", domain.ErrGettingAPITokens, err)
+	}
+
+	user, err := s.getUser(ctx, apiToken.UserID)
+	if err != nil {
+		return domain.User{}, nil, err
+	}
+
+	now := time.Now()
+	if now.Sub(apiToken.LastUsedAt) >= domain.APITokenLastUsedResolution {
+		if err := s.apiTokensRepo.SetLastUsed(ctx, apiToken.ID, now); err != nil {
+			logger.FromContext(ctx).WithError(err).WithField("token_id", apiToken.ID.Hex()).Warn(errSettingLastUsed)
+		}
+	}
+
+	return user, apiToken.Scopes, nil
+}
+
+// getUser returns the user a session or token belongs to, a deleted user
+// makes the token unauthorized.
+func (s *AuthService) getUser(ctx context.Context, userID primitive.ObjectID) (domain.User, error) {
+	user, err := s.usersRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoDocuments) {
+			return domain.User{}, fmt.Errorf("%w (user id: %s): user not found", domain.ErrUnauthorized, userID)
+		}
+
+		return domain.User{}, fmt.Errorf("%w (user id: %s): %s", domain.ErrGettingUser, userID, err)
+	}
+
+	return user, nil
+}
+
+func (s *AuthService) create(ctx context.Context, username, password, role string) (domain.User, error) {
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), s.bcryptCost)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("%w (username: %s): %s", domain.ErrHashingPassword, username, err)
+	}
+
+	user := domain.User{
+		Username:     username,
+		PasswordHash: string(passwordHash),
+		Role:         role,
+		CreatedAt:    time.Now(),
+	}
+
+	user.ID, err = s.usersRepo.Create(ctx, user)
+	if err != nil {
+		if errors.Is(err, domain.ErrDuplicateKey) {
+			return domain.User{}, fmt.Errorf("%w (username: %s)", domain.ErrUserAlreadyExist, username)
+		}
+
+		return domain.User{}, fmt.Errorf("%w (username: %s): %s", domain.ErrCreatingUser, username, err)
+	}
+
+	return user, nil
+}
+
+// newToken returns a random token and the hash it is stored under.
+func newToken() (string, string, error) {
+	b := make([]byte, domain.SessionTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token := hex.EncodeToString(b)
+
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func toUserDto(user domain.User) auth_dto.UserDto {
+	return auth_dto.UserDto{
+		ID:        user.ID,
+		Username:  user.Username,
+		Role:      user.Role,
+		CreatedAt: user.CreatedAt,
+	}
+}
+
+func toTokenDto(token domain.APIToken) auth_dto.TokenDto {
+	res := auth_dto.TokenDto{
+		ID:        token.ID,
+		Name:      token.Name,
+		Scopes:    token.Scopes,
+		CreatedAt: token.CreatedAt,
+	}
+
+	if !token.LastUsedAt.IsZero() {
+		res.LastUsedAt = &token.LastUsedAt
+	}
+
+	return res
+}
@@ -0,0 +1,137 @@
+package chapters_service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/metrics"
+	"video-downloader-server/internal/service/common"
+	"video-downloader-server/internal/tracing"
+)
+
+// ChaptersService reads the chapters stored in video containers and cuts
+// videos into their chapters.
+type ChaptersService struct {
+	videoDir    string
+	ffmpegPath  string
+	ffprobePath string
+}
+
+func NewChaptersService(videoDir, ffmpegPath, ffprobePath string) *ChaptersService {
+	return &ChaptersService{
+		videoDir:    videoDir,
+		ffmpegPath:  ffmpegPath,
+		ffprobePath: ffprobePath,
+	}
+}
+
+type probeOutput struct {
+	Chapters []struct {
+		StartTime string `json:"start_time"`
+		EndTime   string `json:"end_time"`
+		Tags      struct {
+			Title string `json:"title"`
+		} `json:"tags"`
+	} `json:"chapters"`
+}
+
+// Probe returns the chapters of the video at realPath, such as the chapter
+// atoms of an mp4. Untitled chapters are named after their number.
+func (c *ChaptersService) Probe(ctx context.Context, realPath string) (_ []domain.Chapter, err error) {
+	ctx, span := tracing.StartProcess(ctx, metrics.FfmpegProbe, c.ffprobePath)
+	defer tracing.End(span, &err)
+
+	videoPath := filepath.Join(c.videoDir, realPath)
+
+	cmd := common.Command(ctx, c.ffprobePath, "-v", "error", "-show_chapters", "-of", "json", videoPath)
+	start := time.Now()
+	output, err := cmd.Output()
+	metrics.ObserveFfmpeg(metrics.FfmpegProbe, start, err)
+	if err != nil {
+		return nil, fmt.Errorf("%w (video path: %s): %s", domain.ErrExtractingChapters, videoPath, err)
+	}
+
+	var probed probeOutput
+	if err := json.Unmarshal(output, &probed); err != nil {
+		return nil, fmt.Errorf("%w (video path: %s): %s", domain.ErrExtractingChapters, videoPath, err)
+	}
+
+	chapters := make([]domain.Chapter, 0, len(probed.Chapters))
+	for i, probedChapter := range probed.Chapters {
+		chapterStart, err := parseSeconds(probedChapter.StartTime)
+		if err != nil {
+			return nil, fmt.Errorf("%w (video path: %s): %s", domain.ErrExtractingChapters, videoPath, err)
+		}
+
+		chapterEnd, err := parseSeconds(probedChapter.EndTime)
+		if err != nil {
+			return nil, fmt.Errorf("%w (video path: %s): %s", domain.ErrExtractingChapters, videoPath, err)
+		}
+
+		title := strings.TrimSpace(probedChapter.Tags.Title)
+		if title == "" {
+			title = fmt.Sprintf("Chapter %d", i+1)
+		}
+
+		chapters = append(chapters, domain.Chapter{Title: title, Start: chapterStart, End: chapterEnd})
+	}
+
+	return chapters, nil
+}
+
+// Cut copies the streams of chapter number n of the video at realPath into a
+// new file in the video directory and returns its real path. The streams
+// aren't re-encoded, so the cut lands on the keyframe nearest to the start.
+func (c *ChaptersService) Cut(ctx context.Context, realPath string, chapter domain.Chapter, n int) (_ string, err error) {
+	ctx, span := tracing.StartProcess(ctx, metrics.FfmpegSplit, c.ffmpegPath)
+	defer tracing.End(span, &err)
+
+	dir, err := common.CreateRandomDir(c.videoDir)
+	if err != nil {
+		return "", err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(realPath), filepath.Ext(realPath))
+	newRealPath := filepath.Join(dir, fmt.Sprintf("%s_%02d%s", name, n, filepath.Ext(realPath)))
+
+	args := []string{"-v", "error", "-y", "-ss", formatSeconds(chapter.Start), "-i", filepath.Join(c.videoDir, realPath)}
+	if chapter.End > chapter.Start {
+		args = append(args, "-t", formatSeconds(chapter.End-chapter.Start))
+	}
+	args = append(args, "-map", "0", "-map_chapters", "-1", "-c", "copy", "-avoid_negative_ts", "make_zero", filepath.Join(c.videoDir, newRealPath))
+
+	cmd := common.Command(ctx, c.ffmpegPath, args...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err = cmd.Run()
+	metrics.ObserveFfmpeg(metrics.FfmpegSplit, start, err)
+	if err != nil {
+		os.Remove(filepath.Join(c.videoDir, newRealPath))
+		return "", fmt.Errorf("%w (chapter: %s, ffmpeg output: %s): %s", domain.ErrSplittingVideo, chapter.Title, stderr.String(), err)
+	}
+
+	return newRealPath, nil
+}
+
+func parseSeconds(seconds string) (time.Duration, error) {
+	value, err := strconv.ParseFloat(seconds, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(value * float64(time.Second)), nil
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
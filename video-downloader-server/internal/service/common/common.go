@@ -1,12 +1,16 @@
 package common
 
 import (
+	"context"
 	crypto "crypto/rand"
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
+	"syscall"
+	"time"
 	"video-downloader-server/internal/domain"
 )
 
@@ -44,6 +48,8 @@ func ReplaceSpecialSymbols(videoName string) string {
 	return safeFileName
 }
 
+// CreateAndWriteFile writes data to a new file and removes the partial file
+// if writing fails.
 func CreateAndWriteFile(filePath string, data io.ReadCloser) error {
 	file, err := os.Create(filePath)
 	if err != nil {
@@ -53,8 +59,78 @@ func CreateAndWriteFile(filePath string, data io.ReadCloser) error {
 
 	_, err = io.Copy(file, data)
 	if err != nil {
+		file.Close()
+		os.Remove(filePath)
 		return fmt.Errorf("%w (filepath: %s): %s", domain.ErrSavingDataToFile, filePath, err)
 	}
 
 	return nil
 }
+
+// Command is exec.CommandContext that asks the process to exit with SIGTERM
+// when ctx is done and kills it if it is still running after CommandWaitDelay.
+func Command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = domain.CommandWaitDelay
+
+	return cmd
+}
+
+func CopyFile(srcPath, dstPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("%w (from: %s, to: %s): %s", domain.ErrCopyingFile, srcPath, dstPath, err)
+	}
+	defer src.Close()
+
+	if err := CreateAndWriteFile(dstPath, src); err != nil {
+		return fmt.Errorf("%w (from: %s, to: %s): %s", domain.ErrCopyingFile, srcPath, dstPath, err)
+	}
+
+	return nil
+}
+
+// ListFiles returns the paths, relative to root, of all regular files under root
+// that were last modified more than minAge ago. A missing root yields no files.
+func ListFiles(root string, minAge time.Duration) ([]string, error) {
+	var files []string
+	threshold := time.Now().Add(-minAge)
+
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if info.ModTime().After(threshold) {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, relPath)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w (root: %s): %s", domain.ErrListingFiles, root, err)
+	}
+
+	return files, nil
+}
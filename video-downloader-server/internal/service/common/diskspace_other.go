@@ -0,0 +1,14 @@
+//go:build !unix && !windows
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"video-downloader-server/internal/domain"
+)
+
+// FreeSpace is not supported on this platform.
+func FreeSpace(path string) (uint64, error) {
+	return 0, fmt.Errorf("%w (path: %s): %s", domain.ErrGettingFreeSpace, path, errors.ErrUnsupported)
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package common
+
+import (
+	"fmt"
+	"golang.org/x/sys/unix"
+	"video-downloader-server/internal/domain"
+)
+
+// FreeSpace returns the number of bytes available to unprivileged users on
+// the filesystem holding path.
+func FreeSpace(path string) (uint64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, fmt.Errorf("%w (path: %s): %s", domain.ErrGettingFreeSpace, path, err)
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
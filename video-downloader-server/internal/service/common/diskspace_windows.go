@@ -0,0 +1,25 @@
+//go:build windows
+
+package common
+
+import (
+	"fmt"
+	"golang.org/x/sys/windows"
+	"video-downloader-server/internal/domain"
+)
+
+// FreeSpace returns the number of bytes available to the current user on
+// the volume holding path.
+func FreeSpace(path string) (uint64, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, fmt.Errorf("%w (path: %s): %s", domain.ErrGettingFreeSpace, path, err)
+	}
+
+	var freeBytes uint64
+	if err := windows.GetDiskFreeSpaceEx(pathPtr, &freeBytes, nil, nil); err != nil {
+		return 0, fmt.Errorf("%w (path: %s): %s", domain.ErrGettingFreeSpace, path, err)
+	}
+
+	return freeBytes, nil
+}
@@ -5,54 +5,62 @@ import (
 	"errors"
 	"fmt"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"video-downloader-server/internal/delivery/dto/folder_dto"
 	"video-downloader-server/internal/delivery/dto/video_dto"
 	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/tracing"
 )
 
 type FoldersRepo interface {
-	CheckExistenceByID(ctx context.Context, folderID primitive.ObjectID) error
-	CheckExistenceByName(ctx context.Context, folderName string, parentDirID primitive.ObjectID) error
-	Create(ctx context.Context, folderName string, parentDirID primitive.ObjectID) (primitive.ObjectID, error)
-	GetParentDirID(ctx context.Context, folderID primitive.ObjectID) (primitive.ObjectID, error)
-	UpdateName(ctx context.Context, folderID primitive.ObjectID, newFolderName string) error
-	GetName(ctx context.Context, folderID primitive.ObjectID) (string, error)
-	Move(ctx context.Context, folderID primitive.ObjectID, parentDirID primitive.ObjectID) error
-	GetAllNestedFolders(ctx context.Context, parentDirID primitive.ObjectID) ([]primitive.ObjectID, error)
-	DeleteAllNestedFolders(ctx context.Context, foldersID []primitive.ObjectID) error
-	GetNestedFolders(ctx context.Context, folderID primitive.ObjectID) ([]domain.Folder, error)
+	CheckExistenceByName(ctx context.Context, ownerID primitive.ObjectID, folderName string, parentDirID primitive.ObjectID) error
+	Create(ctx context.Context, ownerID primitive.ObjectID, folderName string, parentDirID primitive.ObjectID) (primitive.ObjectID, error)
+	GetParentDirID(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) (primitive.ObjectID, error)
+	UpdateName(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID, newFolderName string) error
+	GetName(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) (string, error)
+	Move(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID, parentDirID primitive.ObjectID) error
+	GetAllNestedFolders(ctx context.Context, ownerID primitive.ObjectID, parentDirID primitive.ObjectID) ([]primitive.ObjectID, error)
+	GetNestedFolders(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) ([]domain.Folder, error)
 }
 
 type Videos interface {
-	DeleteVideos(foldersID []primitive.ObjectID) error
-	GetVideos(folderID primitive.ObjectID) ([]video_dto.VideoDto, error)
+	DeleteFolders(ctx context.Context, ownerID primitive.ObjectID, foldersID []primitive.ObjectID) error
+	GetVideos(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) ([]video_dto.VideoDto, error)
+}
+
+type Access interface {
+	Require(ctx context.Context, userID primitive.ObjectID, folderID primitive.ObjectID, role string) (primitive.ObjectID, error)
+	DeleteFolders(ctx context.Context, foldersID []primitive.ObjectID) error
 }
 
 type FoldersService struct {
-	repo          FoldersRepo
-	videosService Videos
+	repo           FoldersRepo
+	videosService  Videos
+	sharingService Access
 }
 
-func NewFoldersService(repo FoldersRepo, videosService Videos) *FoldersService {
+func NewFoldersService(repo FoldersRepo, videosService Videos, sharingService Access) *FoldersService {
 	return &FoldersService{
-		repo:          repo,
-		videosService: videosService,
+		repo:           repo,
+		videosService:  videosService,
+		sharingService: sharingService,
 	}
 }
 
-func (f *FoldersService) Create(createFolderInput folder_dto.CreateFolderDto) (folder_dto.FolderDto, error) {
-	if createFolderInput.ParentDirID != primitive.NilObjectID {
-		if err := f.checkFolderExistenceByID(createFolderInput.ParentDirID); err != nil {
-			return folder_dto.FolderDto{}, err
-		}
+// Create creates the folder in the tree of the owner of the parent folder.
+func (f *FoldersService) Create(ctx context.Context, userID primitive.ObjectID, createFolderInput folder_dto.CreateFolderDto) (_ folder_dto.FolderDto, err error) {
+	ctx, span := tracing.Start(ctx, "FoldersService.Create")
+	defer tracing.End(span, &err)
+
+	ownerID, err := f.sharingService.Require(ctx, userID, createFolderInput.ParentDirID, domain.FolderContributor)
+	if err != nil {
+		return folder_dto.FolderDto{}, err
 	}
 
-	if err := f.checkFolderExistenceByName(createFolderInput.FolderName, createFolderInput.ParentDirID); err != nil {
+	if err := f.checkFolderExistenceByName(ctx, ownerID, createFolderInput.FolderName, createFolderInput.ParentDirID); err != nil {
 		return folder_dto.FolderDto{}, err
 	}
 
-	folderID, err := f.repo.Create(context.Background(), createFolderInput.FolderName, createFolderInput.ParentDirID)
+	folderID, err := f.repo.Create(ctx, ownerID, createFolderInput.FolderName, createFolderInput.ParentDirID)
 	if err != nil {
 		return folder_dto.FolderDto{}, fmt.Errorf("%w (folder name: %s, parent dir id: %s): %s", domain.ErrCreatingFolder, createFolderInput.FolderName, createFolderInput.ParentDirID, err)
 	}
@@ -69,19 +77,27 @@ func (f *FoldersService) Create(createFolderInput folder_dto.CreateFolderDto) (f
 	}, nil
 }
 
-func (f *FoldersService) Rename(renameFolderInput folder_dto.RenameFolderDto) (folder_dto.FolderDto, error) {
-	parentDirID, err := f.repo.GetParentDirID(context.Background(), renameFolderInput.ID)
+func (f *FoldersService) Rename(ctx context.Context, userID primitive.ObjectID, renameFolderInput folder_dto.RenameFolderDto) (_ folder_dto.FolderDto, err error) {
+	ctx, span := tracing.Start(ctx, "FoldersService.Rename")
+	defer tracing.End(span, &err)
+
+	ownerID, err := f.sharingService.Require(ctx, userID, renameFolderInput.ID, domain.FolderContributor)
+	if err != nil {
+		return folder_dto.FolderDto{}, err
+	}
+
+	parentDirID, err := f.repo.GetParentDirID(ctx, ownerID, renameFolderInput.ID)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
+		if errors.Is(err, domain.ErrNoDocuments) {
 			return folder_dto.FolderDto{}, fmt.Errorf("%w (folder id: %s)", domain.ErrFolderNotFound, renameFolderInput.ID)
 		}
 	}
 
-	if err := f.checkFolderExistenceByName(renameFolderInput.FolderName, parentDirID); err != nil {
+	if err := f.checkFolderExistenceByName(ctx, ownerID, renameFolderInput.FolderName, parentDirID); err != nil {
 		return folder_dto.FolderDto{}, err
 	}
 
-	if err := f.repo.UpdateName(context.Background(), renameFolderInput.ID, renameFolderInput.FolderName); err != nil {
+	if err := f.repo.UpdateName(ctx, ownerID, renameFolderInput.ID, renameFolderInput.FolderName); err != nil {
 		return folder_dto.FolderDto{}, fmt.Errorf("%w (folder id: %s, folder name: %s): %s", domain.ErrRenamingFolder, renameFolderInput.ID, renameFolderInput.FolderName, err)
 	}
 
@@ -92,25 +108,36 @@ func (f *FoldersService) Rename(renameFolderInput folder_dto.RenameFolderDto) (f
 	}, nil
 }
 
-func (f *FoldersService) Move(moveFolderInput folder_dto.MoveFolderDto) (folder_dto.FolderDto, error) {
-	if err := f.checkFolderExistenceByID(moveFolderInput.ID); err != nil {
+// Move moves the folder within the tree of its owner, only owners of the
+// folder may move it.
+func (f *FoldersService) Move(ctx context.Context, userID primitive.ObjectID, moveFolderInput folder_dto.MoveFolderDto) (_ folder_dto.FolderDto, err error) {
+	ctx, span := tracing.Start(ctx, "FoldersService.Move")
+	defer tracing.End(span, &err)
+
+	ownerID, err := f.sharingService.Require(ctx, userID, moveFolderInput.ID, domain.FolderOwner)
+	if err != nil {
 		return folder_dto.FolderDto{}, err
 	}
 
-	if err := f.checkFolderExistenceByID(moveFolderInput.ParentDirID); err != nil {
+	parentOwnerID, err := f.sharingService.Require(ctx, userID, moveFolderInput.ParentDirID, domain.FolderContributor)
+	if err != nil {
 		return folder_dto.FolderDto{}, err
 	}
 
-	name, err := f.repo.GetName(context.Background(), moveFolderInput.ID)
+	if parentOwnerID != ownerID {
+		return folder_dto.FolderDto{}, fmt.Errorf("%w (folder id: %s, parent dir id: %s)", domain.ErrMovingAcrossOwners, moveFolderInput.ID, moveFolderInput.ParentDirID)
+	}
+
+	name, err := f.repo.GetName(ctx, ownerID, moveFolderInput.ID)
 	if err != nil {
 		return folder_dto.FolderDto{}, fmt.Errorf("%w (folder id: %s): %s", domain.ErrGettingFolderName, moveFolderInput.ID, err)
 	}
 
-	if err := f.checkFolderExistenceByName(name, moveFolderInput.ParentDirID); err != nil {
+	if err := f.checkFolderExistenceByName(ctx, ownerID, name, moveFolderInput.ParentDirID); err != nil {
 		return folder_dto.FolderDto{}, err
 	}
 
-	if err := f.repo.Move(context.Background(), moveFolderInput.ID, moveFolderInput.ParentDirID); err != nil {
+	if err := f.repo.Move(ctx, ownerID, moveFolderInput.ID, moveFolderInput.ParentDirID); err != nil {
 		return folder_dto.FolderDto{}, fmt.Errorf("%w (folder id: %s, parent dir id: %s): %s", domain.ErrMovingFolder, moveFolderInput.ID, moveFolderInput.ParentDirID, err)
 	}
 
@@ -121,12 +148,16 @@ func (f *FoldersService) Move(moveFolderInput folder_dto.MoveFolderDto) (folder_
 	}, nil
 }
 
-func (f *FoldersService) Delete(deleteFolderInput folder_dto.DeleteFolderDto) error {
-	if err := f.checkFolderExistenceByID(deleteFolderInput.ID); err != nil {
+func (f *FoldersService) Delete(ctx context.Context, userID primitive.ObjectID, deleteFolderInput folder_dto.DeleteFolderDto) (err error) {
+	ctx, span := tracing.Start(ctx, "FoldersService.Delete")
+	defer tracing.End(span, &err)
+
+	ownerID, err := f.sharingService.Require(ctx, userID, deleteFolderInput.ID, domain.FolderOwner)
+	if err != nil {
 		return err
 	}
 
-	allFolders, err := f.repo.GetAllNestedFolders(context.Background(), deleteFolderInput.ID)
+	allFolders, err := f.repo.GetAllNestedFolders(ctx, ownerID, deleteFolderInput.ID)
 	if err != nil {
 		return fmt.Errorf("%w (folder id: %s): %s", domain.ErrGettingAllNestedFolders, deleteFolderInput.ID, err)
 	}
@@ -135,28 +166,28 @@ func (f *FoldersService) Delete(deleteFolderInput folder_dto.DeleteFolderDto) er
 	foldersID = append(foldersID, deleteFolderInput.ID)
 	foldersID = append(foldersID, allFolders...)
 
-	if err := f.repo.DeleteAllNestedFolders(context.Background(), foldersID); err != nil {
-		return fmt.Errorf("%w (folder id: %s): %s", domain.ErrDeletingAllNestedFolders, deleteFolderInput.ID, err)
-	}
-
-	if err := f.videosService.DeleteVideos(foldersID); err != nil {
+	if err := f.videosService.DeleteFolders(ctx, ownerID, foldersID); err != nil {
 		return err
 	}
 
-	return nil
+	return f.sharingService.DeleteFolders(ctx, foldersID)
 }
 
-func (f *FoldersService) Get(folderID primitive.ObjectID) (folder_dto.FolderContentDto, error) {
-	if err := f.checkFolderExistenceByID(folderID); err != nil {
+func (f *FoldersService) Get(ctx context.Context, userID primitive.ObjectID, folderID primitive.ObjectID) (_ folder_dto.FolderContentDto, err error) {
+	ctx, span := tracing.Start(ctx, "FoldersService.Get")
+	defer tracing.End(span, &err)
+
+	ownerID, err := f.sharingService.Require(ctx, userID, folderID, domain.FolderViewer)
+	if err != nil {
 		return folder_dto.FolderContentDto{}, err
 	}
 
-	folders, err := f.repo.GetNestedFolders(context.Background(), folderID)
+	folders, err := f.repo.GetNestedFolders(ctx, ownerID, folderID)
 	if err != nil {
 		return folder_dto.FolderContentDto{}, fmt.Errorf("%w (folder id: %s): %s", domain.ErrGettingNestedFolders, folderID, err)
 	}
 
-	videos, err := f.videosService.GetVideos(folderID)
+	videos, err := f.videosService.GetVideos(ctx, ownerID, folderID)
 	if err != nil {
 		return folder_dto.FolderContentDto{}, err
 	}
@@ -168,22 +199,9 @@ func (f *FoldersService) Get(folderID primitive.ObjectID) (folder_dto.FolderCont
 	}, nil
 }
 
-func (f *FoldersService) checkFolderExistenceByID(folderID primitive.ObjectID) error {
-	err := f.repo.CheckExistenceByID(context.Background(), folderID)
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return fmt.Errorf("%w (folderID: %s)", domain.ErrFolderNotFound, folderID)
-		}
-
-		return fmt.Errorf("%w (folderID: %s): %s", domain.ErrCheckingFolder, folderID, err)
-	}
-
-	return nil
-}
-
-func (f *FoldersService) checkFolderExistenceByName(folderName string, parentDirID primitive.ObjectID) error {
-	err := f.repo.CheckExistenceByName(context.Background(), folderName, parentDirID)
-	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+func (f *FoldersService) checkFolderExistenceByName(ctx context.Context, ownerID primitive.ObjectID, folderName string, parentDirID primitive.ObjectID) error {
+	err := f.repo.CheckExistenceByName(ctx, ownerID, folderName, parentDirID)
+	if err != nil && !errors.Is(err, domain.ErrNoDocuments) {
 		return fmt.Errorf("%w (folder name: %s, parent dir id: %s): %s", domain.ErrCheckingFolder, folderName, parentDirID, err)
 	}
 
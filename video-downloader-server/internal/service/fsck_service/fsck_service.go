@@ -0,0 +1,205 @@
+package fsck_service
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"os"
+	"path/filepath"
+	"video-downloader-server/internal/delivery/dto/fsck_dto"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/service/common"
+)
+
+type VideosRepo interface {
+	GetAll(ctx context.Context) ([]domain.Video, error)
+	Delete(ctx context.Context, videoID primitive.ObjectID) error
+}
+
+type FoldersRepo interface {
+	GetAll(ctx context.Context) ([]domain.Folder, error)
+	UnsetParent(ctx context.Context, folderID primitive.ObjectID) error
+}
+
+type FsckService struct {
+	videosRepo  VideosRepo
+	foldersRepo FoldersRepo
+	videoDir    string
+	previewDir  string
+}
+
+func NewFsckService(videosRepo VideosRepo, foldersRepo FoldersRepo, videoDir, previewDir string) *FsckService {
+	return &FsckService{
+		videosRepo:  videosRepo,
+		foldersRepo: foldersRepo,
+		videoDir:    videoDir,
+		previewDir:  previewDir,
+	}
+}
+
+// Check cross-checks the videos and folders collections against the video and
+// preview directories. With repair set, dangling rows and videos in missing
+// folders are deleted, orphaned files are removed and folders with a missing
+// parent are moved to the root.
+func (f *FsckService) Check(ctx context.Context, repair bool) (fsck_dto.FsckReportDto, error) {
+	videos, err := f.videosRepo.GetAll(ctx)
+	if err != nil {
+		return fsck_dto.FsckReportDto{}, fmt.Errorf("%w: %s", domain.ErrGettingAllVideos, err)
+	}
+
+	folders, err := f.foldersRepo.GetAll(ctx)
+	if err != nil {
+		return fsck_dto.FsckReportDto{}, fmt.Errorf("%w: %s", domain.ErrGettingAllFolders, err)
+	}
+
+	videoFiles, err := common.ListFiles(f.videoDir, domain.FsckMinFileAge)
+	if err != nil {
+		return fsck_dto.FsckReportDto{}, err
+	}
+
+	previewFiles, err := common.ListFiles(f.previewDir, domain.FsckMinFileAge)
+	if err != nil {
+		return fsck_dto.FsckReportDto{}, err
+	}
+
+	folderIDs := make(map[primitive.ObjectID]struct{}, len(folders))
+	for _, folder := range folders {
+		folderIDs[folder.ID] = struct{}{}
+	}
+
+	var report fsck_dto.FsckReportDto
+	var brokenVideos []domain.Video
+	referencedVideos := make(map[string]struct{}, len(videos))
+	referencedPreviews := make(map[string]struct{}, len(videos))
+
+	for _, video := range videos {
+		referencedVideos[filepath.Clean(video.RealPath)] = struct{}{}
+		referencedPreviews[filepath.Clean(video.PreviewPath)] = struct{}{}
+
+		for _, subtitle := range video.Subtitles {
+			referencedVideos[filepath.Clean(domain.SubtitlePath(video.RealPath, subtitle.Lang))] = struct{}{}
+		}
+
+		for _, storyboardPath := range domain.StoryboardPaths(video.PreviewPath) {
+			referencedPreviews[filepath.Clean(storyboardPath)] = struct{}{}
+		}
+
+		if _, ok := folderIDs[video.FolderID]; !ok {
+			report.VideosInMissingFolders = append(report.VideosInMissingFolders, video.ID)
+			brokenVideos = append(brokenVideos, video)
+			continue
+		}
+
+		if !fileExists(filepath.Join(f.videoDir, video.RealPath)) {
+			report.DanglingVideos = append(report.DanglingVideos, video.ID)
+			brokenVideos = append(brokenVideos, video)
+			continue
+		}
+
+		if !fileExists(filepath.Join(f.previewDir, video.PreviewPath)) {
+			report.MissingPreviews = append(report.MissingPreviews, video.ID)
+		}
+	}
+
+	for _, folder := range folders {
+		if folder.ParentDirID == primitive.NilObjectID {
+			continue
+		}
+
+		if _, ok := folderIDs[folder.ParentDirID]; !ok {
+			report.FoldersWithMissingParents = append(report.FoldersWithMissingParents, folder.ID)
+		}
+	}
+
+	report.OrphanedVideoFiles = orphans(videoFiles, referencedVideos)
+	report.OrphanedPreviewFiles = orphans(previewFiles, referencedPreviews)
+
+	if !repair {
+		return report, nil
+	}
+
+	if err := f.repair(ctx, report, brokenVideos); err != nil {
+		return report, err
+	}
+	report.Repaired = true
+
+	return report, nil
+}
+
+func (f *FsckService) repair(ctx context.Context, report fsck_dto.FsckReportDto, brokenVideos []domain.Video) error {
+	for _, video := range brokenVideos {
+		if err := f.videosRepo.Delete(ctx, video.ID); err != nil {
+			return fmt.Errorf("%w (video id: %s): %s", domain.ErrRepairingVideo, video.ID, err)
+		}
+
+		if err := removeIfExists(f.videoDir, video.RealPath); err != nil {
+			return err
+		}
+
+		if err := removeIfExists(f.previewDir, video.PreviewPath); err != nil {
+			return err
+		}
+
+		for _, subtitle := range video.Subtitles {
+			if err := removeIfExists(f.videoDir, domain.SubtitlePath(video.RealPath, subtitle.Lang)); err != nil {
+				return err
+			}
+		}
+
+		for _, storyboardPath := range domain.StoryboardPaths(video.PreviewPath) {
+			if err := removeIfExists(f.previewDir, storyboardPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, folderID := range report.FoldersWithMissingParents {
+		if err := f.foldersRepo.UnsetParent(ctx, folderID); err != nil {
+			return fmt.Errorf("%w (folder id: %s): %s", domain.ErrRepairingFolder, folderID, err)
+		}
+	}
+
+	for _, path := range report.OrphanedVideoFiles {
+		if err := removeIfExists(f.videoDir, path); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range report.OrphanedPreviewFiles {
+		if err := removeIfExists(f.previewDir, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func orphans(files []string, referenced map[string]struct{}) []string {
+	var res []string
+
+	for _, file := range files {
+		if _, ok := referenced[file]; !ok {
+			res = append(res, file)
+		}
+	}
+
+	return res
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+func removeIfExists(root, relPath string) error {
+	if relPath == "" {
+		return nil
+	}
+
+	path := filepath.Join(root, relPath)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("%w (path: %s): %s", domain.ErrDeletingOrphanFile, path, err)
+	}
+
+	return nil
+}
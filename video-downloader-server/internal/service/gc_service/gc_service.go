@@ -0,0 +1,282 @@
+package gc_service
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"video-downloader-server/internal/delivery/dto/gc_dto"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/logger"
+	"video-downloader-server/internal/service/common"
+)
+
+const (
+	gcRunFinished = "orphaned files garbage collection finished"
+	gcRunFailed   = "orphaned files garbage collection failed"
+)
+
+type VideosRepo interface {
+	GetAll(ctx context.Context) ([]domain.Video, error)
+}
+
+type GcService struct {
+	repo         VideosRepo
+	videoDir     string
+	previewDir   string
+	hlsDir       string
+	interval     time.Duration
+	orphanMinAge time.Duration
+	tmpMinAge    time.Duration
+	dryRun       bool
+
+	mu      sync.Mutex
+	metrics gc_dto.GcMetricsDto
+}
+
+func NewGcService(repo VideosRepo, videoDir, previewDir, hlsDir string, interval, orphanMinAge, tmpMinAge time.Duration, dryRun bool) *GcService {
+	return &GcService{
+		repo:         repo,
+		videoDir:     videoDir,
+		previewDir:   previewDir,
+		hlsDir:       hlsDir,
+		interval:     interval,
+		orphanMinAge: orphanMinAge,
+		tmpMinAge:    tmpMinAge,
+		dryRun:       dryRun,
+	}
+}
+
+// Start runs the collector every interval until ctx is done. A zero interval disables it.
+func (g *GcService) Start(ctx context.Context) {
+	if g.interval == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(g.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report, err := g.Collect(ctx, g.dryRun)
+				if err != nil {
+					logger.FromContext(ctx).WithError(err).Error(gcRunFailed)
+					continue
+				}
+
+				logger.FromContext(ctx).WithFields(log.Fields{
+					"orphaned_videos":   len(report.OrphanedVideoFiles),
+					"orphaned_previews": len(report.OrphanedPreviewFiles),
+					"orphaned_hls":      len(report.OrphanedHlsFiles),
+					"stale_tmp_files":   len(report.StaleTmpFiles),
+					"bytes":             report.ReclaimableBytes,
+					"dry_run":           report.DryRun,
+				}).Info(gcRunFinished)
+			}
+		}
+	}()
+}
+
+// Collect finds files under the video, preview and HLS directories that are
+// not referenced by any video and are older than the orphan threshold, plus
+// leftover download tmp files and unfinished HLS ladders older than the tmp
+// threshold, and deletes them unless dryRun is set.
+func (g *GcService) Collect(ctx context.Context, dryRun bool) (gc_dto.GcReportDto, error) {
+	report, err := g.collect(ctx, dryRun)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.metrics.Runs++
+	g.metrics.LastRunAt = time.Now()
+	g.metrics.LastError = ""
+
+	if err != nil {
+		g.metrics.FailedRuns++
+		g.metrics.LastError = err.Error()
+		return report, err
+	}
+
+	if !dryRun {
+		g.metrics.FilesDeleted += int64(len(report.OrphanedVideoFiles) + len(report.OrphanedPreviewFiles) + len(report.OrphanedHlsFiles) + len(report.StaleTmpFiles))
+		g.metrics.BytesReclaimed += report.ReclaimableBytes
+	}
+
+	return report, nil
+}
+
+func (g *GcService) Metrics() gc_dto.GcMetricsDto {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.metrics
+}
+
+func (g *GcService) collect(ctx context.Context, dryRun bool) (gc_dto.GcReportDto, error) {
+	report := gc_dto.GcReportDto{DryRun: dryRun}
+
+	videos, err := g.repo.GetAll(ctx)
+	if err != nil {
+		return report, fmt.Errorf("%w: %s", domain.ErrGettingAllVideos, err)
+	}
+
+	referencedVideos := make(map[string]struct{}, len(videos))
+	referencedPreviews := make(map[string]struct{}, len(videos))
+
+	for _, video := range videos {
+		referencedVideos[filepath.Clean(video.RealPath)] = struct{}{}
+		referencedPreviews[filepath.Clean(video.PreviewPath)] = struct{}{}
+
+		for _, subtitle := range video.Subtitles {
+			referencedVideos[filepath.Clean(domain.SubtitlePath(video.RealPath, subtitle.Lang))] = struct{}{}
+		}
+
+		for _, storyboardPath := range domain.StoryboardPaths(video.PreviewPath) {
+			referencedPreviews[filepath.Clean(storyboardPath)] = struct{}{}
+		}
+	}
+
+	videoFiles, err := common.ListFiles(g.videoDir, min(g.orphanMinAge, g.tmpMinAge))
+	if err != nil {
+		return report, err
+	}
+
+	previewFiles, err := common.ListFiles(g.previewDir, g.orphanMinAge)
+	if err != nil {
+		return report, err
+	}
+
+	hlsFiles, err := common.ListFiles(g.hlsDir, min(g.orphanMinAge, g.tmpMinAge))
+	if err != nil {
+		return report, err
+	}
+
+	orphanThreshold := time.Now().Add(-g.orphanMinAge)
+	tmpThreshold := time.Now().Add(-g.tmpMinAge)
+
+	for _, file := range videoFiles {
+		if _, ok := referencedVideos[file]; ok {
+			continue
+		}
+
+		info, err := os.Stat(filepath.Join(g.videoDir, file))
+		if err != nil {
+			continue
+		}
+
+		if isTmpFile(file) {
+			if info.ModTime().After(tmpThreshold) {
+				continue
+			}
+			report.StaleTmpFiles = append(report.StaleTmpFiles, file)
+		} else {
+			if info.ModTime().After(orphanThreshold) {
+				continue
+			}
+			report.OrphanedVideoFiles = append(report.OrphanedVideoFiles, file)
+		}
+
+		report.ReclaimableBytes += info.Size()
+	}
+
+	for _, file := range previewFiles {
+		if _, ok := referencedPreviews[file]; ok {
+			continue
+		}
+
+		info, err := os.Stat(filepath.Join(g.previewDir, file))
+		if err != nil {
+			continue
+		}
+
+		report.OrphanedPreviewFiles = append(report.OrphanedPreviewFiles, file)
+		report.ReclaimableBytes += info.Size()
+	}
+
+	for _, file := range hlsFiles {
+		ladder, tmp := hlsLadder(file)
+		if _, ok := referencedVideos[ladder]; ok && !tmp {
+			continue
+		}
+
+		info, err := os.Stat(filepath.Join(g.hlsDir, file))
+		if err != nil {
+			continue
+		}
+
+		threshold := orphanThreshold
+		if tmp {
+			threshold = tmpThreshold
+		}
+
+		if info.ModTime().After(threshold) {
+			continue
+		}
+
+		report.OrphanedHlsFiles = append(report.OrphanedHlsFiles, file)
+		report.ReclaimableBytes += info.Size()
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	for _, file := range append(report.OrphanedVideoFiles, report.StaleTmpFiles...) {
+		if err := removeFile(filepath.Join(g.videoDir, file)); err != nil {
+			return report, err
+		}
+	}
+
+	for _, file := range report.OrphanedPreviewFiles {
+		if err := removeFile(filepath.Join(g.previewDir, file)); err != nil {
+			return report, err
+		}
+	}
+
+	for _, file := range report.OrphanedHlsFiles {
+		if err := removeFile(filepath.Join(g.hlsDir, file)); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// isTmpFile reports whether file is one of the separate video and audio
+// streams the YouTube strategy stores in the root of the video directory before merging.
+func isTmpFile(file string) bool {
+	if filepath.Dir(file) != "." {
+		return false
+	}
+
+	return strings.Contains(file, domain.TmpVideoMarker) || strings.Contains(file, domain.TmpAudioMarker)
+}
+
+// hlsLadder returns the directory of the HLS ladder file belongs to, which is
+// the real path of its video, and whether the ladder is still being
+// generated. The master playlist is at the top of a ladder and the other
+// files one level down.
+func hlsLadder(file string) (string, bool) {
+	ladder := filepath.Dir(file)
+	if filepath.Base(file) != domain.HlsMasterPlaylist {
+		ladder = filepath.Dir(ladder)
+	}
+
+	return ladder, strings.Contains(filepath.Base(ladder), domain.HlsTmpMarker)
+}
+
+func removeFile(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("%w (path: %s): %s", domain.ErrCollectingGarbage, path, err)
+	}
+
+	return nil
+}
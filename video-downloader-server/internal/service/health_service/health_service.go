@@ -0,0 +1,163 @@
+package health_service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+	"video-downloader-server/internal/delivery/dto/health_dto"
+	"video-downloader-server/internal/delivery/dto/job_dto"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/service/common"
+	"video-downloader-server/internal/version"
+)
+
+type Storage interface {
+	Ping(ctx context.Context) error
+}
+
+type Jobs interface {
+	Stats() job_dto.JobsStatsDto
+}
+
+type HealthService struct {
+	storage       Storage
+	jobsService   Jobs
+	transcodeJobs Jobs
+	roots         []string
+	minFreeBytes  uint64
+	executables   []string
+	checkTimeout  time.Duration
+	config        map[string]interface{}
+	startedAt     time.Time
+}
+
+// NewHealthService checks that the storage answers, that every root is
+// writable with at least minFreeBytes available and that every executable
+// runs. config is reported as is by Info, so secrets must be redacted.
+func NewHealthService(storage Storage, jobsService Jobs, transcodeJobs Jobs, roots []string, minFreeBytes uint64, executables []string, checkTimeout time.Duration, config map[string]interface{}) *HealthService {
+	return &HealthService{
+		storage:       storage,
+		jobsService:   jobsService,
+		transcodeJobs: transcodeJobs,
+		roots:         roots,
+		minFreeBytes:  minFreeBytes,
+		executables:   executables,
+		checkTimeout:  checkTimeout,
+		config:        config,
+		startedAt:     time.Now(),
+	}
+}
+
+// Ready runs every readiness check, each one bounded by the check timeout.
+func (h *HealthService) Ready(ctx context.Context) health_dto.ReadinessDto {
+	ctx, cancel := context.WithTimeout(ctx, h.checkTimeout)
+	defer cancel()
+
+	var checks []health_dto.CheckDto
+
+	check := health_dto.CheckDto{Name: "storage"}
+	if err := h.storage.Ping(ctx); err != nil {
+		check.Error = fmt.Errorf("%w: %s", domain.ErrPingingStorage, err).Error()
+	}
+	checks = append(checks, check)
+
+	for _, root := range h.roots {
+		checks = append(checks, h.checkRoot(root))
+	}
+
+	for _, executable := range h.executables {
+		checks = append(checks, h.checkExecutable(ctx, executable))
+	}
+
+	res := health_dto.ReadinessDto{Ready: true, Checks: make([]health_dto.CheckDto, 0, len(checks))}
+	for _, check := range checks {
+		check.Ok = check.Error == ""
+		res.Ready = res.Ready && check.Ok
+		res.Checks = append(res.Checks, check)
+	}
+
+	return res
+}
+
+func (h *HealthService) Info() health_dto.InfoDto {
+	stats := h.jobsService.Stats()
+
+	var utilization float64
+	if stats.Workers > 0 {
+		utilization = float64(stats.Busy) / float64(stats.Workers)
+	}
+
+	return health_dto.InfoDto{
+		Version:           version.Version,
+		Commit:            version.GetCommit(),
+		BuildTime:         version.BuildTime,
+		GoVersion:         runtime.Version(),
+		StartedAt:         h.startedAt,
+		Uptime:            time.Since(h.startedAt).Round(time.Second).String(),
+		Config:            h.config,
+		Jobs:              stats,
+		TranscodeJobs:     h.transcodeJobs.Stats(),
+		WorkerUtilization: utilization,
+	}
+}
+
+func (h *HealthService) checkRoot(root string) health_dto.CheckDto {
+	check := health_dto.CheckDto{Name: root}
+
+	if err := os.MkdirAll(root, os.ModePerm); err != nil {
+		check.Error = fmt.Errorf("%w (dir path: %s): %s", domain.ErrDirNotWritable, root, err).Error()
+		return check
+	}
+
+	file, err := os.CreateTemp(root, ".readyz-*")
+	if err != nil {
+		check.Error = fmt.Errorf("%w (dir path: %s): %s", domain.ErrDirNotWritable, root, err).Error()
+		return check
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	freeBytes, err := common.FreeSpace(root)
+	if err != nil {
+		if !errors.Is(err, errors.ErrUnsupported) {
+			check.Error = err.Error()
+		}
+		return check
+	}
+
+	check.FreeBytes = freeBytes
+	if freeBytes < h.minFreeBytes {
+		check.Error = fmt.Errorf("%w (dir path: %s, free: %d, required: %d)", domain.ErrNotEnoughSpace, root, freeBytes, h.minFreeBytes).Error()
+	}
+
+	return check
+}
+
+func (h *HealthService) checkExecutable(ctx context.Context, executable string) health_dto.CheckDto {
+	check := health_dto.CheckDto{Name: executable}
+
+	path, err := exec.LookPath(executable)
+	if err != nil {
+		check.Error = fmt.Errorf("%w (executable: %s): %s", domain.ErrFindingExecutable, executable, err).Error()
+		return check
+	}
+
+	output, err := common.Command(ctx, path, "-version").Output()
+	if err != nil {
+		check.Error = fmt.Errorf("%w (executable: %s): %s", domain.ErrGettingExecutableVersion, executable, err).Error()
+		return check
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	if scanner.Scan() {
+		check.Version = scanner.Text()
+	}
+
+	return check
+}
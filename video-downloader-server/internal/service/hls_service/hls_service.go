@@ -0,0 +1,321 @@
+package hls_service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"video-downloader-server/internal/delivery/dto/job_dto"
+	"video-downloader-server/internal/delivery/dto/video_dto"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/metrics"
+	"video-downloader-server/internal/service/common"
+	"video-downloader-server/internal/tracing"
+)
+
+type Videos interface {
+	Authorize(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, role string) (domain.Video, error)
+}
+
+type Jobs interface {
+	Submit(ctx context.Context, ownerID primitive.ObjectID, jobType, source string, run domain.JobFunc) (job_dto.JobDto, error)
+}
+
+// HlsService generates an HLS rendition ladder per video with ffmpeg and
+// serves its playlists and segments. The ladder of a video is cached under
+// the HLS directory at the real path of the video, which never changes, and
+// is removed together with the video files.
+type HlsService struct {
+	videosService   Videos
+	jobsService     Jobs
+	videoDir        string
+	hlsDir          string
+	ffmpegPath      string
+	ffprobePath     string
+	renditions      []domain.HlsRendition
+	segmentDuration time.Duration
+
+	mu      sync.Mutex
+	pending map[string]job_dto.JobDto
+}
+
+// NewHlsService creates the service for the ladder made of renditions, sorted
+// from the highest to the lowest.
+func NewHlsService(videosService Videos, jobsService Jobs, videoDir, hlsDir, ffmpegPath, ffprobePath string, renditions []domain.HlsRendition, segmentDuration time.Duration) *HlsService {
+	return &HlsService{
+		videosService:   videosService,
+		jobsService:     jobsService,
+		videoDir:        videoDir,
+		hlsDir:          hlsDir,
+		ffmpegPath:      ffmpegPath,
+		ffprobePath:     ffprobePath,
+		renditions:      renditions,
+		segmentDuration: segmentDuration,
+		pending:         make(map[string]job_dto.JobDto),
+	}
+}
+
+// Generate queues a job generating the ladder of the video unless it is
+// ready or already being generated, in which case the pending job is
+// returned. The job belongs to userID.
+func (s *HlsService) Generate(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID) (_ video_dto.HlsStatusDto, err error) {
+	ctx, span := tracing.Start(ctx, "HlsService.Generate")
+	defer tracing.End(span, &err)
+
+	video, err := s.videosService.Authorize(ctx, userID, videoID, domain.FolderViewer)
+	if err != nil {
+		return video_dto.HlsStatusDto{}, err
+	}
+
+	if s.ready(video) {
+		return video_dto.HlsStatusDto{Ready: true}, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.pending[video.RealPath]; ok {
+		return video_dto.HlsStatusDto{Job: &job}, nil
+	}
+
+	job, err := s.jobsService.Submit(ctx, userID, domain.JobHls, video.VideoName, func(ctx context.Context) (primitive.ObjectID, error) {
+		defer func() {
+			s.mu.Lock()
+			delete(s.pending, video.RealPath)
+			s.mu.Unlock()
+		}()
+
+		return video.ID, s.generate(ctx, video)
+	})
+	if err != nil {
+		return video_dto.HlsStatusDto{}, err
+	}
+	s.pending[video.RealPath] = job
+
+	return video_dto.HlsStatusDto{Job: &job}, nil
+}
+
+// GetFile opens a playlist or segment of the ladder of the video, name is
+// relative to the ladder, e.g. master.m3u8 or 720p/segment_0001.ts. It fails
+// with ErrHlsNotGenerated when the ladder is not ready.
+func (s *HlsService) GetFile(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, name string) (_ video_dto.VideoFileInfoDto, err error) {
+	ctx, span := tracing.Start(ctx, "HlsService.GetFile")
+	defer tracing.End(span, &err)
+
+	video, err := s.videosService.Authorize(ctx, userID, videoID, domain.FolderViewer)
+	if err != nil {
+		return video_dto.VideoFileInfoDto{}, err
+	}
+
+	if !domain.ValidHlsFile(name) {
+		return video_dto.VideoFileInfoDto{}, fmt.Errorf("%w (file: %s)", domain.ErrHlsFileNotFound, name)
+	}
+
+	if !s.ready(video) {
+		return video_dto.VideoFileInfoDto{}, fmt.Errorf("%w (video id: %s)", domain.ErrHlsNotGenerated, videoID.Hex())
+	}
+
+	filePath := filepath.Join(s.hlsDir, video.RealPath, filepath.FromSlash(name))
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return video_dto.VideoFileInfoDto{}, fmt.Errorf("%w (file: %s): %s", domain.ErrHlsFileNotFound, name, err)
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return video_dto.VideoFileInfoDto{}, fmt.Errorf("%w (path: %s): %s", domain.ErrGettingFileInfo, filePath, err)
+	}
+
+	return video_dto.VideoFileInfoDto{
+		VideoName:   filepath.Base(name),
+		FileSize:    fileInfo.Size(),
+		ModTime:     fileInfo.ModTime(),
+		ContentType: domain.HlsContentType(name),
+		VideoFile:   file,
+	}, nil
+}
+
+// ready reports whether the ladder of the video has been generated. The
+// master playlist is renamed into place together with the whole ladder.
+func (s *HlsService) ready(video domain.Video) bool {
+	_, err := os.Stat(filepath.Join(s.hlsDir, video.RealPath, domain.HlsMasterPlaylist))
+	return err == nil
+}
+
+// generate encodes the ladder into a tmp directory and renames it into place
+// once ffmpeg has finished, so that a ladder is either complete or missing.
+func (s *HlsService) generate(ctx context.Context, video domain.Video) (err error) {
+	ctx, span := tracing.Start(ctx, "HlsService.generate")
+	defer tracing.End(span, &err)
+
+	videoPath := filepath.Join(s.videoDir, video.RealPath)
+	ladderPath := filepath.Join(s.hlsDir, video.RealPath)
+
+	height, hasAudio, err := s.probe(ctx, videoPath)
+	if err != nil {
+		return err
+	}
+
+	renditions := s.ladder(height)
+
+	tmpPath := ladderPath + domain.HlsTmpMarker + primitive.NewObjectID().Hex()
+	for _, rendition := range renditions {
+		if err := os.MkdirAll(filepath.Join(tmpPath, rendition.Name), os.ModePerm); err != nil {
+			os.RemoveAll(tmpPath)
+			return fmt.Errorf("%w (dir path: %s): %s", domain.ErrCreatingDir, tmpPath, err)
+		}
+	}
+
+	if err := s.encode(ctx, videoPath, tmpPath, renditions, hasAudio); err != nil {
+		os.RemoveAll(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, ladderPath); err != nil {
+		os.RemoveAll(tmpPath)
+
+		// another job finished the same ladder first
+		if s.ready(video) {
+			return nil
+		}
+
+		return fmt.Errorf("%w (video path: %s): %s", domain.ErrGeneratingHls, video.RealPath, err)
+	}
+
+	return nil
+}
+
+// ladder returns the configured renditions not taller than the source, or
+// the lowest one when the source is smaller than all of them.
+func (s *HlsService) ladder(height int) []domain.HlsRendition {
+	var res []domain.HlsRendition
+
+	for _, rendition := range s.renditions {
+		if rendition.Height <= height {
+			res = append(res, rendition)
+		}
+	}
+
+	if len(res) == 0 {
+		res = s.renditions[len(s.renditions)-1:]
+	}
+
+	return res
+}
+
+type probeOutput struct {
+	Streams []struct {
+		CodecType string `json:"codec_type"`
+		Height    int    `json:"height"`
+	} `json:"streams"`
+}
+
+// probe returns the height of the first video stream and whether the file
+// has an audio stream.
+func (s *HlsService) probe(ctx context.Context, videoPath string) (_ int, _ bool, err error) {
+	ctx, span := tracing.StartProcess(ctx, metrics.FfmpegProbe, s.ffprobePath)
+	defer tracing.End(span, &err)
+
+	cmd := common.Command(ctx, s.ffprobePath, "-v", "error", "-show_entries", "stream=codec_type,height", "-of", "json", videoPath)
+	start := time.Now()
+	output, err := cmd.Output()
+	metrics.ObserveFfmpeg(metrics.FfmpegProbe, start, err)
+	if err != nil {
+		return 0, false, fmt.Errorf("%w (video path: %s): %s", domain.ErrProbingVideo, videoPath, err)
+	}
+
+	var probed probeOutput
+	if err := json.Unmarshal(output, &probed); err != nil {
+		return 0, false, fmt.Errorf("%w (video path: %s): %s", domain.ErrProbingVideo, videoPath, err)
+	}
+
+	height, hasAudio := 0, false
+	for _, stream := range probed.Streams {
+		switch stream.CodecType {
+		case "video":
+			if height == 0 {
+				height = stream.Height
+			}
+		case "audio":
+			hasAudio = true
+		}
+	}
+
+	if height == 0 {
+		return 0, false, fmt.Errorf("%w (video path: %s)", domain.ErrNoVideoStream, videoPath)
+	}
+
+	return height, hasAudio, nil
+}
+
+// encode runs a single ffmpeg that scales the source once per rendition and
+// muxes every rendition into its own variant playlist.
+func (s *HlsService) encode(ctx context.Context, videoPath, outPath string, renditions []domain.HlsRendition, hasAudio bool) (err error) {
+	ctx, span := tracing.StartProcess(ctx, metrics.FfmpegHls, s.ffmpegPath)
+	defer tracing.End(span, &err)
+
+	segmentSeconds := strconv.FormatFloat(s.segmentDuration.Seconds(), 'f', -1, 64)
+
+	filters := make([]string, 0, len(renditions)+1)
+	splits := make([]string, len(renditions))
+	streamMap := make([]string, len(renditions))
+	for i := range renditions {
+		splits[i] = fmt.Sprintf("[s%d]", i)
+	}
+	filters = append(filters, fmt.Sprintf("[0:v:0]split=%d%s", len(renditions), strings.Join(splits, "")))
+
+	args := []string{"-v", "error", "-y", "-i", videoPath}
+	for i, rendition := range renditions {
+		filters = append(filters, fmt.Sprintf("[s%d]scale=-2:%d[v%d]", i, rendition.Height, i))
+	}
+	args = append(args, "-filter_complex", strings.Join(filters, ";"))
+
+	for i, rendition := range renditions {
+		args = append(args,
+			"-map", fmt.Sprintf("[v%d]", i),
+			fmt.Sprintf("-c:v:%d", i), "libx264",
+			fmt.Sprintf("-b:v:%d", i), fmt.Sprintf("%dk", rendition.VideoBitrate),
+		)
+		streamMap[i] = fmt.Sprintf("v:%d,name:%s", i, rendition.Name)
+
+		if hasAudio {
+			args = append(args,
+				"-map", "0:a:0",
+				fmt.Sprintf("-c:a:%d", i), "aac",
+				fmt.Sprintf("-b:a:%d", i), fmt.Sprintf("%dk", rendition.AudioBitrate),
+			)
+			streamMap[i] = fmt.Sprintf("v:%d,a:%d,name:%s", i, i, rendition.Name)
+		}
+	}
+
+	args = append(args,
+		"-preset", "veryfast",
+		"-force_key_frames", "expr:gte(t,n_forced*"+segmentSeconds+")",
+		"-f", "hls",
+		"-hls_time", segmentSeconds,
+		"-hls_playlist_type", "vod",
+		"-hls_flags", "independent_segments",
+		"-hls_segment_filename", filepath.Join(outPath, "%v", domain.HlsSegmentPattern),
+		"-master_pl_name", domain.HlsMasterPlaylist,
+		"-var_stream_map", strings.Join(streamMap, " "),
+		filepath.Join(outPath, "%v", domain.HlsVariantPlaylist),
+	)
+
+	cmd := common.Command(ctx, s.ffmpegPath, args...)
+	start := time.Now()
+	output, err := cmd.CombinedOutput()
+	metrics.ObserveFfmpeg(metrics.FfmpegHls, start, err)
+	if err != nil {
+		return fmt.Errorf("%w (ffmpeg output: %s): %s", domain.ErrGeneratingHls, string(output), err)
+	}
+
+	return nil
+}
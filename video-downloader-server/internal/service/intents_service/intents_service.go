@@ -0,0 +1,288 @@
+package intents_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"os"
+	"path/filepath"
+	"time"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/logger"
+)
+
+const (
+	intentRecovered = "unfinished intent has been replayed"
+	intentFailed    = "error replaying unfinished intent"
+)
+
+type IntentsRepo interface {
+	Create(ctx context.Context, intent domain.Intent) (primitive.ObjectID, error)
+	GetAll(ctx context.Context) ([]domain.Intent, error)
+	RecordFailure(ctx context.Context, intentID primitive.ObjectID, errMsg string) error
+	Delete(ctx context.Context, intentID primitive.ObjectID) error
+}
+
+type VideosRepo interface {
+	Create(ctx context.Context, video domain.Video) (primitive.ObjectID, error)
+	GetByID(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID) (domain.Video, error)
+	Delete(ctx context.Context, videoID primitive.ObjectID) error
+	DeleteVideos(ctx context.Context, foldersID []primitive.ObjectID) error
+}
+
+type FoldersRepo interface {
+	DeleteAllNestedFolders(ctx context.Context, foldersID []primitive.ObjectID) error
+}
+
+// IntentsService is a unit of work over the database and the storage
+// directories. Every change is first recorded as an intent, then database
+// documents are changed before files so that no document ever points to a
+// missing file, and the intent is removed only once every step has succeeded.
+type IntentsService struct {
+	repo        IntentsRepo
+	videosRepo  VideosRepo
+	foldersRepo FoldersRepo
+	videoDir    string
+	previewDir  string
+	hlsDir      string
+}
+
+func NewIntentsService(repo IntentsRepo, videosRepo VideosRepo, foldersRepo FoldersRepo, videoDir, previewDir, hlsDir string) *IntentsService {
+	return &IntentsService{
+		repo:        repo,
+		videosRepo:  videosRepo,
+		foldersRepo: foldersRepo,
+		videoDir:    videoDir,
+		previewDir:  previewDir,
+		hlsDir:      hlsDir,
+	}
+}
+
+// Delete removes the given videos, every video inside the given folders, the
+// folders themselves and the video, preview and HLS files.
+func (s *IntentsService) Delete(ctx context.Context, videoIDs []primitive.ObjectID, foldersID []primitive.ObjectID, videoPaths []string, previewPaths []string) error {
+	intent, err := s.record(ctx, domain.Intent{
+		Type:         domain.IntentDelete,
+		VideoIDs:     videoIDs,
+		FolderIDs:    foldersID,
+		VideoPaths:   videoPaths,
+		PreviewPaths: previewPaths,
+	})
+	if err != nil {
+		return err
+	}
+
+	var applyErr error
+	for attempt := 0; attempt < domain.IntentMaxAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(domain.IntentRetryBackoff << attempt)
+		}
+
+		if applyErr = s.applyDelete(ctx, intent); applyErr == nil {
+			return s.finish(ctx, intent)
+		}
+
+		s.repo.RecordFailure(ctx, intent.ID, applyErr.Error())
+	}
+
+	return applyErr
+}
+
+// CreateVideo saves a video whose files are already on disk. If the document
+// can't be saved, the files are removed.
+func (s *IntentsService) CreateVideo(ctx context.Context, video domain.Video) (primitive.ObjectID, error) {
+	video.ID = primitive.NewObjectID()
+
+	intent, err := s.record(ctx, domain.Intent{
+		Type:         domain.IntentCreateVideo,
+		Video:        &video,
+		VideoPaths:   []string{video.RealPath},
+		PreviewPaths: []string{video.PreviewPath},
+	})
+	if err != nil {
+		s.removeFiles(domain.Intent{VideoPaths: []string{video.RealPath}, PreviewPaths: []string{video.PreviewPath}})
+		return primitive.NilObjectID, err
+	}
+
+	if _, err := s.videosRepo.Create(ctx, video); err != nil {
+		if cerr := s.compensateCreate(ctx, intent); cerr != nil {
+			logger.FromContext(ctx).WithError(cerr).Error(domain.ErrCompensatingIntent)
+		}
+
+		return primitive.NilObjectID, err
+	}
+
+	if err := s.finish(ctx, intent); err != nil {
+		logger.FromContext(ctx).WithError(err).Error(domain.ErrApplyingIntent)
+	}
+
+	return video.ID, nil
+}
+
+// Recover replays every intent left behind by a previous run.
+func (s *IntentsService) Recover(ctx context.Context) error {
+	intents, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %s", domain.ErrGettingIntents, err)
+	}
+
+	for _, intent := range intents {
+		var err error
+
+		switch intent.Type {
+		case domain.IntentDelete:
+			err = s.applyDelete(ctx, intent)
+			if err == nil {
+				err = s.finish(ctx, intent)
+			}
+		case domain.IntentCreateVideo:
+			err = s.recoverCreate(ctx, intent)
+		default:
+			err = fmt.Errorf("%w (intent id: %s, type: %s)", domain.ErrUnknownIntent, intent.ID, intent.Type)
+		}
+
+		if err != nil {
+			s.repo.RecordFailure(ctx, intent.ID, err.Error())
+			logger.FromContext(ctx).WithError(err).WithField("intent_id", intent.ID.Hex()).Error(intentFailed)
+			continue
+		}
+
+		logger.FromContext(ctx).WithFields(log.Fields{"intent_id": intent.ID.Hex(), "type": intent.Type}).Info(intentRecovered)
+	}
+
+	return nil
+}
+
+func (s *IntentsService) record(ctx context.Context, intent domain.Intent) (domain.Intent, error) {
+	intent.CreatedAt = time.Now()
+
+	intentID, err := s.repo.Create(ctx, intent)
+	if err != nil {
+		return domain.Intent{}, fmt.Errorf("%w (type: %s): %s", domain.ErrRecordingIntent, intent.Type, err)
+	}
+	intent.ID = intentID
+
+	return intent, nil
+}
+
+func (s *IntentsService) finish(ctx context.Context, intent domain.Intent) error {
+	if err := s.repo.Delete(ctx, intent.ID); err != nil {
+		return fmt.Errorf("%w (intent id: %s): %s", domain.ErrApplyingIntent, intent.ID, err)
+	}
+
+	return nil
+}
+
+func (s *IntentsService) applyDelete(ctx context.Context, intent domain.Intent) error {
+	for _, videoID := range intent.VideoIDs {
+		if err := s.videosRepo.Delete(ctx, videoID); err != nil {
+			return fmt.Errorf("%w (video id: %s): %s", domain.ErrDeletingVideoFromDB, videoID, err)
+		}
+	}
+
+	if len(intent.FolderIDs) > 0 {
+		if err := s.videosRepo.DeleteVideos(ctx, intent.FolderIDs); err != nil {
+			return fmt.Errorf("%w (from folders: %s): %s", domain.ErrDeletingVideoFromDB, intent.FolderIDs, err)
+		}
+
+		if err := s.foldersRepo.DeleteAllNestedFolders(ctx, intent.FolderIDs); err != nil {
+			return fmt.Errorf("%w (folders id: %s): %s", domain.ErrDeletingAllNestedFolders, intent.FolderIDs, err)
+		}
+	}
+
+	return s.removeFiles(intent)
+}
+
+func (s *IntentsService) recoverCreate(ctx context.Context, intent domain.Intent) error {
+	if intent.Video == nil {
+		return s.finish(ctx, intent)
+	}
+
+	_, err := s.videosRepo.GetByID(ctx, intent.Video.OwnerID, intent.Video.ID)
+	if err == nil {
+		return s.finish(ctx, intent)
+	}
+
+	if !errors.Is(err, domain.ErrNoDocuments) {
+		return fmt.Errorf("%w (video id: %s): %s", domain.ErrCheckingVideo, intent.Video.ID, err)
+	}
+
+	return s.compensateCreate(ctx, intent)
+}
+
+func (s *IntentsService) compensateCreate(ctx context.Context, intent domain.Intent) error {
+	if err := s.removeFiles(intent); err != nil {
+		return fmt.Errorf("%w (intent id: %s): %s", domain.ErrCompensatingIntent, intent.ID, err)
+	}
+
+	return s.finish(ctx, intent)
+}
+
+func (s *IntentsService) removeFiles(intent domain.Intent) error {
+	for _, videoPath := range nonEmpty(intent.VideoPaths...) {
+		if err := os.Remove(filepath.Join(s.videoDir, videoPath)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("%w (video path: %s): %s", domain.ErrDeletingVideo, videoPath, err)
+		}
+
+		if err := removeSubtitles(filepath.Join(s.videoDir, videoPath)); err != nil {
+			return fmt.Errorf("%w (video path: %s): %s", domain.ErrDeletingSubtitles, videoPath, err)
+		}
+
+		// the HLS ladder of a video lives at its real path
+		if err := os.RemoveAll(filepath.Join(s.hlsDir, videoPath)); err != nil {
+			return fmt.Errorf("%w (video path: %s): %s", domain.ErrDeletingHlsFiles, videoPath, err)
+		}
+	}
+
+	for _, previewPath := range nonEmpty(intent.PreviewPaths...) {
+		if err := os.Remove(filepath.Join(s.previewDir, previewPath)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("%w (preview path: %s): %s", domain.ErrDeletingPreview, previewPath, err)
+		}
+
+		for _, storyboardPath := range domain.StoryboardPaths(previewPath) {
+			if err := os.Remove(filepath.Join(s.previewDir, storyboardPath)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("%w (preview path: %s): %s", domain.ErrDeletingPreview, storyboardPath, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeSubtitles removes the subtitle tracks stored next to the video at
+// videoPath.
+func removeSubtitles(videoPath string) error {
+	entries, err := os.ReadDir(filepath.Dir(videoPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		if _, ok := domain.SubtitleLang(filepath.Base(videoPath), entry.Name()); !ok {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(filepath.Dir(videoPath), entry.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func nonEmpty(paths ...string) []string {
+	var res []string
+
+	for _, path := range paths {
+		if path != "" {
+			res = append(res, path)
+		}
+	}
+
+	return res
+}
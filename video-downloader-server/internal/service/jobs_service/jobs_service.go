@@ -0,0 +1,302 @@
+package jobs_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"sync"
+	"time"
+	"video-downloader-server/internal/delivery/dto/job_dto"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/logger"
+	"video-downloader-server/internal/tracing"
+)
+
+const (
+	jobFinished = "job finished"
+	jobFailed   = "job failed"
+	jobCanceled = "job canceled"
+)
+
+type queuedJob struct {
+	id   primitive.ObjectID
+	log  *log.Entry
+	span trace.SpanContext
+	run  domain.JobFunc
+}
+
+// JobsService runs jobs on a fixed number of workers fed by a bounded queue.
+type JobsService struct {
+	workers    int
+	maxPerUser int
+	retention  time.Duration
+	queue      chan queuedJob
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex
+	jobs   map[primitive.ObjectID]*domain.Job
+	busy   int
+	closed bool
+}
+
+// NewJobsService creates a pool of workers with room for queueSize waiting
+// jobs, of which a single owner may have maxPerUser queued or running, 0
+// means no per owner limit. Finished jobs are forgotten after retention.
+func NewJobsService(workers, queueSize, maxPerUser int, retention time.Duration) *JobsService {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &JobsService{
+		workers:    workers,
+		maxPerUser: maxPerUser,
+		retention:  retention,
+		queue:      make(chan queuedJob, queueSize),
+		ctx:        ctx,
+		cancel:     cancel,
+		jobs:       make(map[primitive.ObjectID]*domain.Job),
+	}
+}
+
+func (s *JobsService) Start() {
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+
+			for job := range s.queue {
+				s.run(job)
+			}
+		}()
+	}
+}
+
+// Submit queues run and returns at once. It fails with ErrJobQueueFull when
+// every worker is busy and the queue is full, with ErrTooManyJobs when
+// ownerID already has the maximum of active jobs, and with ErrShuttingDown once
+// Shutdown has been called. The job is only visible to ownerID. run gets
+// the logger of ctx with the job ID added and continues its trace, but not
+// ctx itself since the job outlives the request.
+func (s *JobsService) Submit(ctx context.Context, ownerID primitive.ObjectID, jobType, source string, run domain.JobFunc) (job_dto.JobDto, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return job_dto.JobDto{}, domain.ErrShuttingDown
+	}
+
+	s.prune()
+
+	if s.maxPerUser > 0 {
+		if active := s.active(ownerID); active >= s.maxPerUser {
+			return job_dto.JobDto{}, fmt.Errorf("%w (active jobs: %d)", domain.ErrTooManyJobs, active)
+		}
+	}
+
+	job := &domain.Job{
+		ID:        primitive.NewObjectID(),
+		OwnerID:   ownerID,
+		Type:      jobType,
+		Status:    domain.JobQueued,
+		Source:    source,
+		CreatedAt: time.Now(),
+	}
+
+	select {
+	case s.queue <- queuedJob{
+		id:   job.ID,
+		log:  logger.FromContext(ctx).WithField(logger.JobIDField, job.ID.Hex()),
+		span: trace.SpanContextFromContext(ctx),
+		run:  run,
+	}:
+	default:
+		return job_dto.JobDto{}, fmt.Errorf("%w (queue size: %d)", domain.ErrJobQueueFull, cap(s.queue))
+	}
+
+	s.jobs[job.ID] = job
+
+	return toJobDto(*job), nil
+}
+
+// Get returns the job only if it belongs to ownerID.
+func (s *JobsService) Get(ownerID primitive.ObjectID, jobID primitive.ObjectID) (job_dto.JobDto, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[jobID]
+	if !ok || job.OwnerID != ownerID {
+		return job_dto.JobDto{}, fmt.Errorf("%w (job id: %s)", domain.ErrJobNotFound, jobID.Hex())
+	}
+
+	return toJobDto(*job), nil
+}
+
+func (s *JobsService) Stats() job_dto.JobsStatsDto {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return job_dto.JobsStatsDto{
+		Workers:   s.workers,
+		Busy:      s.busy,
+		Queued:    len(s.queue),
+		QueueSize: cap(s.queue),
+	}
+}
+
+// Shutdown stops accepting jobs, cancels the queued ones and waits for the
+// running ones. When ctx is done first the running jobs are canceled, which
+// terminates their subprocesses and removes their partial files, and
+// Shutdown returns once they have exited.
+func (s *JobsService) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	defer s.cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (s *JobsService) run(queued queuedJob) {
+	s.mu.Lock()
+	job := s.jobs[queued.id]
+	if s.closed {
+		job.Status = domain.JobCanceled
+		job.Error = domain.ErrShuttingDown.Error()
+		job.FinishedAt = time.Now()
+		s.mu.Unlock()
+
+		queued.log.WithField("source", job.Source).Warn(jobCanceled)
+		return
+	}
+	job.Status = domain.JobRunning
+	job.StartedAt = time.Now()
+	s.busy++
+	s.mu.Unlock()
+
+	ctx := trace.ContextWithSpanContext(logger.WithContext(s.ctx, queued.log), queued.span)
+	ctx = domain.WithJobReporter(ctx, jobReporter{s: s, job: job})
+	ctx, span := tracing.Start(ctx, "job "+job.Type, trace.WithAttributes(attribute.String(logger.JobIDField, job.ID.Hex())))
+	videoID, err := queued.run(ctx)
+	tracing.End(span, &err)
+
+	s.mu.Lock()
+	s.busy--
+	job.VideoID = videoID
+	job.FinishedAt = time.Now()
+
+	switch {
+	case err == nil:
+		job.Status = domain.JobDone
+	case errors.Is(s.ctx.Err(), context.Canceled):
+		job.Status = domain.JobCanceled
+		job.Error = err.Error()
+	default:
+		job.Status = domain.JobFailed
+		job.Error = err.Error()
+	}
+	finished := *job
+	s.mu.Unlock()
+
+	entry := queued.log.WithFields(log.Fields{"type": finished.Type, "source": finished.Source})
+	switch finished.Status {
+	case domain.JobDone:
+		entry.Info(jobFinished)
+	case domain.JobCanceled:
+		entry.WithError(err).Warn(jobCanceled)
+	default:
+		entry.WithError(err).Error(jobFailed)
+	}
+}
+
+// jobReporter publishes the progress and the next job of a running job.
+type jobReporter struct {
+	s   *JobsService
+	job *domain.Job
+}
+
+func (r jobReporter) SetProgress(fraction float64) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.job.Progress = min(max(fraction, 0), 1)
+}
+
+func (r jobReporter) SetNextJob(jobID primitive.ObjectID) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.job.NextJobID = jobID
+}
+
+// prune forgets jobs that finished more than retention ago, s.mu must be held.
+func (s *JobsService) prune() {
+	for id, job := range s.jobs {
+		if !job.FinishedAt.IsZero() && time.Since(job.FinishedAt) > s.retention {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// active counts the queued and running jobs of ownerID, s.mu must be held.
+func (s *JobsService) active(ownerID primitive.ObjectID) int {
+	count := 0
+	for _, job := range s.jobs {
+		if job.OwnerID == ownerID && (job.Status == domain.JobQueued || job.Status == domain.JobRunning) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func toJobDto(job domain.Job) job_dto.JobDto {
+	res := job_dto.JobDto{
+		ID:        job.ID,
+		Type:      job.Type,
+		Status:    job.Status,
+		Source:    job.Source,
+		Error:     job.Error,
+		Progress:  job.Progress,
+		CreatedAt: job.CreatedAt,
+	}
+
+	if !job.NextJobID.IsZero() {
+		res.NextJobID = &job.NextJobID
+	}
+
+	if !job.VideoID.IsZero() {
+		res.VideoID = &job.VideoID
+	}
+
+	if !job.StartedAt.IsZero() {
+		res.StartedAt = &job.StartedAt
+	}
+
+	if !job.FinishedAt.IsZero() {
+		res.FinishedAt = &job.FinishedAt
+	}
+
+	return res
+}
@@ -1,62 +1,205 @@
 package preview_service
 
 import (
+	"context"
 	"fmt"
-	"math/rand"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	"math"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
+	"video-downloader-server/internal/delivery/dto/video_dto"
 	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/logger"
+	"video-downloader-server/internal/metrics"
 	"video-downloader-server/internal/service/common"
+	"video-downloader-server/internal/tracing"
+)
+
+const (
+	scoringCandidate = "error scoring preview candidate"
+
+	candidatePattern = "candidate_%d" + domain.PreviewFormat
+
+	// thumbnailFrames is the number of frames the thumbnail filter compares
+	// for a candidate, which keeps transitions and motion blur out of
+	// previews.
+	thumbnailFrames = 60
+
+	// darkLuma and blankDeviation are the mean and the deviation of the luma
+	// below which a frame is dark or blank, such as a fade to black or a
+	// title card.
+	darkLuma       = 32
+	blankDeviation = 12
+
+	scoreSamples = 64
 )
 
 type PreviewService struct {
+	videoDir        string
+	previewDir      string
+	ffmpegPath      string
+	ffprobePath     string
+	minTimeFraction float64
+	maxTimeFraction float64
+	candidates      int
 }
 
-func NewPreviewService() *PreviewService {
-	return &PreviewService{}
+func NewPreviewService(videoDir, previewDir, ffmpegPath, ffprobePath string, minTimeFraction, maxTimeFraction float64, candidates int) *PreviewService {
+	return &PreviewService{
+		videoDir:        videoDir,
+		previewDir:      previewDir,
+		ffmpegPath:      ffmpegPath,
+		ffprobePath:     ffprobePath,
+		minTimeFraction: minTimeFraction,
+		maxTimeFraction: maxTimeFraction,
+		candidates:      candidates,
+	}
 }
 
-func (p *PreviewService) CreatePreview(videoName string, realPath string) (string, error) {
-	previewDir, err := common.CreateRandomDir(domain.CommonPreviewDir)
+// CreatePreview samples candidate frames evenly across the preview window of
+// the video and keeps the most detailed one that is neither dark nor blank,
+// or the most detailed of all when every candidate is.
+func (p *PreviewService) CreatePreview(ctx context.Context, videoName string, realPath string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "PreviewService.CreatePreview")
+	defer tracing.End(span, &err)
+
+	previewDir, err := common.CreateRandomDir(p.previewDir)
 	if err != nil {
 		return "", err
 	}
 
-	videoPath := filepath.Join(domain.CommonVideoDir, realPath)
+	videoPath := filepath.Join(p.videoDir, realPath)
 	videoName = common.ReplaceSpecialSymbols(videoName)
-	previewPath := filepath.Join(domain.CommonPreviewDir, previewDir, videoName+domain.PreviewFormat)
+	previewPath := filepath.Join(p.previewDir, previewDir, videoName+domain.PreviewFormat)
 
-	videoDuration, err := p.getVideoDuration(videoPath)
+	videoDuration, err := p.getVideoDuration(ctx, videoPath)
 	if err != nil {
 		return "", err
 	}
 
-	previewTime := p.generateRandomTime(videoDuration)
+	var best *candidate
+	for i, previewTime := range p.candidateTimes(videoDuration) {
+		candidatePath := filepath.Join(p.previewDir, previewDir, fmt.Sprintf(candidatePattern, i))
+		if err = p.generatePreview(ctx, videoPath, candidatePath, previewTime); err != nil {
+			continue
+		}
 
-	if err := p.generatePreview(videoPath, previewPath, previewTime); err != nil {
+		score, serr := scoreFrame(candidatePath)
+		if serr != nil {
+			logger.FromContext(ctx).WithError(serr).Warn(scoringCandidate)
+		}
+
+		if best != nil && !score.better(best.score) {
+			os.Remove(candidatePath)
+			continue
+		}
+
+		if best != nil {
+			os.Remove(best.path)
+		}
+		best = &candidate{path: candidatePath, score: score}
+	}
+
+	if best == nil {
 		return "", err
 	}
 
+	if err := os.Rename(best.path, previewPath); err != nil {
+		os.Remove(best.path)
+		return "", fmt.Errorf("%w (preview path: %s): %s", domain.ErrGeneratingPreview, previewPath, err)
+	}
+
 	return filepath.Join(previewDir, videoName+domain.PreviewFormat), nil
 }
 
-func (p *PreviewService) DeletePreviews(paths []string) error {
-	for _, previewPath := range paths {
-		if err := os.Remove(filepath.Join(domain.CommonPreviewDir, previewPath)); err != nil {
-			return fmt.Errorf("%w (preview path: %s): %s", domain.ErrDeletingPreview, previewPath, err)
+// CopyPreview copies the preview together with the storyboard of the video,
+// if it has been generated.
+func (p *PreviewService) CopyPreview(previewPath string) (_ string, err error) {
+	previewDir, err := common.CreateRandomDir(p.previewDir)
+	if err != nil {
+		return "", err
+	}
+
+	// a partial copy is removed
+	var copied []string
+	defer func() {
+		if err != nil {
+			for _, path := range copied {
+				os.Remove(path)
+			}
 		}
+	}()
+
+	newPreviewPath := filepath.Join(previewDir, filepath.Base(previewPath))
+	copied = append(copied, filepath.Join(p.previewDir, newPreviewPath))
+	if err := common.CopyFile(filepath.Join(p.previewDir, previewPath), filepath.Join(p.previewDir, newPreviewPath)); err != nil {
+		return "", err
 	}
 
-	return nil
+	// the track is copied last as it marks the storyboard ready
+	for _, name := range []string{domain.StoryboardSprite, domain.StoryboardTrack} {
+		storyboardPath := filepath.Join(p.previewDir, domain.StoryboardPath(previewPath, name))
+		if _, err := os.Stat(storyboardPath); err != nil {
+			break
+		}
+
+		newStoryboardPath := filepath.Join(p.previewDir, domain.StoryboardPath(newPreviewPath, name))
+		copied = append(copied, newStoryboardPath)
+		if err := common.CopyFile(storyboardPath, newStoryboardPath); err != nil {
+			return "", err
+		}
+	}
+
+	return newPreviewPath, nil
+}
+
+// RemovePreview removes a preview, with its storyboard, that no video was
+// saved with.
+func (p *PreviewService) RemovePreview(previewPath string) {
+	os.Remove(filepath.Join(p.previewDir, previewPath))
+
+	for _, storyboardPath := range domain.StoryboardPaths(previewPath) {
+		os.Remove(filepath.Join(p.previewDir, storyboardPath))
+	}
 }
 
-func (p *PreviewService) getVideoDuration(videoPath string) (time.Duration, error) {
-	cmd := exec.Command("ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", videoPath)
+// OpenPreview opens the preview image at previewPath.
+func (p *PreviewService) OpenPreview(previewPath string) (video_dto.VideoFileInfoDto, error) {
+	filePath := filepath.Join(p.previewDir, previewPath)
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return video_dto.VideoFileInfoDto{}, fmt.Errorf("%w (preview path: %s): %s", domain.ErrPreviewNotFound, previewPath, err)
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return video_dto.VideoFileInfoDto{}, fmt.Errorf("%w (path: %s): %s", domain.ErrGettingFileInfo, filePath, err)
+	}
+
+	return video_dto.VideoFileInfoDto{
+		VideoName:   filepath.Base(previewPath),
+		FileSize:    fileInfo.Size(),
+		ModTime:     fileInfo.ModTime(),
+		ContentType: domain.PreviewContentType,
+		VideoFile:   file,
+	}, nil
+}
+
+func (p *PreviewService) getVideoDuration(ctx context.Context, videoPath string) (_ time.Duration, err error) {
+	ctx, span := tracing.StartProcess(ctx, metrics.FfmpegProbe, p.ffprobePath)
+	defer tracing.End(span, &err)
+
+	cmd := common.Command(ctx, p.ffprobePath, "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", videoPath)
+	start := time.Now()
 	output, err := cmd.Output()
+	metrics.ObserveFfmpeg(metrics.FfmpegProbe, start, err)
 	if err != nil {
 		return 0, fmt.Errorf("%w (video path: %s): %s", domain.ErrGettingVideoDuration, videoPath, err)
 	}
@@ -69,20 +212,105 @@ func (p *PreviewService) getVideoDuration(videoPath string) (time.Duration, erro
 	return time.Duration(durationSeconds) * time.Second, nil
 }
 
-func (p *PreviewService) generateRandomTime(videoDuration time.Duration) string {
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	randomFraction := domain.MinTimeFraction + rng.Float64()*domain.MaxTimeFraction
-	randomSeconds := int64(videoDuration.Seconds() * randomFraction)
-	randomTime := time.Duration(randomSeconds) * time.Second
-	return fmt.Sprintf("%d", int(randomTime.Seconds()))
+// candidateTimes returns the times of the candidate frames, in seconds, at the
+// middle of equal slices of the preview window. A video shorter than a second
+// has a single candidate at its start.
+func (p *PreviewService) candidateTimes(videoDuration time.Duration) []string {
+	if videoDuration <= 0 {
+		return []string{"0"}
+	}
+
+	times := make([]string, p.candidates)
+	for i := range times {
+		fraction := p.minTimeFraction + p.maxTimeFraction*(float64(i)+0.5)/float64(p.candidates)
+		times[i] = strconv.FormatFloat(videoDuration.Seconds()*fraction, 'f', 3, 64)
+	}
+
+	return times
 }
 
-func (p *PreviewService) generatePreview(videoPath, previewPath, previewTime string) error {
-	cmd := exec.Command("ffmpeg", "-i", videoPath, "-ss", previewTime, "-vframes", "1", previewPath)
+// generatePreview writes the most representative of the thumbnailFrames
+// frames from previewTime on, as picked by the thumbnail filter of ffmpeg.
+func (p *PreviewService) generatePreview(ctx context.Context, videoPath, previewPath, previewTime string) (err error) {
+	ctx, span := tracing.StartProcess(ctx, metrics.FfmpegPreview, p.ffmpegPath)
+	defer tracing.End(span, &err)
+
+	cmd := common.Command(ctx, p.ffmpegPath, "-v", "error", "-y", "-ss", previewTime, "-i", videoPath, "-vf", fmt.Sprintf("thumbnail=%d", thumbnailFrames), "-frames:v", "1", previewPath)
+	start := time.Now()
 	output, err := cmd.CombinedOutput()
+	metrics.ObserveFfmpeg(metrics.FfmpegPreview, start, err)
 	if err != nil {
+		os.Remove(previewPath)
 		return fmt.Errorf("%w (ffmpeg output: %s): %s", domain.ErrGeneratingPreview, string(output), err)
 	}
 
+	// ffmpeg succeeds without output when previewTime is past the last frame
+	if info, err := os.Stat(previewPath); err != nil || info.Size() == 0 {
+		os.Remove(previewPath)
+		return fmt.Errorf("%w (preview time: %s): no frame", domain.ErrGeneratingPreview, previewTime)
+	}
+
 	return nil
 }
+
+type candidate struct {
+	path  string
+	score frameScore
+}
+
+// frameScore is the mean and the standard deviation of the luma of a frame,
+// on a 0 to 255 scale. The deviation measures how much there is to see.
+type frameScore struct {
+	luma      float64
+	deviation float64
+}
+
+// usable reports whether the frame is neither dark nor blank.
+func (s frameScore) usable() bool {
+	return s.luma >= darkLuma && s.deviation >= blankDeviation
+}
+
+// better reports whether s makes a better preview than other: a usable frame
+// beats one that isn't, then the most detailed frame wins.
+func (s frameScore) better(other frameScore) bool {
+	if s.usable() != other.usable() {
+		return s.usable()
+	}
+
+	return s.deviation > other.deviation
+}
+
+// scoreFrame measures the image at path on a grid of at most scoreSamples
+// by scoreSamples pixels. A frame that can't be decoded scores zero.
+func scoreFrame(path string) (frameScore, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return frameScore{}, fmt.Errorf("%w (path: %s): %s", domain.ErrScoringPreview, path, err)
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return frameScore{}, fmt.Errorf("%w (path: %s): %s", domain.ErrScoringPreview, path, err)
+	}
+
+	bounds := img.Bounds()
+	stepX, stepY := max(1, bounds.Dx()/scoreSamples), max(1, bounds.Dy()/scoreSamples)
+
+	var sum, sumSquares, n float64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += stepY {
+		for x := bounds.Min.X; x < bounds.Max.X; x += stepX {
+			luma := float64(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+			sum += luma
+			sumSquares += luma * luma
+			n++
+		}
+	}
+
+	if n == 0 {
+		return frameScore{}, nil
+	}
+
+	mean := sum / n
+	return frameScore{luma: mean, deviation: math.Sqrt(max(0, sumSquares/n-mean*mean))}, nil
+}
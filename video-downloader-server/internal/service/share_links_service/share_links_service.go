@@ -0,0 +1,350 @@
+package share_links_service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+	"sync"
+	"time"
+	"video-downloader-server/internal/delivery/dto/video_dto"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/tracing"
+)
+
+type ShareLinksRepo interface {
+	Create(ctx context.Context, link domain.ShareLink) (primitive.ObjectID, error)
+	GetByTokenHash(ctx context.Context, tokenHash string) (domain.ShareLink, error)
+	GetByVideo(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID) ([]domain.ShareLink, error)
+	Delete(ctx context.Context, ownerID primitive.ObjectID, linkID primitive.ObjectID) error
+	AddView(ctx context.Context, linkID primitive.ObjectID) error
+}
+
+type VideosRepo interface {
+	Lookup(ctx context.Context, videoID primitive.ObjectID) (domain.Video, error)
+}
+
+type Videos interface {
+	Authorize(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, role string) (domain.Video, error)
+	OpenVideo(ctx context.Context, video domain.Video) (video_dto.VideoFileInfoDto, error)
+}
+
+// viewGrant lets the viewer holding it seek in a view that was already
+// counted.
+type viewGrant struct {
+	linkID    primitive.ObjectID
+	expiresAt time.Time
+}
+
+// ShareLinksService manages public links to single videos. Only owners of the
+// folder of a video may share it, anyone holding the token may then watch it
+// until the link expires, runs out of views or is revoked.
+type ShareLinksService struct {
+	repo          ShareLinksRepo
+	videosRepo    VideosRepo
+	videosService Videos
+	bcryptCost    int
+
+	mu     sync.Mutex
+	grants map[string]viewGrant // by hash of the grant
+	swept  time.Time
+}
+
+func NewShareLinksService(repo ShareLinksRepo, videosRepo VideosRepo, videosService Videos, bcryptCost int) *ShareLinksService {
+	return &ShareLinksService{
+		repo:          repo,
+		videosRepo:    videosRepo,
+		videosService: videosService,
+		bcryptCost:    bcryptCost,
+		grants:        make(map[string]viewGrant),
+		swept:         time.Now(),
+	}
+}
+
+// Create creates a share link for the video. The token is only returned here.
+func (s *ShareLinksService) Create(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, createShareLinkInput video_dto.CreateShareLinkDto) (_ video_dto.ShareLinkDto, err error) {
+	ctx, span := tracing.Start(ctx, "ShareLinksService.Create")
+	defer tracing.End(span, &err)
+
+	video, err := s.videosService.Authorize(ctx, userID, videoID, domain.FolderOwner)
+	if err != nil {
+		return video_dto.ShareLinkDto{}, err
+	}
+
+	token, tokenHash, err := newToken()
+	if err != nil {
+		return video_dto.ShareLinkDto{}, fmt.Errorf("%w (video id: %s): %s", domain.ErrCreatingShareLink, videoID, err)
+	}
+
+	now := time.Now()
+	link := domain.ShareLink{
+		VideoID:   videoID,
+		OwnerID:   video.OwnerID,
+		CreatedBy: userID,
+		TokenHash: tokenHash,
+		MaxViews:  createShareLinkInput.MaxViews,
+		CreatedAt: now,
+	}
+
+	if createShareLinkInput.ExpiresIn > 0 {
+		link.ExpiresAt = now.Add(time.Duration(createShareLinkInput.ExpiresIn) * time.Second)
+	}
+
+	if createShareLinkInput.Password != "" {
+		passwordHash, err := bcrypt.GenerateFromPassword([]byte(createShareLinkInput.Password), s.bcryptCost)
+		if err != nil {
+			return video_dto.ShareLinkDto{}, fmt.Errorf("%w (video id: %s): %s", domain.ErrHashingPassword, videoID, err)
+		}
+		link.PasswordHash = string(passwordHash)
+	}
+
+	link.ID, err = s.repo.Create(ctx, link)
+	if err != nil {
+		return video_dto.ShareLinkDto{}, fmt.Errorf("%w (video id: %s): %s", domain.ErrCreatingShareLink, videoID, err)
+	}
+
+	res := toShareLinkDto(link)
+	res.Token = token
+
+	return res, nil
+}
+
+// List returns the share links of the video, without their tokens.
+func (s *ShareLinksService) List(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID) (_ []video_dto.ShareLinkDto, err error) {
+	ctx, span := tracing.Start(ctx, "ShareLinksService.List")
+	defer tracing.End(span, &err)
+
+	links, _, err := s.getLinks(ctx, userID, videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]video_dto.ShareLinkDto, 0, len(links))
+	for _, link := range links {
+		res = append(res, toShareLinkDto(link))
+	}
+
+	return res, nil
+}
+
+// Revoke deletes a share link of the video, it stops working at once.
+func (s *ShareLinksService) Revoke(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, linkID primitive.ObjectID) (err error) {
+	ctx, span := tracing.Start(ctx, "ShareLinksService.Revoke")
+	defer tracing.End(span, &err)
+
+	links, ownerID, err := s.getLinks(ctx, userID, videoID)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, link := range links {
+		found = found || link.ID == linkID
+	}
+
+	if !found {
+		return fmt.Errorf("%w (video id: %s, link id: %s)", domain.ErrShareLinkNotFound, videoID, linkID)
+	}
+
+	if err := s.repo.Delete(ctx, ownerID, linkID); err != nil {
+		if errors.Is(err, domain.ErrNoDocuments) {
+			return fmt.Errorf("%w (video id: %s, link id: %s)", domain.ErrShareLinkNotFound, videoID, linkID)
+		}
+
+		return fmt.Errorf("%w (link id: %s): %s", domain.ErrRevokingShareLink, linkID, err)
+	}
+
+	return nil
+}
+
+// GetVideoFileInfo opens the shared video. A request carrying a live grant
+// belongs to a view that was already counted and is served as is, any other
+// request counts as a new view when countView is set and returns the grant
+// for the rest of that view. Without countView the limit is still checked but
+// no view is counted, for requests that send no content.
+func (s *ShareLinksService) GetVideoFileInfo(ctx context.Context, token, password, grant string, countView bool) (_ video_dto.VideoFileInfoDto, _ string, err error) {
+	ctx, span := tracing.Start(ctx, "ShareLinksService.GetVideoFileInfo")
+	defer tracing.End(span, &err)
+
+	video, grant, err := s.open(ctx, token, password, grant, countView)
+	if err != nil {
+		return video_dto.VideoFileInfoDto{}, "", err
+	}
+
+	videoInfo, err := s.videosService.OpenVideo(ctx, video)
+	if err != nil {
+		return video_dto.VideoFileInfoDto{}, "", err
+	}
+
+	return videoInfo, grant, nil
+}
+
+// open returns the video behind token and the grant of the view. Unknown and
+// expired links fail with ErrShareLinkNotFound, used up links with
+// ErrShareLinkUsedUp unless grant belongs to one of their counted views.
+func (s *ShareLinksService) open(ctx context.Context, token, password, grant string, countView bool) (domain.Video, string, error) {
+	link, err := s.repo.GetByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, domain.ErrNoDocuments) {
+			return domain.Video{}, "", domain.ErrShareLinkNotFound
+		}
+
+		return domain.Video{}, "", fmt.Errorf("%w: %s", domain.ErrGettingShareLinks, err)
+	}
+
+	now := time.Now()
+	if link.Expired(now) {
+		return domain.Video{}, "", fmt.Errorf("%w (link id: %s): expired", domain.ErrShareLinkNotFound, link.ID)
+	}
+
+	granted := grant != "" && s.useGrant(grant, link.ID, now)
+	if !granted {
+		if link.UsedUp() {
+			return domain.Video{}, "", fmt.Errorf("%w (link id: %s)", domain.ErrShareLinkUsedUp, link.ID)
+		}
+
+		if link.PasswordHash != "" {
+			if err := bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)); err != nil {
+				return domain.Video{}, "", fmt.Errorf("%w (link id: %s)", domain.ErrInvalidSharePassword, link.ID)
+			}
+		}
+	}
+
+	video, err := s.videosRepo.Lookup(ctx, link.VideoID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoDocuments) {
+			return domain.Video{}, "", fmt.Errorf("%w (link id: %s): video deleted", domain.ErrShareLinkNotFound, link.ID)
+		}
+
+		return domain.Video{}, "", fmt.Errorf("%w (video id: %s): %s", domain.ErrCheckingVideo, link.VideoID, err)
+	}
+
+	if granted {
+		return video, grant, nil
+	}
+
+	if !countView {
+		return video, "", nil
+	}
+
+	if err := s.repo.AddView(ctx, link.ID); err != nil {
+		if errors.Is(err, domain.ErrNoDocuments) {
+			return domain.Video{}, "", fmt.Errorf("%w (link id: %s)", domain.ErrShareLinkUsedUp, link.ID)
+		}
+
+		return domain.Video{}, "", fmt.Errorf("%w (link id: %s): %s", domain.ErrCountingShareView, link.ID, err)
+	}
+
+	grant, err = s.newGrant(link.ID, now)
+	if err != nil {
+		return domain.Video{}, "", fmt.Errorf("%w (link id: %s): %s", domain.ErrCountingShareView, link.ID, err)
+	}
+
+	return video, grant, nil
+}
+
+// newGrant returns a grant for a view of linkID just counted at now.
+func (s *ShareLinksService) newGrant(linkID primitive.ObjectID, now time.Time) (string, error) {
+	grant, grantHash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+	s.grants[grantHash] = viewGrant{linkID: linkID, expiresAt: now.Add(domain.ShareViewGrantTTL)}
+
+	return grant, nil
+}
+
+// useGrant reports whether grant is live for linkID at now and keeps it alive
+// for another ShareViewGrantTTL.
+func (s *ShareLinksService) useGrant(grant string, linkID primitive.ObjectID, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	grantHash := hashToken(grant)
+	g, ok := s.grants[grantHash]
+	if !ok || g.linkID != linkID || !now.Before(g.expiresAt) {
+		return false
+	}
+
+	g.expiresAt = now.Add(domain.ShareViewGrantTTL)
+	s.grants[grantHash] = g
+
+	return true
+}
+
+// sweep forgets the expired grants once per ShareViewGrantTTL, s.mu must be
+// held.
+func (s *ShareLinksService) sweep(now time.Time) {
+	if now.Sub(s.swept) < domain.ShareViewGrantTTL {
+		return
+	}
+
+	for grantHash, g := range s.grants {
+		if !now.Before(g.expiresAt) {
+			delete(s.grants, grantHash)
+		}
+	}
+	s.swept = now
+}
+
+// getLinks returns the share links of the video and its owner, if userID
+// owns the folder of the video.
+func (s *ShareLinksService) getLinks(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID) ([]domain.ShareLink, primitive.ObjectID, error) {
+	video, err := s.videosService.Authorize(ctx, userID, videoID, domain.FolderOwner)
+	if err != nil {
+		return nil, primitive.NilObjectID, err
+	}
+
+	links, err := s.repo.GetByVideo(ctx, video.OwnerID, videoID)
+	if err != nil {
+		return nil, primitive.NilObjectID, fmt.Errorf("%w (video id: %s): %s", domain.ErrGettingShareLinks, videoID, err)
+	}
+
+	return links, video.OwnerID, nil
+}
+
+// newToken returns a random url safe token and the hash it is stored under.
+func newToken() (string, string, error) {
+	b := make([]byte, domain.ShareLinkTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func toShareLinkDto(link domain.ShareLink) video_dto.ShareLinkDto {
+	res := video_dto.ShareLinkDto{
+		ID:                link.ID,
+		VideoID:           link.VideoID,
+		PasswordProtected: link.PasswordHash != "",
+		MaxViews:          link.MaxViews,
+		Views:             link.Views,
+		CreatedAt:         link.CreatedAt,
+	}
+
+	if !link.ExpiresAt.IsZero() {
+		res.ExpiresAt = &link.ExpiresAt
+	}
+
+	return res
+}
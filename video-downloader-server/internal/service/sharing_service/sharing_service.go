@@ -0,0 +1,334 @@
+package sharing_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+	"video-downloader-server/internal/delivery/dto/folder_dto"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/tracing"
+)
+
+type FoldersRepo interface {
+	Lookup(ctx context.Context, folderID primitive.ObjectID) (domain.Folder, error)
+}
+
+type MembersRepo interface {
+	Upsert(ctx context.Context, member domain.FolderMember) error
+	Delete(ctx context.Context, folderID primitive.ObjectID, userID primitive.ObjectID) error
+	GetByFolder(ctx context.Context, folderID primitive.ObjectID) ([]domain.FolderMember, error)
+	GetByUser(ctx context.Context, userID primitive.ObjectID) ([]domain.FolderMember, error)
+	DeleteByFolders(ctx context.Context, foldersID []primitive.ObjectID) error
+}
+
+type UsersRepo interface {
+	GetByID(ctx context.Context, userID primitive.ObjectID) (domain.User, error)
+	GetByUsername(ctx context.Context, username string) (domain.User, error)
+}
+
+// SharingService decides what a user may do in a folder. The owner of a
+// folder has every right on it, other users have the strongest role granted
+// to them on the folder or any of its parents.
+type SharingService struct {
+	foldersRepo FoldersRepo
+	membersRepo MembersRepo
+	usersRepo   UsersRepo
+}
+
+func NewSharingService(foldersRepo FoldersRepo, membersRepo MembersRepo, usersRepo UsersRepo) *SharingService {
+	return &SharingService{
+		foldersRepo: foldersRepo,
+		membersRepo: membersRepo,
+		usersRepo:   usersRepo,
+	}
+}
+
+// Require checks that userID has at least role in folderID and returns the
+// owner of the folder, whose videos and folders the caller then works on. The
+// root folder always belongs to userID. A folder the user can't see at all
+// fails with ErrFolderNotFound, a role too weak with ErrFolderAccessDenied.
+func (s *SharingService) Require(ctx context.Context, userID primitive.ObjectID, folderID primitive.ObjectID, role string) (_ primitive.ObjectID, err error) {
+	ctx, span := tracing.Start(ctx, "SharingService.Require")
+	defer tracing.End(span, &err)
+
+	if folderID == primitive.NilObjectID {
+		return userID, nil
+	}
+
+	chain, userRole, err := s.access(ctx, userID, folderID)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	if !domain.FolderRoleAtLeast(userRole, role) {
+		return primitive.NilObjectID, fmt.Errorf("%w (folder id: %s, role: %s, required: %s)", domain.ErrFolderAccessDenied, folderID, userRole, role)
+	}
+
+	return chain[0].OwnerID, nil
+}
+
+// Grant gives the user named in grantAccessInput a role on the folder and
+// every folder below it. Only owners of the folder may grant.
+func (s *SharingService) Grant(ctx context.Context, userID primitive.ObjectID, grantAccessInput folder_dto.GrantAccessDto) (_ folder_dto.MemberDto, err error) {
+	ctx, span := tracing.Start(ctx, "SharingService.Grant")
+	defer tracing.End(span, &err)
+
+	ownerID, err := s.Require(ctx, userID, grantAccessInput.FolderID, domain.FolderOwner)
+	if err != nil {
+		return folder_dto.MemberDto{}, err
+	}
+
+	member, err := s.usersRepo.GetByUsername(ctx, grantAccessInput.Username)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoDocuments) {
+			return folder_dto.MemberDto{}, fmt.Errorf("%w (username: %s)", domain.ErrUserNotFound, grantAccessInput.Username)
+		}
+
+		return folder_dto.MemberDto{}, fmt.Errorf("%w (username: %s): %s", domain.ErrGettingUser, grantAccessInput.Username, err)
+	}
+
+	if member.ID == ownerID {
+		return folder_dto.MemberDto{}, fmt.Errorf("%w (folder id: %s)", domain.ErrSharingWithOwner, grantAccessInput.FolderID)
+	}
+
+	err = s.membersRepo.Upsert(ctx, domain.FolderMember{
+		FolderID:  grantAccessInput.FolderID,
+		UserID:    member.ID,
+		Role:      grantAccessInput.Role,
+		GrantedBy: userID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return folder_dto.MemberDto{}, fmt.Errorf("%w (folder id: %s, user id: %s): %s", domain.ErrGrantingAccess, grantAccessInput.FolderID, member.ID, err)
+	}
+
+	return folder_dto.MemberDto{
+		UserID:    member.ID,
+		Username:  member.Username,
+		Role:      grantAccessInput.Role,
+		GrantedOn: grantAccessInput.FolderID,
+	}, nil
+}
+
+// Revoke removes the role granted on the folder itself, roles inherited from
+// its parents stay. Owners of the folder may revoke anyone, other members
+// only themselves.
+func (s *SharingService) Revoke(ctx context.Context, userID primitive.ObjectID, revokeAccessInput folder_dto.RevokeAccessDto) (err error) {
+	ctx, span := tracing.Start(ctx, "SharingService.Revoke")
+	defer tracing.End(span, &err)
+
+	if revokeAccessInput.UserID != userID {
+		if _, err := s.Require(ctx, userID, revokeAccessInput.FolderID, domain.FolderOwner); err != nil {
+			return err
+		}
+	}
+
+	if err := s.membersRepo.Delete(ctx, revokeAccessInput.FolderID, revokeAccessInput.UserID); err != nil {
+		if errors.Is(err, domain.ErrNoDocuments) {
+			return fmt.Errorf("%w (folder id: %s, user id: %s)", domain.ErrMemberNotFound, revokeAccessInput.FolderID, revokeAccessInput.UserID)
+		}
+
+		return fmt.Errorf("%w (folder id: %s, user id: %s): %s", domain.ErrRevokingAccess, revokeAccessInput.FolderID, revokeAccessInput.UserID, err)
+	}
+
+	return nil
+}
+
+// GetMembers lists the owner of the folder and every user with a role on it
+// or one of its parents, with the strongest role of each.
+func (s *SharingService) GetMembers(ctx context.Context, userID primitive.ObjectID, folderID primitive.ObjectID) (_ []folder_dto.MemberDto, err error) {
+	ctx, span := tracing.Start(ctx, "SharingService.GetMembers")
+	defer tracing.End(span, &err)
+
+	if folderID == primitive.NilObjectID {
+		return nil, fmt.Errorf("%w (folder id: %s)", domain.ErrFolderNotFound, folderID)
+	}
+
+	chain, _, err := s.access(ctx, userID, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	ownerID := chain[0].OwnerID
+	owner, err := s.usersRepo.GetByID(ctx, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("%w (user id: %s): %s", domain.ErrGettingUser, ownerID, err)
+	}
+
+	res := []folder_dto.MemberDto{{
+		UserID:    owner.ID,
+		Username:  owner.Username,
+		Role:      domain.FolderOwner,
+		GrantedOn: chain[len(chain)-1].ID,
+	}}
+	index := map[primitive.ObjectID]int{owner.ID: 0}
+
+	for _, folder := range chain {
+		members, err := s.membersRepo.GetByFolder(ctx, folder.ID)
+		if err != nil {
+			return nil, fmt.Errorf("%w (folder id: %s): %s", domain.ErrGettingMembers, folder.ID, err)
+		}
+
+		for _, member := range members {
+			if i, ok := index[member.UserID]; ok {
+				if domain.FolderRoleAtLeast(res[i].Role, member.Role) {
+					continue
+				}
+
+				res[i].Role, res[i].GrantedOn = member.Role, member.FolderID
+				continue
+			}
+
+			user, err := s.usersRepo.GetByID(ctx, member.UserID)
+			if err != nil {
+				if errors.Is(err, domain.ErrNoDocuments) {
+					continue
+				}
+
+				return nil, fmt.Errorf("%w (user id: %s): %s", domain.ErrGettingUser, member.UserID, err)
+			}
+
+			index[user.ID] = len(res)
+			res = append(res, folder_dto.MemberDto{
+				UserID:    user.ID,
+				Username:  user.Username,
+				Role:      member.Role,
+				GrantedOn: member.FolderID,
+			})
+		}
+	}
+
+	return res, nil
+}
+
+// GetShared lists the folders other users have shared with userID directly.
+func (s *SharingService) GetShared(ctx context.Context, userID primitive.ObjectID) (_ []folder_dto.SharedFolderDto, err error) {
+	ctx, span := tracing.Start(ctx, "SharingService.GetShared")
+	defer tracing.End(span, &err)
+
+	members, err := s.membersRepo.GetByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w (user id: %s): %s", domain.ErrGettingMembers, userID, err)
+	}
+
+	res := make([]folder_dto.SharedFolderDto, 0, len(members))
+	for _, member := range members {
+		folder, err := s.foldersRepo.Lookup(ctx, member.FolderID)
+		if err != nil {
+			if errors.Is(err, domain.ErrNoDocuments) {
+				continue
+			}
+
+			return nil, fmt.Errorf("%w (folder id: %s): %s", domain.ErrCheckingFolder, member.FolderID, err)
+		}
+
+		res = append(res, folder_dto.SharedFolderDto{
+			ID:         folder.ID,
+			FolderName: folder.FolderName,
+			OwnerID:    folder.OwnerID,
+			Role:       member.Role,
+		})
+	}
+
+	return res, nil
+}
+
+// DeleteFolders forgets every role granted on the deleted folders.
+func (s *SharingService) DeleteFolders(ctx context.Context, foldersID []primitive.ObjectID) error {
+	if err := s.membersRepo.DeleteByFolders(ctx, foldersID); err != nil {
+		return fmt.Errorf("%w (folders id: %s): %s", domain.ErrDeletingMembers, foldersID, err)
+	}
+
+	return nil
+}
+
+// access returns the folder with its parents and the role of userID on it.
+// It fails with ErrFolderNotFound when the user has no role at all.
+func (s *SharingService) access(ctx context.Context, userID primitive.ObjectID, folderID primitive.ObjectID) ([]domain.Folder, string, error) {
+	chain, err := s.ancestors(ctx, folderID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	role, err := s.resolveRole(ctx, userID, chain)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if role == "" {
+		return nil, "", fmt.Errorf("%w (folder id: %s)", domain.ErrFolderNotFound, folderID)
+	}
+
+	return chain, role, nil
+}
+
+// ancestors returns folderID followed by its parents up to the root, within
+// the tree of the owner of folderID.
+func (s *SharingService) ancestors(ctx context.Context, folderID primitive.ObjectID) ([]domain.Folder, error) {
+	folder, err := s.lookup(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := []domain.Folder{folder}
+	for len(chain) < domain.MaxFolderDepth && folder.ParentDirID != primitive.NilObjectID {
+		parent, err := s.lookup(ctx, folder.ParentDirID)
+		if errors.Is(err, domain.ErrFolderNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if parent.OwnerID != chain[0].OwnerID {
+			break
+		}
+
+		chain = append(chain, parent)
+		folder = parent
+	}
+
+	return chain, nil
+}
+
+// resolveRole returns the strongest role of userID on the first folder of
+// chain, or an empty string when the user has none.
+func (s *SharingService) resolveRole(ctx context.Context, userID primitive.ObjectID, chain []domain.Folder) (string, error) {
+	if chain[0].OwnerID == userID {
+		return domain.FolderOwner, nil
+	}
+
+	members, err := s.membersRepo.GetByUser(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("%w (user id: %s): %s", domain.ErrCheckingAccess, userID, err)
+	}
+
+	granted := make(map[primitive.ObjectID]string, len(members))
+	for _, member := range members {
+		granted[member.FolderID] = member.Role
+	}
+
+	var role string
+	for _, folder := range chain {
+		if folderRole, ok := granted[folder.ID]; ok && domain.FolderRoleAtLeast(folderRole, role) {
+			role = folderRole
+		}
+	}
+
+	return role, nil
+}
+
+func (s *SharingService) lookup(ctx context.Context, folderID primitive.ObjectID) (domain.Folder, error) {
+	folder, err := s.foldersRepo.Lookup(ctx, folderID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoDocuments) {
+			return domain.Folder{}, fmt.Errorf("%w (folder id: %s)", domain.ErrFolderNotFound, folderID)
+		}
+
+		return domain.Folder{}, fmt.Errorf("%w (folder id: %s): %s", domain.ErrCheckingFolder, folderID, err)
+	}
+
+	return folder, nil
+}
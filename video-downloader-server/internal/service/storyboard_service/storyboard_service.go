@@ -0,0 +1,330 @@
+package storyboard_service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"video-downloader-server/internal/delivery/dto/job_dto"
+	"video-downloader-server/internal/delivery/dto/video_dto"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/metrics"
+	"video-downloader-server/internal/service/common"
+	"video-downloader-server/internal/tracing"
+)
+
+const (
+	// storyboardColumns is the number of thumbnails per row of a sprite.
+	storyboardColumns = 10
+
+	spriteQuality = "5"
+)
+
+type Videos interface {
+	Authorize(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, role string) (domain.Video, error)
+}
+
+type Jobs interface {
+	Submit(ctx context.Context, ownerID primitive.ObjectID, jobType, source string, run domain.JobFunc) (job_dto.JobDto, error)
+}
+
+// StoryboardService generates the storyboard of a video with ffmpeg and
+// serves it, for players to preview the frame under the cursor while
+// scrubbing. A storyboard is a single sprite of thumbnails with a WebVTT
+// thumbnails track pointing into it, stored next to the preview of the video
+// and removed together with it.
+type StoryboardService struct {
+	videosService Videos
+	jobsService   Jobs
+	videoDir      string
+	previewDir    string
+	ffmpegPath    string
+	ffprobePath   string
+	interval      time.Duration
+	maxTiles      int
+	tileWidth     int
+
+	mu      sync.Mutex
+	pending map[string]job_dto.JobDto
+}
+
+// NewStoryboardService creates the service for storyboards with a thumbnail
+// every interval, stretched so that there are at most maxTiles, each
+// tileWidth pixels wide.
+func NewStoryboardService(videosService Videos, jobsService Jobs, videoDir, previewDir, ffmpegPath, ffprobePath string, interval time.Duration, maxTiles, tileWidth int) *StoryboardService {
+	return &StoryboardService{
+		videosService: videosService,
+		jobsService:   jobsService,
+		videoDir:      videoDir,
+		previewDir:    previewDir,
+		ffmpegPath:    ffmpegPath,
+		ffprobePath:   ffprobePath,
+		interval:      interval,
+		maxTiles:      maxTiles,
+		tileWidth:     tileWidth,
+		pending:       make(map[string]job_dto.JobDto),
+	}
+}
+
+// Generate queues a job generating the storyboard of the video unless it is
+// ready or already being generated, in which case the pending job is
+// returned. The job belongs to userID.
+func (s *StoryboardService) Generate(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID) (_ video_dto.StoryboardStatusDto, err error) {
+	ctx, span := tracing.Start(ctx, "StoryboardService.Generate")
+	defer tracing.End(span, &err)
+
+	video, err := s.videosService.Authorize(ctx, userID, videoID, domain.FolderViewer)
+	if err != nil {
+		return video_dto.StoryboardStatusDto{}, err
+	}
+
+	if s.ready(video) {
+		return video_dto.StoryboardStatusDto{Ready: true}, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.pending[video.PreviewPath]; ok {
+		return video_dto.StoryboardStatusDto{Job: &job}, nil
+	}
+
+	job, err := s.jobsService.Submit(ctx, userID, domain.JobStoryboard, video.VideoName, func(ctx context.Context) (primitive.ObjectID, error) {
+		defer func() {
+			s.mu.Lock()
+			delete(s.pending, video.PreviewPath)
+			s.mu.Unlock()
+		}()
+
+		return video.ID, s.generate(ctx, video)
+	})
+	if err != nil {
+		return video_dto.StoryboardStatusDto{}, err
+	}
+	s.pending[video.PreviewPath] = job
+
+	return video_dto.StoryboardStatusDto{Job: &job}, nil
+}
+
+// GetFile opens the sprite or the track of the storyboard of the video. It
+// fails with ErrStoryboardNotGenerated when the storyboard is not ready.
+func (s *StoryboardService) GetFile(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, name string) (_ video_dto.VideoFileInfoDto, err error) {
+	ctx, span := tracing.Start(ctx, "StoryboardService.GetFile")
+	defer tracing.End(span, &err)
+
+	video, err := s.videosService.Authorize(ctx, userID, videoID, domain.FolderViewer)
+	if err != nil {
+		return video_dto.VideoFileInfoDto{}, err
+	}
+
+	if !domain.ValidStoryboardFile(name) {
+		return video_dto.VideoFileInfoDto{}, fmt.Errorf("%w (file: %s)", domain.ErrStoryboardFileNotFound, name)
+	}
+
+	if !s.ready(video) {
+		return video_dto.VideoFileInfoDto{}, fmt.Errorf("%w (video id: %s)", domain.ErrStoryboardNotGenerated, videoID.Hex())
+	}
+
+	filePath := filepath.Join(s.previewDir, domain.StoryboardPath(video.PreviewPath, name))
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return video_dto.VideoFileInfoDto{}, fmt.Errorf("%w (file: %s): %s", domain.ErrStoryboardFileNotFound, name, err)
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return video_dto.VideoFileInfoDto{}, fmt.Errorf("%w (path: %s): %s", domain.ErrGettingFileInfo, filePath, err)
+	}
+
+	contentType := domain.PreviewContentType
+	if name == domain.StoryboardTrack {
+		contentType = domain.SubtitleContentType
+	}
+
+	return video_dto.VideoFileInfoDto{
+		VideoName:   name,
+		FileSize:    fileInfo.Size(),
+		ModTime:     fileInfo.ModTime(),
+		ContentType: contentType,
+		VideoFile:   file,
+	}, nil
+}
+
+// ready reports whether the storyboard of the video has been generated. The
+// track is renamed into place after the sprite.
+func (s *StoryboardService) ready(video domain.Video) bool {
+	_, err := os.Stat(filepath.Join(s.previewDir, domain.StoryboardPath(video.PreviewPath, domain.StoryboardTrack)))
+	return err == nil
+}
+
+// layout is the grid of thumbnails of a storyboard.
+type layout struct {
+	tiles      int
+	columns    int
+	rows       int
+	tileWidth  int
+	tileHeight int
+	interval   time.Duration
+	duration   time.Duration
+}
+
+// generate writes the sprite and then the track, each into a tmp file renamed
+// into place, so that a storyboard is either complete or missing.
+func (s *StoryboardService) generate(ctx context.Context, video domain.Video) (err error) {
+	ctx, span := tracing.Start(ctx, "StoryboardService.generate")
+	defer tracing.End(span, &err)
+
+	videoPath := filepath.Join(s.videoDir, video.RealPath)
+	spritePath := filepath.Join(s.previewDir, domain.StoryboardPath(video.PreviewPath, domain.StoryboardSprite))
+	trackPath := filepath.Join(s.previewDir, domain.StoryboardPath(video.PreviewPath, domain.StoryboardTrack))
+
+	grid, err := s.probe(ctx, videoPath)
+	if err != nil {
+		return err
+	}
+
+	tmpSpritePath := tmpPath(spritePath)
+	if err := s.encode(ctx, videoPath, tmpSpritePath, grid); err != nil {
+		os.Remove(tmpSpritePath)
+		return err
+	}
+
+	if err := os.Rename(tmpSpritePath, spritePath); err != nil {
+		os.Remove(tmpSpritePath)
+		return fmt.Errorf("%w (video path: %s): %s", domain.ErrGeneratingStoryboard, video.RealPath, err)
+	}
+
+	tmpTrackPath := tmpPath(trackPath)
+	if err := os.WriteFile(tmpTrackPath, s.track(grid), 0o644); err != nil {
+		os.Remove(tmpTrackPath)
+		return fmt.Errorf("%w (video path: %s): %s", domain.ErrGeneratingStoryboard, video.RealPath, err)
+	}
+
+	if err := os.Rename(tmpTrackPath, trackPath); err != nil {
+		os.Remove(tmpTrackPath)
+		return fmt.Errorf("%w (video path: %s): %s", domain.ErrGeneratingStoryboard, video.RealPath, err)
+	}
+
+	return nil
+}
+
+// tmpPath returns a unique path next to path with the same extension, which
+// ffmpeg picks the muxer by.
+func tmpPath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + domain.StoryboardTmpMarker + primitive.NewObjectID().Hex() + ext
+}
+
+type probeOutput struct {
+	Streams []struct {
+		Width  int `json:"width"`
+		Height int `json:"height"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+// probe lays out the storyboard of the video from its duration and the size
+// of its first video stream. The interval is stretched for the thumbnails of
+// long videos to fit in maxTiles.
+func (s *StoryboardService) probe(ctx context.Context, videoPath string) (_ layout, err error) {
+	ctx, span := tracing.StartProcess(ctx, metrics.FfmpegProbe, s.ffprobePath)
+	defer tracing.End(span, &err)
+
+	cmd := common.Command(ctx, s.ffprobePath, "-v", "error", "-select_streams", "v:0", "-show_entries", "stream=width,height:format=duration", "-of", "json", videoPath)
+	start := time.Now()
+	output, err := cmd.Output()
+	metrics.ObserveFfmpeg(metrics.FfmpegProbe, start, err)
+	if err != nil {
+		return layout{}, fmt.Errorf("%w (video path: %s): %s", domain.ErrProbingVideo, videoPath, err)
+	}
+
+	var probed probeOutput
+	if err := json.Unmarshal(output, &probed); err != nil {
+		return layout{}, fmt.Errorf("%w (video path: %s): %s", domain.ErrProbingVideo, videoPath, err)
+	}
+
+	if len(probed.Streams) == 0 || probed.Streams[0].Width == 0 || probed.Streams[0].Height == 0 {
+		return layout{}, fmt.Errorf("%w (video path: %s)", domain.ErrNoVideoStream, videoPath)
+	}
+
+	durationSeconds, err := strconv.ParseFloat(probed.Format.Duration, 64)
+	if err != nil || durationSeconds <= 0 {
+		return layout{}, fmt.Errorf("%w (video path: %s, duration: %s)", domain.ErrParsingVideoDuration, videoPath, probed.Format.Duration)
+	}
+	duration := time.Duration(durationSeconds * float64(time.Second))
+
+	interval := max(s.interval, duration/time.Duration(s.maxTiles))
+	tiles := int((duration + interval - 1) / interval)
+	columns := min(tiles, storyboardColumns)
+
+	// the height keeps the aspect ratio and is even for the encoder
+	tileHeight := max(2, (s.tileWidth*probed.Streams[0].Height/probed.Streams[0].Width+1)/2*2)
+
+	return layout{
+		tiles:      tiles,
+		columns:    columns,
+		rows:       (tiles + columns - 1) / columns,
+		tileWidth:  s.tileWidth,
+		tileHeight: tileHeight,
+		interval:   interval,
+		duration:   duration,
+	}, nil
+}
+
+// encode samples a frame every interval, scales it to a tile and packs the
+// tiles into a single image.
+func (s *StoryboardService) encode(ctx context.Context, videoPath, spritePath string, grid layout) (err error) {
+	ctx, span := tracing.StartProcess(ctx, metrics.FfmpegStoryboard, s.ffmpegPath)
+	defer tracing.End(span, &err)
+
+	filter := fmt.Sprintf("fps=1/%s,scale=%d:%d,tile=%dx%d", strconv.FormatFloat(grid.interval.Seconds(), 'f', 3, 64), grid.tileWidth, grid.tileHeight, grid.columns, grid.rows)
+
+	cmd := common.Command(ctx, s.ffmpegPath, "-v", "error", "-y", "-i", videoPath, "-an", "-vf", filter, "-frames:v", "1", "-q:v", spriteQuality, spritePath)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err = cmd.Run()
+	metrics.ObserveFfmpeg(metrics.FfmpegStoryboard, start, err)
+	if err != nil {
+		return fmt.Errorf("%w (ffmpeg output: %s): %s", domain.ErrGeneratingStoryboard, stderr.String(), err)
+	}
+
+	return nil
+}
+
+// track returns the WebVTT thumbnails track of the storyboard. Each cue
+// covers an interval and points at its tile with a media fragment, relative
+// to the track so that players resolve it next to it.
+func (s *StoryboardService) track(grid layout) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(domain.WebVTTHeader + "\n")
+
+	for i := range grid.tiles {
+		start := time.Duration(i) * grid.interval
+		end := min(start+grid.interval, grid.duration)
+
+		fmt.Fprintf(&buf, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			formatTimestamp(start), formatTimestamp(end), domain.StoryboardSprite,
+			i%grid.columns*grid.tileWidth, i/grid.columns*grid.tileHeight, grid.tileWidth, grid.tileHeight)
+	}
+
+	return buf.Bytes()
+}
+
+// formatTimestamp formats d as a WebVTT timestamp, hh:mm:ss.ttt.
+func formatTimestamp(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60, d.Milliseconds()%1000)
+}
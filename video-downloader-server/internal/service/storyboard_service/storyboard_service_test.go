@@ -0,0 +1,62 @@
+package storyboard_service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatTimestamp(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "00:00:00.000"},
+		{1500 * time.Millisecond, "00:00:01.500"},
+		{59*time.Second + 999*time.Millisecond, "00:00:59.999"},
+		{time.Minute, "00:01:00.000"},
+		{time.Hour + 2*time.Minute + 3*time.Second + 4*time.Millisecond, "01:02:03.004"},
+		{100 * time.Hour, "100:00:00.000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := formatTimestamp(tt.d); got != tt.want {
+				t.Errorf("formatTimestamp(%s) = %q, want %q", tt.d, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrack(t *testing.T) {
+	tests := []struct {
+		name string
+		grid layout
+		want string
+	}{
+		{
+			"single tile",
+			layout{tiles: 1, columns: 1, rows: 1, tileWidth: 160, tileHeight: 90, interval: 10 * time.Second, duration: 4 * time.Second},
+			"WEBVTT\n" +
+				"\n00:00:00.000 --> 00:00:04.000\nstoryboard.jpg#xywh=0,0,160,90\n",
+		},
+		{
+			"grid wraps into rows and the last cue ends with the video",
+			layout{tiles: 5, columns: 2, rows: 3, tileWidth: 160, tileHeight: 90, interval: 10 * time.Second, duration: 45500 * time.Millisecond},
+			"WEBVTT\n" +
+				"\n00:00:00.000 --> 00:00:10.000\nstoryboard.jpg#xywh=0,0,160,90\n" +
+				"\n00:00:10.000 --> 00:00:20.000\nstoryboard.jpg#xywh=160,0,160,90\n" +
+				"\n00:00:20.000 --> 00:00:30.000\nstoryboard.jpg#xywh=0,90,160,90\n" +
+				"\n00:00:30.000 --> 00:00:40.000\nstoryboard.jpg#xywh=160,90,160,90\n" +
+				"\n00:00:40.000 --> 00:00:45.500\nstoryboard.jpg#xywh=0,180,160,90\n",
+		},
+	}
+
+	var s StoryboardService
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(s.track(tt.grid)); got != tt.want {
+				t.Errorf("track() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
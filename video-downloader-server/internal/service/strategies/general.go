@@ -1,18 +1,35 @@
 package strategies
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"path/filepath"
 	"strings"
 	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/logger"
 	"video-downloader-server/internal/service/common"
+	"video-downloader-server/internal/tracing"
 )
 
-type GeneralDownloadStrategy struct{}
+const (
+	downloadingFile = "downloading video file"
+)
+
+type GeneralDownloadStrategy struct {
+	VideoDir string
+}
 
-func (s GeneralDownloadStrategy) Download(videoURL string, quality string) (string, string, error) {
-	res, err := http.Get(videoURL)
+func (s GeneralDownloadStrategy) Download(ctx context.Context, videoURL string, quality string) (_ string, _ string, err error) {
+	ctx, span := tracing.Start(ctx, "GeneralDownloadStrategy.Download")
+	defer tracing.End(span, &err)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, videoURL, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("%w (video url: %s): %s", domain.ErrSendingReq, videoURL, err)
+	}
+
+	res, err := httpClient.Do(req)
 	if err != nil {
 		return "", "", fmt.Errorf("%w (video url: %s): %s", domain.ErrSendingReq, videoURL, err)
 	}
@@ -22,13 +39,15 @@ func (s GeneralDownloadStrategy) Download(videoURL string, quality string) (stri
 		return "", "", fmt.Errorf("%w (video url: %s, status code: %d)", domain.ErrDownloadingVideo, videoURL, res.StatusCode)
 	}
 
-	realPath, err := common.CreateRandomDir(domain.CommonVideoDir)
+	realPath, err := common.CreateRandomDir(s.VideoDir)
 	if err != nil {
 		return "", "", err
 	}
 
 	videoName := common.ReplaceSpecialSymbols(filepath.Base(videoURL))
-	filePath := filepath.Join(domain.CommonVideoDir, realPath, videoName)
+	filePath := filepath.Join(s.VideoDir, realPath, videoName)
+
+	logger.FromContext(ctx).WithField("real_path", filepath.Join(realPath, videoName)).Debug(downloadingFile)
 
 	if err := common.CreateAndWriteFile(filePath, res.Body); err != nil {
 		return "", "", err
@@ -0,0 +1,9 @@
+package strategies
+
+import (
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"net/http"
+)
+
+// httpClient traces every request the strategies send.
+var httpClient = &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
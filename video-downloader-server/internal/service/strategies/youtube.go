@@ -1,33 +1,77 @@
 package strategies
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"github.com/kkdai/youtube/v2"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"net/http"
 	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/logger"
+	"video-downloader-server/internal/metrics"
 	"video-downloader-server/internal/service/common"
+	"video-downloader-server/internal/tracing"
 )
 
-type YouTubeDownloadStrategy struct{}
+const (
+	fetchedMetadata      = "fetched youtube video metadata"
+	mergingStreams       = "merging youtube video and audio streams"
+	downloadingSubtitle  = "error downloading youtube caption track"
+	subtitleNotAvailable = "youtube video has no caption track in the language"
+)
+
+const (
+	// autoCaptionTrackKind is the kind of the tracks generated by speech
+	// recognition.
+	autoCaptionTrackKind = "asr"
+	captionTrackFormat   = "vtt"
+
+	minDescriptionChapters = 3
+)
+
+// descriptionChapter matches a line of a video description starting with a
+// timestamp, possibly after a bullet or in parentheses, followed by the
+// title of the chapter.
+var descriptionChapter = regexp.MustCompile(`^[^\w(]*\(?((?:\d{1,2}:)?\d{1,2}:\d{2})\)?[\s\-–—:|.]*(\S.*)$`)
+
+type YouTubeDownloadStrategy struct {
+	VideoDir   string
+	FfmpegPath string
+}
+
+func (s YouTubeDownloadStrategy) Download(ctx context.Context, videoURL string, quality string) (_ string, _ string, err error) {
+	ctx, span := tracing.Start(ctx, "YouTubeDownloadStrategy.Download")
+	defer tracing.End(span, &err)
+
+	return s.download(ctx, videoURL, quality)
+}
 
-func (s YouTubeDownloadStrategy) Download(videoURL string, quality string) (string, string, error) {
+func (s YouTubeDownloadStrategy) download(ctx context.Context, videoURL string, quality string) (string, string, error) {
 	videoID, err := s.getVideoID(videoURL)
 	if err != nil {
 		return "", "", err
 	}
 
-	video, err := s.fetchVideoMetadata(videoID)
+	video, err := s.fetchVideoMetadata(ctx, videoID)
 	if err != nil {
 		return "", "", err
 	}
 
 	videoName := common.ReplaceSpecialSymbols(video.Title)
+	logger.FromContext(ctx).WithFields(log.Fields{"youtube_id": videoID, "title": video.Title}).Debug(fetchedMetadata)
 
-	videoPath, audioPath, format, err := s.downloadAndPrepareFiles(video, quality, videoName)
+	videoPath, audioPath, format, err := s.downloadAndPrepareFiles(ctx, video, quality, videoName)
 	if err != nil {
 		return "", "", err
 	}
@@ -40,13 +84,14 @@ func (s YouTubeDownloadStrategy) Download(videoURL string, quality string) (stri
 		}
 	}()
 
-	realPath, err := common.CreateRandomDir(domain.CommonVideoDir)
+	realPath, err := common.CreateRandomDir(s.VideoDir)
 	if err != nil {
 		return "", "", err
 	}
 
-	mergedFilePath := filepath.Join(domain.CommonVideoDir, realPath, fmt.Sprintf("%s %s%s", videoName, format.QualityLabel, domain.VideoFormat))
-	if err := s.mergeVideoAudio(videoPath, audioPath, mergedFilePath); err != nil {
+	mergedFilePath := filepath.Join(s.VideoDir, realPath, fmt.Sprintf("%s %s%s", videoName, format.QualityLabel, domain.VideoFormat))
+	logger.FromContext(ctx).WithFields(log.Fields{"quality": format.QualityLabel, "real_path": mergedFilePath}).Debug(mergingStreams)
+	if err := s.mergeVideoAudio(ctx, videoPath, audioPath, mergedFilePath); err != nil {
 		return "", "", err
 	}
 
@@ -68,9 +113,12 @@ func (s YouTubeDownloadStrategy) getVideoID(videoURL string) (string, error) {
 	return videoID, nil
 }
 
-func (s YouTubeDownloadStrategy) fetchVideoMetadata(videoID string) (*youtube.Video, error) {
-	client := youtube.Client{}
-	video, err := client.GetVideo(videoID)
+func (s YouTubeDownloadStrategy) fetchVideoMetadata(ctx context.Context, videoID string) (_ *youtube.Video, err error) {
+	ctx, span := tracing.Start(ctx, "YouTubeDownloadStrategy.fetchVideoMetadata", trace.WithAttributes(attribute.String("youtube.video_id", videoID)))
+	defer tracing.End(span, &err)
+
+	client := youtube.Client{HTTPClient: httpClient}
+	video, err := client.GetVideoContext(ctx, videoID)
 	if err != nil {
 		return nil, fmt.Errorf("%w (video id: %s): %s", domain.ErrFetchingMetadata, videoID, err)
 	}
@@ -78,16 +126,17 @@ func (s YouTubeDownloadStrategy) fetchVideoMetadata(videoID string) (*youtube.Vi
 	return video, nil
 }
 
-func (s YouTubeDownloadStrategy) downloadAndPrepareFiles(video *youtube.Video, quality string, videoName string) (string, string, *youtube.Format, error) {
+func (s YouTubeDownloadStrategy) downloadAndPrepareFiles(ctx context.Context, video *youtube.Video, quality string, videoName string) (string, string, *youtube.Format, error) {
 	selectedVideoFormat := s.selectVideoFormat(video, quality)
-	videoPath := filepath.Join(domain.CommonVideoDir, fmt.Sprintf("%s_video_%s%s", videoName, selectedVideoFormat.QualityLabel, domain.VideoFormat))
-	if err := s.downloadStreamToFile(video, selectedVideoFormat, videoPath); err != nil {
+	videoPath := filepath.Join(s.VideoDir, fmt.Sprintf("%s%s%s%s", videoName, domain.TmpVideoMarker, selectedVideoFormat.QualityLabel, domain.VideoFormat))
+	if err := s.downloadStreamToFile(ctx, video, selectedVideoFormat, videoPath); err != nil {
 		return "", "", nil, err
 	}
 
 	selectedAudioFormat := s.selectAudioFormat(video)
-	audioPath := filepath.Join(domain.CommonVideoDir, fmt.Sprintf("%s_audio%s", videoName, domain.VideoFormat))
-	if err := s.downloadStreamToFile(video, selectedAudioFormat, audioPath); err != nil {
+	audioPath := filepath.Join(s.VideoDir, fmt.Sprintf("%s%s%s", videoName, domain.TmpAudioMarker, domain.VideoFormat))
+	if err := s.downloadStreamToFile(ctx, video, selectedAudioFormat, audioPath); err != nil {
+		os.Remove(videoPath)
 		return "", "", nil, err
 	}
 
@@ -116,10 +165,16 @@ func (s YouTubeDownloadStrategy) selectAudioFormat(video *youtube.Video) *youtub
 	return &formats[0]
 }
 
-func (s YouTubeDownloadStrategy) downloadStreamToFile(video *youtube.Video, format *youtube.Format, fileName string) error {
-	client := youtube.Client{}
+func (s YouTubeDownloadStrategy) downloadStreamToFile(ctx context.Context, video *youtube.Video, format *youtube.Format, fileName string) (err error) {
+	ctx, span := tracing.Start(ctx, "YouTubeDownloadStrategy.downloadStreamToFile", trace.WithAttributes(
+		attribute.String("youtube.mime_type", format.MimeType),
+		attribute.String("youtube.quality", format.Quality),
+	))
+	defer tracing.End(span, &err)
+
+	client := youtube.Client{HTTPClient: httpClient}
 
-	stream, _, err := client.GetStream(video, format)
+	stream, _, err := client.GetStreamContext(ctx, video, format)
 	if err != nil {
 		return fmt.Errorf("%w (for file: %s): %s", domain.ErrGettingStream, fileName, err)
 	}
@@ -150,11 +205,195 @@ func (s YouTubeDownloadStrategy) deleteTmpFiles(videoPath, audioPath string) err
 	return nil
 }
 
-func (s YouTubeDownloadStrategy) mergeVideoAudio(videoFileName string, audioFileName string, mergedFileName string) error {
-	cmd := exec.Command("ffmpeg", "-i", videoFileName, "-i", audioFileName, "-c", "copy", mergedFileName)
-	if err := cmd.Run(); err != nil {
+func (s YouTubeDownloadStrategy) mergeVideoAudio(ctx context.Context, videoFileName string, audioFileName string, mergedFileName string) (err error) {
+	ctx, span := tracing.StartProcess(ctx, metrics.FfmpegMerge, s.FfmpegPath)
+	defer tracing.End(span, &err)
+
+	cmd := common.Command(ctx, s.FfmpegPath, "-i", videoFileName, "-i", audioFileName, "-c", "copy", mergedFileName)
+
+	start := time.Now()
+	err = cmd.Run()
+	metrics.ObserveFfmpeg(metrics.FfmpegMerge, start, err)
+	if err != nil {
+		os.Remove(mergedFileName)
 		return fmt.Errorf("%w (to file: %s): %s", domain.ErrMerging, mergedFileName, err)
 	}
 
 	return nil
 }
+
+// DownloadSubtitles saves the caption tracks of the video in langs as WebVTT
+// next to the video at realPath. A track written by the uploader is preferred
+// to the one generated by speech recognition, which is only taken when auto
+// is set. Languages without a track, or whose track can't be downloaded, are
+// skipped.
+func (s YouTubeDownloadStrategy) DownloadSubtitles(ctx context.Context, videoURL string, realPath string, langs []string, auto bool) (_ []domain.Subtitle, err error) {
+	ctx, span := tracing.Start(ctx, "YouTubeDownloadStrategy.DownloadSubtitles")
+	defer tracing.End(span, &err)
+
+	videoID, err := s.getVideoID(videoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	video, err := s.fetchVideoMetadata(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	var subtitles []domain.Subtitle
+	for _, lang := range langs {
+		track, ok := s.selectCaptionTrack(video, lang, auto)
+		if !ok {
+			logger.FromContext(ctx).WithField("lang", lang).Info(subtitleNotAvailable)
+			continue
+		}
+
+		if err := s.downloadCaptionTrack(ctx, track, filepath.Join(s.VideoDir, domain.SubtitlePath(realPath, lang))); err != nil {
+			logger.FromContext(ctx).WithError(err).WithField("lang", lang).Warn(downloadingSubtitle)
+			continue
+		}
+
+		subtitles = append(subtitles, domain.Subtitle{
+			Lang:  lang,
+			Label: track.Name.SimpleText,
+			Auto:  track.Kind == autoCaptionTrackKind,
+		})
+	}
+
+	return subtitles, nil
+}
+
+func (s YouTubeDownloadStrategy) selectCaptionTrack(video *youtube.Video, lang string, auto bool) (youtube.CaptionTrack, bool) {
+	var autoTrack *youtube.CaptionTrack
+
+	for i, track := range video.CaptionTracks {
+		if !strings.EqualFold(track.LanguageCode, lang) {
+			continue
+		}
+
+		if track.Kind != autoCaptionTrackKind {
+			return track, true
+		}
+
+		if auto && autoTrack == nil {
+			autoTrack = &video.CaptionTracks[i]
+		}
+	}
+
+	if autoTrack == nil {
+		return youtube.CaptionTrack{}, false
+	}
+
+	return *autoTrack, true
+}
+
+func (s YouTubeDownloadStrategy) downloadCaptionTrack(ctx context.Context, track youtube.CaptionTrack, filePath string) (err error) {
+	ctx, span := tracing.Start(ctx, "YouTubeDownloadStrategy.downloadCaptionTrack", trace.WithAttributes(attribute.String("youtube.lang", track.LanguageCode)))
+	defer tracing.End(span, &err)
+
+	trackURL, err := url.Parse(track.BaseURL)
+	if err != nil {
+		return fmt.Errorf("%w (track url: %s): %s", domain.ErrParsingURL, track.BaseURL, err)
+	}
+
+	// youtube converts the track to WebVTT itself
+	query := trackURL.Query()
+	query.Set("fmt", captionTrackFormat)
+	trackURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, trackURL.String(), nil)
+	if err != nil {
+		return fmt.Errorf("%w (lang: %s): %s", domain.ErrDownloadingSubtitles, track.LanguageCode, err)
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w (lang: %s): %s", domain.ErrDownloadingSubtitles, track.LanguageCode, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w (lang: %s, status code: %d)", domain.ErrDownloadingSubtitles, track.LanguageCode, res.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, domain.MaxSubtitleSize+1))
+	if err != nil {
+		return fmt.Errorf("%w (lang: %s): %s", domain.ErrDownloadingSubtitles, track.LanguageCode, err)
+	}
+
+	if len(data) > domain.MaxSubtitleSize || !domain.ValidWebVTT(data) {
+		return fmt.Errorf("%w (lang: %s)", domain.ErrInvalidSubtitle, track.LanguageCode)
+	}
+
+	return common.CreateAndWriteFile(filePath, io.NopCloser(bytes.NewReader(data)))
+}
+
+// DownloadChapters returns the chapters listed in the description of the
+// video, youtube doesn't provide them in the metadata.
+func (s YouTubeDownloadStrategy) DownloadChapters(ctx context.Context, videoURL string) (_ []domain.Chapter, err error) {
+	ctx, span := tracing.Start(ctx, "YouTubeDownloadStrategy.DownloadChapters")
+	defer tracing.End(span, &err)
+
+	videoID, err := s.getVideoID(videoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	video, err := s.fetchVideoMetadata(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.parseDescriptionChapters(video.Description, video.Duration), nil
+}
+
+// parseDescriptionChapters reads the lines of description that start with
+// a timestamp, e.g. "0:00 Intro" or "1:02:03 - Outro". Like youtube, it only
+// accepts a list starting at 0:00 with at least minDescriptionChapters
+// chapters in ascending order.
+func (s YouTubeDownloadStrategy) parseDescriptionChapters(description string, duration time.Duration) []domain.Chapter {
+	var chapters []domain.Chapter
+
+	for _, line := range strings.Split(description, "\n") {
+		match := descriptionChapter.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+
+		start, ok := parseTimestamp(match[1])
+		if !ok {
+			continue
+		}
+
+		if len(chapters) > 0 && start <= chapters[len(chapters)-1].Start {
+			return nil
+		}
+
+		chapters = append(chapters, domain.Chapter{Title: match[2], Start: start})
+	}
+
+	if len(chapters) < minDescriptionChapters || chapters[0].Start != 0 {
+		return nil
+	}
+
+	domain.CloseChapters(chapters, duration)
+
+	return chapters
+}
+
+// parseTimestamp parses a [h:]mm:ss timestamp.
+func parseTimestamp(timestamp string) (time.Duration, bool) {
+	var total time.Duration
+
+	for i, part := range strings.Split(timestamp, ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil || i > 0 && n >= 60 {
+			return 0, false
+		}
+
+		total = total*60 + time.Duration(n)
+	}
+
+	return total * time.Second, true
+}
@@ -0,0 +1,188 @@
+package subtitles_service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+	"video-downloader-server/internal/delivery/dto/video_dto"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/tracing"
+)
+
+const srtTimingSeparator = "-->"
+
+type Videos interface {
+	Authorize(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, role string) (domain.Video, error)
+}
+
+type VideosRepo interface {
+	SetSubtitles(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, subtitles []domain.Subtitle) error
+}
+
+// SubtitlesService stores the uploaded subtitle tracks of videos and serves
+// every track, uploaded or downloaded with the video. Tracks are WebVTT files
+// next to the video file.
+type SubtitlesService struct {
+	videosService Videos
+	repo          VideosRepo
+	videoDir      string
+}
+
+func NewSubtitlesService(videosService Videos, repo VideosRepo, videoDir string) *SubtitlesService {
+	return &SubtitlesService{
+		videosService: videosService,
+		repo:          repo,
+		videoDir:      videoDir,
+	}
+}
+
+// Upload saves a .srt or .vtt file as the track of the video in the language
+// of the input, replacing the existing one. SubRip files are converted to
+// WebVTT.
+func (s *SubtitlesService) Upload(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, uploadSubtitleInput video_dto.UploadSubtitleDto, fileName string, file io.Reader) (_ video_dto.SubtitleDto, err error) {
+	ctx, span := tracing.Start(ctx, "SubtitlesService.Upload")
+	defer tracing.End(span, &err)
+
+	video, err := s.videosService.Authorize(ctx, userID, videoID, domain.FolderContributor)
+	if err != nil {
+		return video_dto.SubtitleDto{}, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, domain.MaxSubtitleSize+1))
+	if err != nil {
+		return video_dto.SubtitleDto{}, fmt.Errorf("%w (file: %s): %s", domain.ErrSavingSubtitle, fileName, err)
+	}
+
+	if len(data) > domain.MaxSubtitleSize || !utf8.Valid(data) {
+		return video_dto.SubtitleDto{}, fmt.Errorf("%w (file: %s)", domain.ErrInvalidSubtitle, fileName)
+	}
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case domain.SubtitleFormat:
+		if !domain.ValidWebVTT(data) {
+			return video_dto.SubtitleDto{}, fmt.Errorf("%w (file: %s)", domain.ErrInvalidSubtitle, fileName)
+		}
+	case domain.SrtSubtitleFormat:
+		if data, err = srtToVtt(data); err != nil {
+			return video_dto.SubtitleDto{}, fmt.Errorf("%w (file: %s)", err, fileName)
+		}
+	default:
+		return video_dto.SubtitleDto{}, fmt.Errorf("%w (file: %s)", domain.ErrUnsupportedSubtitleFormat, fileName)
+	}
+
+	subtitle := domain.Subtitle{Lang: uploadSubtitleInput.Lang, Label: uploadSubtitleInput.Label}
+	if subtitle.Label == "" {
+		subtitle.Label = subtitle.Lang
+	}
+
+	subtitlePath := filepath.Join(s.videoDir, domain.SubtitlePath(video.RealPath, subtitle.Lang))
+	if err := writeFile(subtitlePath, data); err != nil {
+		return video_dto.SubtitleDto{}, err
+	}
+
+	subtitles := slices.DeleteFunc(slices.Clone(video.Subtitles), func(other domain.Subtitle) bool {
+		return other.Lang == subtitle.Lang
+	})
+	subtitles = append(subtitles, subtitle)
+
+	if err := s.repo.SetSubtitles(ctx, video.OwnerID, video.ID, subtitles); err != nil {
+		return video_dto.SubtitleDto{}, fmt.Errorf("%w (video id: %s): %s", domain.ErrSavingSubtitle, video.ID.Hex(), err)
+	}
+
+	return video_dto.SubtitleDto{Lang: subtitle.Lang, Label: subtitle.Label, Auto: subtitle.Auto}, nil
+}
+
+// GetFile opens the lang track of the video.
+func (s *SubtitlesService) GetFile(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, lang string) (_ video_dto.VideoFileInfoDto, err error) {
+	ctx, span := tracing.Start(ctx, "SubtitlesService.GetFile")
+	defer tracing.End(span, &err)
+
+	video, err := s.videosService.Authorize(ctx, userID, videoID, domain.FolderViewer)
+	if err != nil {
+		return video_dto.VideoFileInfoDto{}, err
+	}
+
+	if !slices.ContainsFunc(video.Subtitles, func(subtitle domain.Subtitle) bool { return subtitle.Lang == lang }) {
+		return video_dto.VideoFileInfoDto{}, fmt.Errorf("%w (video id: %s, lang: %s)", domain.ErrSubtitleNotFound, videoID.Hex(), lang)
+	}
+
+	filePath := filepath.Join(s.videoDir, domain.SubtitlePath(video.RealPath, lang))
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return video_dto.VideoFileInfoDto{}, fmt.Errorf("%w (video id: %s, lang: %s): %s", domain.ErrSubtitleNotFound, videoID.Hex(), lang, err)
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return video_dto.VideoFileInfoDto{}, fmt.Errorf("%w (path: %s): %s", domain.ErrGettingFileInfo, filePath, err)
+	}
+
+	return video_dto.VideoFileInfoDto{
+		VideoName:   filepath.Base(filePath),
+		FileSize:    fileInfo.Size(),
+		ModTime:     fileInfo.ModTime(),
+		ContentType: domain.SubtitleContentType,
+		VideoFile:   file,
+	}, nil
+}
+
+// srtToVtt converts a SubRip file to WebVTT. The cue numbers are kept as cue
+// identifiers and the comma before the milliseconds of the timings becomes
+// a dot.
+func srtToVtt(data []byte) ([]byte, error) {
+	lines := strings.Split(strings.ReplaceAll(string(domain.TrimBOM(data)), "\r\n", "\n"), "\n")
+
+	var cues int
+	for i, line := range lines {
+		if strings.Contains(line, srtTimingSeparator) {
+			lines[i] = strings.ReplaceAll(line, ",", ".")
+			cues++
+		}
+	}
+
+	if cues == 0 {
+		return nil, domain.ErrInvalidSubtitle
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(domain.WebVTTHeader + "\n\n")
+	buf.WriteString(strings.TrimLeft(strings.Join(lines, "\n"), "\n"))
+
+	return buf.Bytes(), nil
+}
+
+// writeFile replaces the file at path with data at once, so that a player
+// reading the previous track never gets a partial one.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("%w (path: %s): %s", domain.ErrSavingSubtitle, path, err)
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("%w (path: %s): %s", domain.ErrSavingSubtitle, path, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("%w (path: %s): %s", domain.ErrSavingSubtitle, path, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("%w (path: %s): %s", domain.ErrSavingSubtitle, path, err)
+	}
+
+	return nil
+}
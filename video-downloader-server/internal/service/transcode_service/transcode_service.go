@@ -0,0 +1,142 @@
+package transcode_service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/metrics"
+	"video-downloader-server/internal/service/common"
+	"video-downloader-server/internal/tracing"
+)
+
+const (
+	progressOutTime = "out_time_us="
+	progressEnd     = "progress=end"
+)
+
+type TranscodeService struct {
+	videoDir    string
+	ffmpegPath  string
+	ffprobePath string
+}
+
+func NewTranscodeService(videoDir, ffmpegPath, ffprobePath string) *TranscodeService {
+	return &TranscodeService{
+		videoDir:    videoDir,
+		ffmpegPath:  ffmpegPath,
+		ffprobePath: ffprobePath,
+	}
+}
+
+// Transcode encodes the video at realPath with profile into a new file in
+// the video directory and returns its real path. The progress parsed from
+// ffmpeg is published to the reporter of the running job.
+func (t *TranscodeService) Transcode(ctx context.Context, realPath string, profile domain.TranscodeProfile) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "TranscodeService.Transcode")
+	defer tracing.End(span, &err)
+
+	dir, err := common.CreateRandomDir(t.videoDir)
+	if err != nil {
+		return "", err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(realPath), filepath.Ext(realPath))
+	newRealPath := filepath.Join(dir, name+"_"+profile.Name+profile.Container)
+
+	// a video of unknown duration is transcoded without progress
+	duration, _ := t.getVideoDuration(ctx, filepath.Join(t.videoDir, realPath))
+
+	if err := t.encode(ctx, filepath.Join(t.videoDir, realPath), filepath.Join(t.videoDir, newRealPath), profile, duration); err != nil {
+		os.Remove(filepath.Join(t.videoDir, newRealPath))
+		return "", err
+	}
+
+	return newRealPath, nil
+}
+
+func (t *TranscodeService) getVideoDuration(ctx context.Context, videoPath string) (_ time.Duration, err error) {
+	ctx, span := tracing.StartProcess(ctx, metrics.FfmpegProbe, t.ffprobePath)
+	defer tracing.End(span, &err)
+
+	cmd := common.Command(ctx, t.ffprobePath, "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", videoPath)
+	start := time.Now()
+	output, err := cmd.Output()
+	metrics.ObserveFfmpeg(metrics.FfmpegProbe, start, err)
+	if err != nil {
+		return 0, fmt.Errorf("%w (video path: %s): %s", domain.ErrGettingVideoDuration, videoPath, err)
+	}
+
+	durationSeconds, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w (video path: %s): %s", domain.ErrParsingVideoDuration, videoPath, err)
+	}
+
+	return time.Duration(durationSeconds * float64(time.Second)), nil
+}
+
+// encode runs ffmpeg with -progress on stdout and reports the encoded time
+// against duration as it goes.
+func (t *TranscodeService) encode(ctx context.Context, videoPath, outPath string, profile domain.TranscodeProfile, duration time.Duration) (err error) {
+	ctx, span := tracing.StartProcess(ctx, metrics.FfmpegTranscode, t.ffmpegPath)
+	defer tracing.End(span, &err)
+
+	args := []string{
+		"-v", "error", "-nostats", "-progress", "pipe:1", "-y",
+		"-i", videoPath,
+		"-map", "0:v:0", "-map", "0:a:0?",
+		"-vf", fmt.Sprintf(`scale=-2:min(%d\,ih)`, profile.Height),
+		"-c:v", profile.VideoCodec,
+		"-c:a", profile.AudioCodec,
+	}
+	args = append(args, profile.Args...)
+	args = append(args, outPath)
+
+	cmd := common.Command(ctx, t.ffmpegPath, args...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("%w (video path: %s): %s", domain.ErrTranscodingVideo, videoPath, err)
+	}
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		metrics.ObserveFfmpeg(metrics.FfmpegTranscode, start, err)
+		return fmt.Errorf("%w (video path: %s): %s", domain.ErrTranscodingVideo, videoPath, err)
+	}
+
+	reporter := domain.JobReporterFromContext(ctx)
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case line == progressEnd:
+			reporter.SetProgress(1)
+		case strings.HasPrefix(line, progressOutTime) && duration > 0:
+			if outTime, err := strconv.ParseInt(strings.TrimPrefix(line, progressOutTime), 10, 64); err == nil {
+				reporter.SetProgress(float64(time.Duration(outTime)*time.Microsecond) / float64(duration))
+			}
+		}
+	}
+	// keep ffmpeg from blocking on a full pipe if scanning stopped early
+	io.Copy(io.Discard, stdout)
+
+	err = cmd.Wait()
+	metrics.ObserveFfmpeg(metrics.FfmpegTranscode, start, err)
+	if err != nil {
+		return fmt.Errorf("%w (ffmpeg output: %s): %s", domain.ErrTranscodingVideo, stderr.String(), err)
+	}
+
+	return nil
+}
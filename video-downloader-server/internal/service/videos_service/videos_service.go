@@ -4,239 +4,752 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	log "github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"os"
 	"path/filepath"
-	"strconv"
-	"strings"
+	"time"
+	"video-downloader-server/internal/delivery/dto/job_dto"
 	"video-downloader-server/internal/delivery/dto/video_dto"
 	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/logger"
+	"video-downloader-server/internal/metrics"
+	"video-downloader-server/internal/service/common"
 	"video-downloader-server/internal/service/strategies"
+	"video-downloader-server/internal/tracing"
 )
 
+const (
+	videoSaved           = "downloaded video has been saved"
+	transcodedVideoSaved = "transcoded video has been saved"
+	submittingTranscode  = "error submitting transcode job of downloaded video"
+	savingSubtitles      = "error saving subtitles of downloaded video"
+	subtitlesUnsupported = "subtitles can't be downloaded with the strategy"
+	savingChapters       = "error saving chapters of downloaded video"
+	splitVideoSaved      = "chapter of split video has been saved"
+	restoringReplaced    = "error restoring the name of the video that was to be overwritten"
+	deletingReplaced     = "error deleting overwritten video"
+)
+
+// replacedNameFormat is the temporary name of a video being overwritten, from
+// its name and ID, so that it doesn't hold the name while the new video is
+// saved.
+const replacedNameFormat = "%s.replaced-%s"
+
 type VideosRepo interface {
-	Create(ctx context.Context, video domain.Video) error
-	GetRealPath(ctx context.Context, videoID primitive.ObjectID) (string, error)
-	CheckExistByID(ctx context.Context, videoID primitive.ObjectID) error
-	Rename(ctx context.Context, videoID primitive.ObjectID, newVideoName string) error
-	Move(ctx context.Context, videoID primitive.ObjectID, folderID primitive.ObjectID) error
-	Delete(ctx context.Context, videoID primitive.ObjectID) error
-	GetPathsByFolders(ctx context.Context, foldersID []primitive.ObjectID) ([]string, []string, error)
-	DeleteVideos(ctx context.Context, foldersID []primitive.ObjectID) error
-	GetVideos(ctx context.Context, folderID primitive.ObjectID) ([]domain.Video, error)
+	GetByID(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID) (domain.Video, error)
+	GetByName(ctx context.Context, ownerID primitive.ObjectID, videoName string, folderID primitive.ObjectID) (domain.Video, error)
+	Rename(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, newVideoName string) error
+	Move(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, folderID primitive.ObjectID, videoName string) error
+	SetSubtitles(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, subtitles []domain.Subtitle) error
+	SetChapters(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, chapters []domain.Chapter) error
+	GetPathsByFolders(ctx context.Context, ownerID primitive.ObjectID, foldersID []primitive.ObjectID) ([]string, []string, error)
+	GetVideos(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) ([]domain.Video, error)
+	GetAll(ctx context.Context) ([]domain.Video, error)
+	Lookup(ctx context.Context, videoID primitive.ObjectID) (domain.Video, error)
+}
+
+type FoldersRepo interface {
+	CheckExistenceByName(ctx context.Context, ownerID primitive.ObjectID, folderName string, parentDirID primitive.ObjectID) error
+	Create(ctx context.Context, ownerID primitive.ObjectID, folderName string, parentDirID primitive.ObjectID) (primitive.ObjectID, error)
+}
+
+type Access interface {
+	Require(ctx context.Context, userID primitive.ObjectID, folderID primitive.ObjectID, role string) (primitive.ObjectID, error)
 }
 
 type Preview interface {
-	CreatePreview(videoName string, realPath string) (string, error)
-	DeletePreviews(paths []string) error
+	CreatePreview(ctx context.Context, videoName string, realPath string) (string, error)
+	CopyPreview(previewPath string) (string, error)
+	RemovePreview(previewPath string)
+	OpenPreview(previewPath string) (video_dto.VideoFileInfoDto, error)
+}
+
+type Intents interface {
+	CreateVideo(ctx context.Context, video domain.Video) (primitive.ObjectID, error)
+	Delete(ctx context.Context, videoIDs []primitive.ObjectID, foldersID []primitive.ObjectID, videoPaths []string, previewPaths []string) error
+}
+
+type Jobs interface {
+	Submit(ctx context.Context, ownerID primitive.ObjectID, jobType, source string, run domain.JobFunc) (job_dto.JobDto, error)
+}
+
+type Transcoder interface {
+	Transcode(ctx context.Context, realPath string, profile domain.TranscodeProfile) (string, error)
+}
+
+type Chapters interface {
+	Probe(ctx context.Context, realPath string) ([]domain.Chapter, error)
+	Cut(ctx context.Context, realPath string, chapter domain.Chapter, n int) (string, error)
 }
 
 type VideoDownloadStrategy interface {
-	Download(videoURL string, quality string) (string, string, error)
+	Download(ctx context.Context, videoURL string, quality string) (string, string, error)
+}
+
+// SubtitlesDownloadStrategy is implemented by the strategies that can save
+// the caption tracks of the videos they download.
+type SubtitlesDownloadStrategy interface {
+	DownloadSubtitles(ctx context.Context, videoURL string, realPath string, langs []string, auto bool) ([]domain.Subtitle, error)
+}
+
+// ChaptersDownloadStrategy is implemented by the strategies that can list
+// the chapters of the videos they download when the file has none.
+type ChaptersDownloadStrategy interface {
+	DownloadChapters(ctx context.Context, videoURL string) ([]domain.Chapter, error)
 }
 
 type VideosService struct {
 	repo           VideosRepo
+	foldersRepo    FoldersRepo
+	sharingService Access
 	previewService Preview
-	strategy       VideoDownloadStrategy
+	intentsService Intents
+	jobsService    Jobs
+	conflictPolicy string
+	maxNameSuffix  int
+
+	transcodeService Transcoder
+	transcodeJobs    Jobs
+	profiles         map[string]domain.TranscodeProfile
+
+	chaptersService Chapters
+
+	videoDir   string
+	ffmpegPath string
 }
 
-func NewVideosService(repo VideosRepo, previewService Preview) *VideosService {
+func NewVideosService(repo VideosRepo, foldersRepo FoldersRepo, sharingService Access, previewService Preview, intentsService Intents, jobsService Jobs, transcodeService Transcoder, transcodeJobs Jobs, chaptersService Chapters, profiles map[string]domain.TranscodeProfile, conflictPolicy string, maxNameSuffix int, videoDir, ffmpegPath string) *VideosService {
 	return &VideosService{
-		repo:           repo,
-		previewService: previewService,
+		repo:             repo,
+		foldersRepo:      foldersRepo,
+		sharingService:   sharingService,
+		previewService:   previewService,
+		intentsService:   intentsService,
+		jobsService:      jobsService,
+		conflictPolicy:   conflictPolicy,
+		maxNameSuffix:    maxNameSuffix,
+		transcodeService: transcodeService,
+		transcodeJobs:    transcodeJobs,
+		profiles:         profiles,
+		chaptersService:  chaptersService,
+		videoDir:         videoDir,
+		ffmpegPath:       ffmpegPath,
 	}
 }
 
-func (v *VideosService) setVideoDownloadStrategy(strategy VideoDownloadStrategy) {
-	v.strategy = strategy
-}
-
-func (v *VideosService) DownloadToServer(downloadVideoInput video_dto.DownloadVideoDto) error {
-	switch downloadVideoInput.Type {
+func (v *VideosService) videoDownloadStrategy(videoType string) VideoDownloadStrategy {
+	switch videoType {
 	case domain.YouTubeVideoType:
-		v.setVideoDownloadStrategy(strategies.YouTubeDownloadStrategy{})
+		return strategies.YouTubeDownloadStrategy{VideoDir: v.videoDir, FfmpegPath: v.ffmpegPath}
 	default:
-		v.setVideoDownloadStrategy(strategies.GeneralDownloadStrategy{})
+		return strategies.GeneralDownloadStrategy{VideoDir: v.videoDir}
+	}
+}
+
+// DownloadToServer queues the download and returns the job tracking it. The
+// job belongs to userID, the video to the owner of the folder. With a
+// transcode profile the finished download queues a transcode job, linked as
+// the next job of the download.
+func (v *VideosService) DownloadToServer(ctx context.Context, userID primitive.ObjectID, downloadVideoInput video_dto.DownloadVideoDto) (_ job_dto.JobDto, err error) {
+	ctx, span := tracing.Start(ctx, "VideosService.DownloadToServer")
+	defer tracing.End(span, &err)
+
+	if downloadVideoInput.TranscodeProfile != "" {
+		if _, err := v.transcodeProfile(downloadVideoInput.TranscodeProfile); err != nil {
+			return job_dto.JobDto{}, err
+		}
 	}
 
-	videoName, realPath, err := v.strategy.Download(downloadVideoInput.VideoURL, downloadVideoInput.Quality)
+	ownerID, err := v.sharingService.Require(ctx, userID, downloadVideoInput.FolderID, domain.FolderContributor)
 	if err != nil {
-		return err
+		return job_dto.JobDto{}, err
+	}
+
+	return v.jobsService.Submit(ctx, userID, domain.JobDownload, downloadVideoInput.VideoURL, func(ctx context.Context) (primitive.ObjectID, error) {
+		start := time.Now()
+		videoID, err := v.download(ctx, ownerID, downloadVideoInput)
+		metrics.ObserveDownload(downloadVideoInput.Type, start, err, ctx.Err())
+		if err != nil || downloadVideoInput.TranscodeProfile == "" {
+			return videoID, err
+		}
+
+		// the download is done whether or not the transcode can be queued
+		job, err := v.Transcode(ctx, userID, videoID, video_dto.TranscodeVideoDto{Profile: downloadVideoInput.TranscodeProfile})
+		if err != nil {
+			logger.FromContext(ctx).WithError(err).Warn(submittingTranscode)
+			return videoID, nil
+		}
+		domain.JobReporterFromContext(ctx).SetNextJob(job.ID)
+
+		return videoID, nil
+	})
+}
+
+// Transcode queues the encoding of videoID with a configured profile and
+// returns the job tracking it. The transcoded video is saved next to the
+// original, under the name of the original suffixed with the profile.
+func (v *VideosService) Transcode(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, transcodeVideoInput video_dto.TranscodeVideoDto) (_ job_dto.JobDto, err error) {
+	ctx, span := tracing.Start(ctx, "VideosService.Transcode")
+	defer tracing.End(span, &err)
+
+	profile, err := v.transcodeProfile(transcodeVideoInput.Profile)
+	if err != nil {
+		return job_dto.JobDto{}, err
 	}
 
-	previewPath, err := v.previewService.CreatePreview(videoName, realPath)
+	video, err := v.getVideo(ctx, userID, videoID, domain.FolderContributor)
 	if err != nil {
-		return err
+		return job_dto.JobDto{}, err
 	}
 
-	if err := v.repo.Create(context.Background(), domain.Video{
-		VideoName:   videoName,
-		FolderID:    downloadVideoInput.FolderID,
-		RealPath:    realPath,
-		PreviewPath: previewPath,
-	}); err != nil {
-		return fmt.Errorf("%w (video name: %s): %s", domain.ErrSavingVideoToDb, videoName, err)
+	return v.transcodeJobs.Submit(ctx, userID, domain.JobTranscode, video.ID.Hex(), func(ctx context.Context) (primitive.ObjectID, error) {
+		return v.transcode(ctx, video, profile)
+	})
+}
+
+func (v *VideosService) transcodeProfile(name string) (domain.TranscodeProfile, error) {
+	profile, ok := v.profiles[name]
+	if !ok {
+		return domain.TranscodeProfile{}, fmt.Errorf("%w (profile: %s)", domain.ErrUnknownTranscodeProfile, name)
 	}
 
-	return nil
+	return profile, nil
+}
+
+func (v *VideosService) transcode(ctx context.Context, video domain.Video, profile domain.TranscodeProfile) (_ primitive.ObjectID, err error) {
+	ctx, span := tracing.Start(ctx, "VideosService.transcode")
+	defer tracing.End(span, &err)
+
+	ctx = logger.WithFields(ctx, log.Fields{
+		logger.VideoIDField:  video.ID.Hex(),
+		logger.FolderIDField: video.FolderID.Hex(),
+	})
+
+	realPath, err := v.transcodeService.Transcode(ctx, video.RealPath, profile)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	videoName := fmt.Sprintf("%s (%s)", video.VideoName, profile.Name)
+	videoID, err := v.save(ctx, video.OwnerID, videoName, video.FolderID, realPath)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	// the timeline of the video is the same after transcoding
+	if len(video.Chapters) > 0 {
+		if err := v.repo.SetChapters(ctx, video.OwnerID, videoID, video.Chapters); err != nil {
+			return primitive.NilObjectID, fmt.Errorf("%w (video id: %s): %s", domain.ErrSavingChapters, videoID.Hex(), err)
+		}
+	}
+
+	logger.FromContext(ctx).WithField(logger.VideoIDField, videoID.Hex()).Info(transcodedVideoSaved)
+
+	return videoID, nil
 }
 
-func (v *VideosService) GetVideoFileInfo(videoID primitive.ObjectID) (video_dto.VideoFileInfoDto, error) {
-	videoRealPath, err := v.repo.GetRealPath(context.Background(), videoID)
+func (v *VideosService) download(ctx context.Context, ownerID primitive.ObjectID, downloadVideoInput video_dto.DownloadVideoDto) (_ primitive.ObjectID, err error) {
+	ctx, span := tracing.Start(ctx, "VideosService.download")
+	defer tracing.End(span, &err)
+
+	ctx = logger.WithFields(ctx, log.Fields{
+		logger.FolderIDField: downloadVideoInput.FolderID.Hex(),
+		logger.StrategyField: downloadVideoInput.Type,
+	})
+	strategy := v.videoDownloadStrategy(downloadVideoInput.Type)
+
+	videoName, realPath, err := strategy.Download(ctx, downloadVideoInput.VideoURL, downloadVideoInput.Quality)
 	if err != nil {
-		return video_dto.VideoFileInfoDto{}, fmt.Errorf("%w (video id: %s): %s", domain.ErrGettingRealVideoPath, videoID, err)
+		return primitive.NilObjectID, err
+	}
+
+	if info, err := os.Stat(filepath.Join(v.videoDir, realPath)); err == nil {
+		metrics.DownloadedBytes.WithLabelValues(downloadVideoInput.Type).Add(float64(info.Size()))
 	}
 
-	videoFile, err := os.Open(filepath.Join(domain.CommonVideoDir, videoRealPath))
+	videoID, err := v.save(ctx, ownerID, videoName, downloadVideoInput.FolderID, realPath)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	logger.FromContext(ctx).WithField(logger.VideoIDField, videoID.Hex()).Info(videoSaved)
+
+	v.downloadChapters(ctx, strategy, ownerID, videoID, realPath, downloadVideoInput.VideoURL)
+
+	if len(downloadVideoInput.Subtitles) > 0 {
+		v.downloadSubtitles(ctx, strategy, ownerID, videoID, realPath, downloadVideoInput)
+	}
+
+	return videoID, nil
+}
+
+// downloadSubtitles saves the requested caption tracks of a downloaded video.
+// The video is kept whether or not they can be saved.
+func (v *VideosService) downloadSubtitles(ctx context.Context, strategy VideoDownloadStrategy, ownerID primitive.ObjectID, videoID primitive.ObjectID, realPath string, downloadVideoInput video_dto.DownloadVideoDto) {
+	subtitlesStrategy, ok := strategy.(SubtitlesDownloadStrategy)
+	if !ok {
+		logger.FromContext(ctx).Warn(subtitlesUnsupported)
+		return
+	}
+
+	subtitles, err := subtitlesStrategy.DownloadSubtitles(ctx, downloadVideoInput.VideoURL, realPath, downloadVideoInput.Subtitles, downloadVideoInput.AutoSubtitles)
+	if err == nil && len(subtitles) > 0 {
+		err = v.repo.SetSubtitles(ctx, ownerID, videoID, subtitles)
+	}
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Warn(savingSubtitles)
+	}
+}
+
+// downloadChapters saves the chapters of a downloaded video, read from the
+// file or else listed by the strategy. The video is kept whether or not they
+// can be saved.
+func (v *VideosService) downloadChapters(ctx context.Context, strategy VideoDownloadStrategy, ownerID primitive.ObjectID, videoID primitive.ObjectID, realPath string, videoURL string) {
+	chapters, err := v.chaptersService.Probe(ctx, realPath)
+	if err == nil && len(chapters) == 0 {
+		if chaptersStrategy, ok := strategy.(ChaptersDownloadStrategy); ok {
+			chapters, err = chaptersStrategy.DownloadChapters(ctx, videoURL)
+		}
+	}
+	if err == nil && len(chapters) > 0 {
+		err = v.repo.SetChapters(ctx, ownerID, videoID, chapters)
+	}
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Warn(savingChapters)
+	}
+}
+
+// save adds the file at realPath to folderID as videoName, resolving name
+// conflicts and creating its preview. The file is removed when the video
+// cannot be saved.
+func (v *VideosService) save(ctx context.Context, ownerID primitive.ObjectID, videoName string, folderID primitive.ObjectID, realPath string) (_ primitive.ObjectID, err error) {
+	// the files are removed when saving fails before CreateVideo takes them
+	// over, it removes them itself when the video can't be saved
+	var previewPath string
+	owned := false
+	defer func() {
+		if err != nil && !owned {
+			os.Remove(filepath.Join(v.videoDir, realPath))
+
+			if previewPath != "" {
+				v.previewService.RemovePreview(previewPath)
+			}
+		}
+	}()
+
+	videoName, replaced, err := v.resolveVideoName(ctx, ownerID, videoName, folderID, primitive.NilObjectID)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	previewPath, err = v.previewService.CreatePreview(ctx, videoName, realPath)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	var videoID primitive.ObjectID
+	err = v.replace(ctx, replaced, func() error {
+		owned = true
+		videoID, err = v.intentsService.CreateVideo(ctx, domain.Video{
+			OwnerID:     ownerID,
+			VideoName:   videoName,
+			FolderID:    folderID,
+			RealPath:    realPath,
+			PreviewPath: previewPath,
+		})
+		if err != nil {
+			if errors.Is(err, domain.ErrDuplicateKey) {
+				return fmt.Errorf("%w (video name: %s, folder id: %s)", domain.ErrVideoAlreadyExist, videoName, folderID)
+			}
+
+			return fmt.Errorf("%w (video name: %s): %s", domain.ErrSavingVideoToDb, videoName, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return videoID, nil
+}
+
+func (v *VideosService) GetVideoFileInfo(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID) (_ video_dto.VideoFileInfoDto, err error) {
+	ctx, span := tracing.Start(ctx, "VideosService.GetVideoFileInfo")
+	defer tracing.End(span, &err)
+
+	video, err := v.getVideo(ctx, userID, videoID, domain.FolderViewer)
+	if err != nil {
+		return video_dto.VideoFileInfoDto{}, err
+	}
+
+	return v.OpenVideo(ctx, video)
+}
+
+// GetPreviewFileInfo opens the preview image of videoID.
+func (v *VideosService) GetPreviewFileInfo(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID) (_ video_dto.VideoFileInfoDto, err error) {
+	ctx, span := tracing.Start(ctx, "VideosService.GetPreviewFileInfo")
+	defer tracing.End(span, &err)
+
+	video, err := v.getVideo(ctx, userID, videoID, domain.FolderViewer)
+	if err != nil {
+		return video_dto.VideoFileInfoDto{}, err
+	}
+
+	return v.previewService.OpenPreview(video.PreviewPath)
+}
+
+// OpenVideo opens the file of a video the caller already has access to.
+func (v *VideosService) OpenVideo(ctx context.Context, video domain.Video) (_ video_dto.VideoFileInfoDto, err error) {
+	_, span := tracing.Start(ctx, "VideosService.OpenVideo")
+	defer tracing.End(span, &err)
+
+	videoRealPath := video.RealPath
+
+	videoFile, err := os.Open(filepath.Join(v.videoDir, videoRealPath))
 	if err != nil {
 		return video_dto.VideoFileInfoDto{}, fmt.Errorf("%w (video path: %s): %s", domain.ErrVideoNotFound, videoRealPath, err)
 	}
 
 	fileInfo, err := videoFile.Stat()
 	if err != nil {
+		videoFile.Close()
 		return video_dto.VideoFileInfoDto{}, fmt.Errorf("%w (video path: %s): %s", domain.ErrGettingFileInfo, videoRealPath, err)
 	}
 
 	_, videoName := filepath.Split(videoRealPath)
 
 	return video_dto.VideoFileInfoDto{
-		VideoName: videoName,
-		FileSize:  fileInfo.Size(),
-		VideoFile: videoFile,
+		VideoName:   videoName,
+		FileSize:    fileInfo.Size(),
+		ModTime:     fileInfo.ModTime(),
+		ContentType: domain.VideoContentType(videoName),
+		VideoFile:   videoFile,
 	}, nil
 }
 
-func (v *VideosService) GetVideoRangeInfo(videoID primitive.ObjectID, rangeHeader string) (video_dto.VideoRangeInfoDto, error) {
-	videoFileInfo, err := v.GetVideoFileInfo(videoID)
+func (v *VideosService) Rename(ctx context.Context, userID primitive.ObjectID, renameVideoInput video_dto.RenameVideoDto) (_ video_dto.VideoDto, err error) {
+	ctx, span := tracing.Start(ctx, "VideosService.Rename")
+	defer tracing.End(span, &err)
 
-	rangeStart, rangeEnd, err := v.parseRangeHeader(rangeHeader, videoFileInfo.FileSize)
+	video, err := v.getVideo(ctx, userID, renameVideoInput.ID, domain.FolderContributor)
 	if err != nil {
-		return video_dto.VideoRangeInfoDto{}, fmt.Errorf("%w: %s", domain.ErrInvalidRangeHeader, err)
+		return video_dto.VideoDto{}, err
 	}
+	ownerID := video.OwnerID
 
-	return video_dto.VideoRangeInfoDto{
-		RangeStart: rangeStart,
-		RangeEnd:   rangeEnd,
-		VideoInfo:  videoFileInfo,
-	}, nil
-}
-
-func (v *VideosService) Rename(renameVideoInput video_dto.RenameVideoDto) (video_dto.VideoDto, error) {
-	if err := v.checkVideoExistenceByID(renameVideoInput.ID); err != nil {
+	videoName, replaced, err := v.resolveVideoName(ctx, ownerID, renameVideoInput.VideoName, video.FolderID, video.ID)
+	if err != nil {
 		return video_dto.VideoDto{}, err
 	}
 
-	if err := v.repo.Rename(context.Background(), renameVideoInput.ID, renameVideoInput.VideoName); err != nil {
-		return video_dto.VideoDto{}, fmt.Errorf("%w (video id: %s): %s", domain.ErrRenamingVideo, renameVideoInput.ID, err)
+	err = v.replace(ctx, replaced, func() error {
+		if err := v.repo.Rename(ctx, ownerID, renameVideoInput.ID, videoName); err != nil {
+			if errors.Is(err, domain.ErrDuplicateKey) {
+				return fmt.Errorf("%w (video name: %s, folder id: %s)", domain.ErrVideoAlreadyExist, videoName, video.FolderID)
+			}
+
+			return fmt.Errorf("%w (video id: %s): %s", domain.ErrRenamingVideo, renameVideoInput.ID, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return video_dto.VideoDto{}, err
 	}
 
 	return video_dto.VideoDto{
 		ID:        renameVideoInput.ID,
-		VideoName: renameVideoInput.VideoName,
+		VideoName: videoName,
 	}, nil
 }
 
-func (v *VideosService) Move(moveVideoInput video_dto.MoveVideoDto) (video_dto.VideoDto, error) {
-	if err := v.checkVideoExistenceByID(moveVideoInput.ID); err != nil {
+func (v *VideosService) Move(ctx context.Context, userID primitive.ObjectID, moveVideoInput video_dto.MoveVideoDto) (_ video_dto.VideoDto, err error) {
+	ctx, span := tracing.Start(ctx, "VideosService.Move")
+	defer tracing.End(span, &err)
+
+	video, err := v.getVideo(ctx, userID, moveVideoInput.ID, domain.FolderContributor)
+	if err != nil {
+		return video_dto.VideoDto{}, err
+	}
+	ownerID := video.OwnerID
+
+	folderOwnerID, err := v.sharingService.Require(ctx, userID, moveVideoInput.FolderID, domain.FolderContributor)
+	if err != nil {
 		return video_dto.VideoDto{}, err
 	}
 
-	if err := v.repo.Move(context.Background(), moveVideoInput.ID, moveVideoInput.FolderID); err != nil {
-		return video_dto.VideoDto{}, fmt.Errorf("%w (video id: %s): %s", domain.ErrMovingVideo, moveVideoInput.ID, err)
+	if folderOwnerID != ownerID {
+		return video_dto.VideoDto{}, fmt.Errorf("%w (video id: %s, folder id: %s)", domain.ErrMovingAcrossOwners, video.ID, moveVideoInput.FolderID)
+	}
+
+	videoName, replaced, err := v.resolveVideoName(ctx, ownerID, video.VideoName, moveVideoInput.FolderID, video.ID)
+	if err != nil {
+		return video_dto.VideoDto{}, err
+	}
+
+	err = v.replace(ctx, replaced, func() error {
+		if err := v.repo.Move(ctx, ownerID, moveVideoInput.ID, moveVideoInput.FolderID, videoName); err != nil {
+			if errors.Is(err, domain.ErrDuplicateKey) {
+				return fmt.Errorf("%w (video name: %s, folder id: %s)", domain.ErrVideoAlreadyExist, videoName, moveVideoInput.FolderID)
+			}
+
+			return fmt.Errorf("%w (video id: %s): %s", domain.ErrMovingVideo, moveVideoInput.ID, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return video_dto.VideoDto{}, err
 	}
 
 	return video_dto.VideoDto{
-		ID:       moveVideoInput.ID,
-		FolderID: moveVideoInput.FolderID,
+		ID:        moveVideoInput.ID,
+		VideoName: videoName,
+		FolderID:  moveVideoInput.FolderID,
 	}, nil
 }
 
-func (v *VideosService) Delete(deleteVideoInput video_dto.DeleteVideoDto) error {
-	if err := v.checkVideoExistenceByID(deleteVideoInput.ID); err != nil {
-		return err
+// Copy copies the video into a folder, which may belong to another user than
+// the video. The copy belongs to the owner of the folder.
+func (v *VideosService) Copy(ctx context.Context, userID primitive.ObjectID, copyVideoInput video_dto.CopyVideoDto) (_ video_dto.VideoDto, err error) {
+	ctx, span := tracing.Start(ctx, "VideosService.Copy")
+	defer tracing.End(span, &err)
+
+	video, err := v.getVideo(ctx, userID, copyVideoInput.ID, domain.FolderViewer)
+	if err != nil {
+		return video_dto.VideoDto{}, err
 	}
 
-	realPath, err := v.repo.GetRealPath(context.Background(), deleteVideoInput.ID)
+	ownerID, err := v.sharingService.Require(ctx, userID, copyVideoInput.FolderID, domain.FolderContributor)
 	if err != nil {
-		return fmt.Errorf("%w (video id: %s): %s", domain.ErrGettingRealVideoPath, deleteVideoInput.ID, err)
+		return video_dto.VideoDto{}, err
 	}
 
-	if err := os.Remove(filepath.Join(domain.CommonVideoDir, realPath)); err != nil {
-		return fmt.Errorf("%w (video path: %s): %s", domain.ErrDeletingVideo, realPath, err)
+	videoName, replaced, err := v.resolveVideoName(ctx, ownerID, video.VideoName, copyVideoInput.FolderID, primitive.NilObjectID)
+	if err != nil {
+		return video_dto.VideoDto{}, err
 	}
 
-	if err = v.repo.Delete(context.Background(), deleteVideoInput.ID); err != nil {
-		return fmt.Errorf("%w (video id: %s): %s", domain.ErrDeletingVideoFromDB, deleteVideoInput.ID, err)
+	realDir, err := common.CreateRandomDir(v.videoDir)
+	if err != nil {
+		return video_dto.VideoDto{}, err
 	}
 
-	return nil
-}
+	// the copied files are removed when the copy fails before CreateVideo
+	// takes them over, it removes them itself when the video can't be saved
+	var copied []string
+	var previewPath string
+	owned := false
+	defer func() {
+		if err != nil && !owned {
+			for _, path := range copied {
+				os.Remove(path)
+			}
+
+			if previewPath != "" {
+				v.previewService.RemovePreview(previewPath)
+			}
+		}
+	}()
+
+	realPath := filepath.Join(realDir, filepath.Base(video.RealPath))
+	copied = append(copied, filepath.Join(v.videoDir, realPath))
+	if err := common.CopyFile(filepath.Join(v.videoDir, video.RealPath), filepath.Join(v.videoDir, realPath)); err != nil {
+		return video_dto.VideoDto{}, fmt.Errorf("%w (video id: %s): %s", domain.ErrCopyingVideo, video.ID, err)
+	}
 
-func (v *VideosService) DeleteVideos(foldersID []primitive.ObjectID) error {
-	realPaths, previewPaths, err := v.repo.GetPathsByFolders(context.Background(), foldersID)
+	for _, subtitle := range video.Subtitles {
+		subtitlePath := filepath.Join(v.videoDir, domain.SubtitlePath(realPath, subtitle.Lang))
+		copied = append(copied, subtitlePath)
+		if err := common.CopyFile(filepath.Join(v.videoDir, domain.SubtitlePath(video.RealPath, subtitle.Lang)), subtitlePath); err != nil {
+			return video_dto.VideoDto{}, fmt.Errorf("%w (video id: %s): %s", domain.ErrCopyingVideo, video.ID, err)
+		}
+	}
+
+	previewPath, err = v.previewService.CopyPreview(video.PreviewPath)
 	if err != nil {
-		return fmt.Errorf("%w (folders id: %s): %s", domain.ErrGettingPaths, foldersID, err)
+		return video_dto.VideoDto{}, fmt.Errorf("%w (video id: %s): %s", domain.ErrCopyingVideo, video.ID, err)
 	}
 
-	if err = v.repo.DeleteVideos(context.Background(), foldersID); err != nil {
-		return fmt.Errorf("%w (from folders: %s): %s", domain.ErrDeletingVideoFromDB, foldersID, err)
+	newVideo := domain.Video{
+		OwnerID:     ownerID,
+		VideoName:   videoName,
+		FolderID:    copyVideoInput.FolderID,
+		RealPath:    realPath,
+		PreviewPath: previewPath,
+		Subtitles:   video.Subtitles,
+		Chapters:    video.Chapters,
 	}
 
-	for _, realPath := range realPaths {
-		if err := os.Remove(filepath.Join(domain.CommonVideoDir, realPath)); err != nil {
-			return fmt.Errorf("%w (video path: %s): %s", domain.ErrDeletingVideo, realPath, err)
+	err = v.replace(ctx, replaced, func() error {
+		owned = true
+		newVideo.ID, err = v.intentsService.CreateVideo(ctx, newVideo)
+		if err != nil {
+			if errors.Is(err, domain.ErrDuplicateKey) {
+				return fmt.Errorf("%w (video name: %s, folder id: %s)", domain.ErrVideoAlreadyExist, videoName, copyVideoInput.FolderID)
+			}
+
+			return fmt.Errorf("%w (video name: %s): %s", domain.ErrSavingVideoToDb, videoName, err)
 		}
-	}
 
-	if err := v.previewService.DeletePreviews(previewPaths); err != nil {
-		return err
+		return nil
+	})
+	if err != nil {
+		return video_dto.VideoDto{}, err
 	}
 
-	return nil
+	return v.toVideoDto([]domain.Video{newVideo})[0], nil
 }
 
-func (v *VideosService) GetVideos(folderID primitive.ObjectID) ([]video_dto.VideoDto, error) {
-	videos, err := v.repo.GetVideos(context.Background(), folderID)
+// ExtractChapters reads the chapters of the file of videoID again, for the
+// videos saved before chapters were extracted on download. The chapters
+// stored are kept when the file has none.
+func (v *VideosService) ExtractChapters(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID) (_ []video_dto.ChapterDto, err error) {
+	ctx, span := tracing.Start(ctx, "VideosService.ExtractChapters")
+	defer tracing.End(span, &err)
+
+	video, err := v.getVideo(ctx, userID, videoID, domain.FolderContributor)
 	if err != nil {
-		return nil, fmt.Errorf("%w (folder id: %s): %s", domain.ErrGettingVideos, folderID, err)
+		return nil, err
 	}
 
-	return v.toVideoDto(videos), nil
+	chapters, err := v.chaptersService.Probe(ctx, video.RealPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(chapters) == 0 {
+		return nil, fmt.Errorf("%w (video id: %s)", domain.ErrNoChapters, video.ID.Hex())
+	}
+
+	if err := v.repo.SetChapters(ctx, video.OwnerID, video.ID, chapters); err != nil {
+		return nil, fmt.Errorf("%w (video id: %s): %s", domain.ErrSavingChapters, video.ID.Hex(), err)
+	}
+
+	return v.toChapterDto(chapters), nil
 }
 
-func (v *VideosService) parseRangeHeader(rangeHeader string, fileSize int64) (int64, int64, error) {
-	parts := strings.Split(rangeHeader, "=")
-	if len(parts) != 2 || parts[0] != "bytes" {
-		return 0, 0, domain.ErrInvalidRangeFormat
+// SplitChapters queues cutting videoID into one video per chapter and
+// returns the job tracking it. The videos are saved in a new folder next to
+// the original, numbered in the order of the chapters, and the job links the
+// first one.
+func (v *VideosService) SplitChapters(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, splitVideoInput video_dto.SplitVideoDto) (_ job_dto.JobDto, err error) {
+	ctx, span := tracing.Start(ctx, "VideosService.SplitChapters")
+	defer tracing.End(span, &err)
+
+	video, err := v.getVideo(ctx, userID, videoID, domain.FolderContributor)
+	if err != nil {
+		return job_dto.JobDto{}, err
+	}
+
+	if len(video.Chapters) == 0 {
+		return job_dto.JobDto{}, fmt.Errorf("%w (video id: %s)", domain.ErrNoChapters, video.ID.Hex())
 	}
 
-	byteRanges := strings.Split(parts[1], "-")
-	if len(byteRanges) != 2 {
-		return 0, 0, domain.ErrInvalidBytesFormat
+	if err := v.checkFolderExistenceByName(ctx, video.OwnerID, splitVideoInput.FolderName, video.FolderID); err != nil {
+		return job_dto.JobDto{}, err
 	}
 
-	start, err := strconv.ParseInt(byteRanges[0], 10, 64)
+	return v.transcodeJobs.Submit(ctx, userID, domain.JobSplit, video.ID.Hex(), func(ctx context.Context) (primitive.ObjectID, error) {
+		return v.split(ctx, video, splitVideoInput.FolderName)
+	})
+}
+
+func (v *VideosService) split(ctx context.Context, video domain.Video, folderName string) (_ primitive.ObjectID, err error) {
+	ctx, span := tracing.Start(ctx, "VideosService.split")
+	defer tracing.End(span, &err)
+
+	ctx = logger.WithFields(ctx, log.Fields{
+		logger.VideoIDField:  video.ID.Hex(),
+		logger.FolderIDField: video.FolderID.Hex(),
+	})
+
+	// the name was free when the job was queued but may not be anymore
+	if err := v.checkFolderExistenceByName(ctx, video.OwnerID, folderName, video.FolderID); err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	folderID, err := v.foldersRepo.Create(ctx, video.OwnerID, folderName, video.FolderID)
 	if err != nil {
-		return 0, 0, domain.ErrInvalidRangeStart
+		return primitive.NilObjectID, fmt.Errorf("%w (folder name: %s, parent dir id: %s): %s", domain.ErrCreatingFolder, folderName, video.FolderID, err)
 	}
 
-	var end int64
-	if byteRanges[1] == "" {
-		end = min(fileSize-1, start+int64(float64(fileSize-1)*domain.DefaultRangePercentage))
-	} else {
-		end, err = strconv.ParseInt(byteRanges[1], 10, 64)
-		if err != nil || end >= fileSize {
-			return 0, 0, domain.ErrInvalidRangeEnd
+	reporter := domain.JobReporterFromContext(ctx)
+
+	var firstVideoID primitive.ObjectID
+	for i, chapter := range video.Chapters {
+		realPath, err := v.chaptersService.Cut(ctx, video.RealPath, chapter, i+1)
+		if err != nil {
+			return firstVideoID, err
+		}
+
+		videoName := fmt.Sprintf("%02d %s", i+1, common.ReplaceSpecialSymbols(chapter.Title))
+		videoID, err := v.save(ctx, video.OwnerID, videoName, folderID, realPath)
+		if err != nil {
+			return firstVideoID, err
 		}
+
+		if i == 0 {
+			firstVideoID = videoID
+		}
+
+		logger.FromContext(ctx).WithField(logger.VideoIDField, videoID.Hex()).Info(splitVideoSaved)
+		reporter.SetProgress(float64(i+1) / float64(len(video.Chapters)))
+	}
+
+	return firstVideoID, nil
+}
+
+func (v *VideosService) checkFolderExistenceByName(ctx context.Context, ownerID primitive.ObjectID, folderName string, parentDirID primitive.ObjectID) error {
+	err := v.foldersRepo.CheckExistenceByName(ctx, ownerID, folderName, parentDirID)
+	if err != nil && !errors.Is(err, domain.ErrNoDocuments) {
+		return fmt.Errorf("%w (folder name: %s, parent dir id: %s): %s", domain.ErrCheckingFolder, folderName, parentDirID, err)
+	}
+
+	if err == nil {
+		return fmt.Errorf("%w (folder name: %s, parent dir id: %s)", domain.ErrFolderAlreadyExist, folderName, parentDirID)
+	}
+
+	return nil
+}
+
+func (v *VideosService) Delete(ctx context.Context, userID primitive.ObjectID, deleteVideoInput video_dto.DeleteVideoDto) (err error) {
+	ctx, span := tracing.Start(ctx, "VideosService.Delete")
+	defer tracing.End(span, &err)
+
+	video, err := v.getVideo(ctx, userID, deleteVideoInput.ID, domain.FolderContributor)
+	if err != nil {
+		return err
 	}
 
-	if start > end || start < 0 || end >= fileSize {
-		return 0, 0, domain.ErrInvalidRange
+	return v.deleteVideo(ctx, video)
+}
+
+// DeleteFolders deletes the folders together with every video inside them.
+func (v *VideosService) DeleteFolders(ctx context.Context, ownerID primitive.ObjectID, foldersID []primitive.ObjectID) (err error) {
+	ctx, span := tracing.Start(ctx, "VideosService.DeleteFolders")
+	defer tracing.End(span, &err)
+
+	realPaths, previewPaths, err := v.repo.GetPathsByFolders(ctx, ownerID, foldersID)
+	if err != nil {
+		return fmt.Errorf("%w (folders id: %s): %s", domain.ErrGettingPaths, foldersID, err)
+	}
+
+	return v.intentsService.Delete(ctx, nil, foldersID, realPaths, previewPaths)
+}
+
+func (v *VideosService) GetVideos(ctx context.Context, ownerID primitive.ObjectID, folderID primitive.ObjectID) (_ []video_dto.VideoDto, err error) {
+	ctx, span := tracing.Start(ctx, "VideosService.GetVideos")
+	defer tracing.End(span, &err)
+
+	videos, err := v.repo.GetVideos(ctx, ownerID, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("%w (folder id: %s): %s", domain.ErrGettingVideos, folderID, err)
 	}
 
-	return start, end, nil
+	return v.toVideoDto(videos), nil
 }
 
 func (v *VideosService) toVideoDto(videos []domain.Video) []video_dto.VideoDto {
@@ -250,19 +763,163 @@ func (v *VideosService) toVideoDto(videos []domain.Video) []video_dto.VideoDto {
 			RealPath:    video.RealPath,
 			PreviewPath: video.PreviewPath,
 		}
+
+		for _, subtitle := range video.Subtitles {
+			res[i].Subtitles = append(res[i].Subtitles, video_dto.SubtitleDto{
+				Lang:  subtitle.Lang,
+				Label: subtitle.Label,
+				Auto:  subtitle.Auto,
+			})
+		}
+
+		res[i].Chapters = v.toChapterDto(video.Chapters)
+	}
+
+	return res
+}
+
+func (v *VideosService) toChapterDto(chapters []domain.Chapter) []video_dto.ChapterDto {
+	var res []video_dto.ChapterDto
+
+	for _, chapter := range chapters {
+		res = append(res, video_dto.ChapterDto{
+			Title: chapter.Title,
+			Start: chapter.Start.Seconds(),
+			End:   chapter.End.Seconds(),
+		})
 	}
 
 	return res
 }
 
-func (v *VideosService) checkVideoExistenceByID(videoID primitive.ObjectID) error {
-	err := v.repo.CheckExistByID(context.Background(), videoID)
+// LibraryStats returns the number of videos and the total size of their
+// files. Missing files are not counted.
+func (v *VideosService) LibraryStats(ctx context.Context) (_ int, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "VideosService.LibraryStats")
+	defer tracing.End(span, &err)
+
+	videos, err := v.repo.GetAll(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %s", domain.ErrGettingAllVideos, err)
+	}
+
+	var size int64
+	for _, video := range videos {
+		if info, err := os.Stat(filepath.Join(v.videoDir, video.RealPath)); err == nil {
+			size += info.Size()
+		}
+	}
+
+	return len(videos), size, nil
+}
+
+// Authorize returns the video if userID has at least role in its folder.
+func (v *VideosService) Authorize(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, role string) (_ domain.Video, err error) {
+	ctx, span := tracing.Start(ctx, "VideosService.Authorize")
+	defer tracing.End(span, &err)
+
+	return v.getVideo(ctx, userID, videoID, role)
+}
+
+// getVideo returns the video if userID has at least role in its folder. A
+// video the user can't see at all fails with ErrVideoNotFound.
+func (v *VideosService) getVideo(ctx context.Context, userID primitive.ObjectID, videoID primitive.ObjectID, role string) (domain.Video, error) {
+	video, err := v.repo.Lookup(ctx, videoID)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return fmt.Errorf("%w (video id: %s): %s", domain.ErrVideoNotFound, videoID, err)
+		if errors.Is(err, domain.ErrNoDocuments) {
+			return domain.Video{}, fmt.Errorf("%w (video id: %s): %s", domain.ErrVideoNotFound, videoID, err)
 		}
 
-		return fmt.Errorf("%w (video id: %s): %s", domain.ErrCheckingVideo, videoID, err)
+		return domain.Video{}, fmt.Errorf("%w (video id: %s): %s", domain.ErrCheckingVideo, videoID, err)
+	}
+
+	ownerID, err := v.sharingService.Require(ctx, userID, video.FolderID, role)
+	if err != nil {
+		if errors.Is(err, domain.ErrFolderNotFound) {
+			return domain.Video{}, fmt.Errorf("%w (video id: %s): %s", domain.ErrVideoNotFound, videoID, err)
+		}
+
+		return domain.Video{}, err
+	}
+
+	// videos in the root are only seen by their owner
+	if ownerID != video.OwnerID {
+		return domain.Video{}, fmt.Errorf("%w (video id: %s)", domain.ErrVideoNotFound, videoID)
+	}
+
+	return video, nil
+}
+
+func (v *VideosService) deleteVideo(ctx context.Context, video domain.Video) error {
+	return v.intentsService.Delete(ctx, []primitive.ObjectID{video.ID}, nil, []string{video.RealPath}, []string{video.PreviewPath})
+}
+
+// resolveVideoName applies the configured conflict policy to videoName inside folderID.
+// selfID is the video being renamed or moved, so that it never conflicts with itself.
+// With the overwrite policy it also returns the video holding the name, which the
+// caller replaces with replace. The returned video is zero otherwise.
+func (v *VideosService) resolveVideoName(ctx context.Context, ownerID primitive.ObjectID, videoName string, folderID primitive.ObjectID, selfID primitive.ObjectID) (string, domain.Video, error) {
+	existing, err := v.repo.GetByName(ctx, ownerID, videoName, folderID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoDocuments) {
+			return videoName, domain.Video{}, nil
+		}
+
+		return "", domain.Video{}, fmt.Errorf("%w (video name: %s, folder id: %s): %s", domain.ErrCheckingVideo, videoName, folderID, err)
+	}
+
+	if existing.ID == selfID {
+		return videoName, domain.Video{}, nil
+	}
+
+	switch v.conflictPolicy {
+	case domain.ConflictPolicySuffix:
+		for i := 2; i <= v.maxNameSuffix; i++ {
+			candidate := domain.SuffixedVideoName(videoName, i)
+
+			_, err := v.repo.GetByName(ctx, ownerID, candidate, folderID)
+			if errors.Is(err, domain.ErrNoDocuments) {
+				return candidate, domain.Video{}, nil
+			}
+
+			if err != nil {
+				return "", domain.Video{}, fmt.Errorf("%w (video name: %s, folder id: %s): %s", domain.ErrCheckingVideo, candidate, folderID, err)
+			}
+		}
+
+		return "", domain.Video{}, fmt.Errorf("%w (video name: %s, folder id: %s): too many copies", domain.ErrResolvingVideoName, videoName, folderID)
+	case domain.ConflictPolicyOverwrite:
+		return videoName, existing, nil
+	default:
+		return "", domain.Video{}, fmt.Errorf("%w (video name: %s, folder id: %s)", domain.ErrVideoAlreadyExist, videoName, folderID)
+	}
+}
+
+// replace runs save, which stores a video under the name of replaced, and
+// deletes replaced once save succeeded. Until then replaced is kept under a
+// temporary name, as the name is unique, and it gets its name back when save
+// fails. A zero replaced just runs save.
+func (v *VideosService) replace(ctx context.Context, replaced domain.Video, save func() error) error {
+	if replaced.ID.IsZero() {
+		return save()
+	}
+
+	tmpName := fmt.Sprintf(replacedNameFormat, replaced.VideoName, replaced.ID.Hex())
+	if err := v.repo.Rename(ctx, replaced.OwnerID, replaced.ID, tmpName); err != nil {
+		return fmt.Errorf("%w (video name: %s, folder id: %s): %s", domain.ErrResolvingVideoName, replaced.VideoName, replaced.FolderID, err)
+	}
+
+	if err := save(); err != nil {
+		if err := v.repo.Rename(ctx, replaced.OwnerID, replaced.ID, replaced.VideoName); err != nil {
+			logger.FromContext(ctx).WithError(err).WithField(logger.VideoIDField, replaced.ID.Hex()).Error(restoringReplaced)
+		}
+
+		return err
+	}
+
+	// the new video is saved, so the request succeeded even if this fails
+	if err := v.deleteVideo(ctx, replaced); err != nil {
+		logger.FromContext(ctx).WithError(err).WithField(logger.VideoIDField, replaced.ID.Hex()).Error(deletingReplaced)
 	}
 
 	return nil
@@ -0,0 +1,226 @@
+package videos_service
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"os"
+	"path/filepath"
+	"testing"
+	"video-downloader-server/internal/delivery/dto/video_dto"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/repository"
+)
+
+// fakeIntents deletes videos from the repo right away.
+type fakeIntents struct {
+	repo *repository.VideosMemoryRepo
+}
+
+func (f fakeIntents) CreateVideo(ctx context.Context, video domain.Video) (primitive.ObjectID, error) {
+	return f.repo.Create(ctx, video)
+}
+
+func (f fakeIntents) Delete(ctx context.Context, videoIDs []primitive.ObjectID, foldersID []primitive.ObjectID, videoPaths []string, previewPaths []string) error {
+	for _, videoID := range videoIDs {
+		if err := f.repo.Delete(ctx, videoID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fakePreview creates previewPath for every video and records the removed
+// previews.
+type fakePreview struct {
+	previewPath string
+	removed     []string
+}
+
+func (f *fakePreview) CreatePreview(ctx context.Context, videoName string, realPath string) (string, error) {
+	return f.previewPath, nil
+}
+
+func (f *fakePreview) CopyPreview(previewPath string) (string, error) {
+	return f.previewPath, nil
+}
+
+func (f *fakePreview) RemovePreview(previewPath string) {
+	f.removed = append(f.removed, previewPath)
+}
+
+func (f *fakePreview) OpenPreview(previewPath string) (video_dto.VideoFileInfoDto, error) {
+	return video_dto.VideoFileInfoDto{}, errors.New("no preview")
+}
+
+// failingRename fails to rename any video.
+type failingRename struct {
+	*repository.VideosMemoryRepo
+}
+
+func (failingRename) Rename(ctx context.Context, ownerID primitive.ObjectID, videoID primitive.ObjectID, videoName string) error {
+	return errors.New("rename failed")
+}
+
+// newService returns a service with videos named names in folderID of
+// ownerID, by name.
+func newService(t *testing.T, conflictPolicy string, ownerID, folderID primitive.ObjectID, names ...string) (*VideosService, map[string]domain.Video) {
+	t.Helper()
+
+	repo := repository.NewVideosMemoryRepo()
+	videos := make(map[string]domain.Video, len(names))
+	for _, name := range names {
+		video := domain.Video{OwnerID: ownerID, VideoName: name, FolderID: folderID, RealPath: name + ".mp4"}
+
+		var err error
+		if video.ID, err = repo.Create(context.Background(), video); err != nil {
+			t.Fatal(err)
+		}
+		videos[name] = video
+	}
+
+	return &VideosService{
+		repo:           repo,
+		intentsService: fakeIntents{repo},
+		conflictPolicy: conflictPolicy,
+		maxNameSuffix:  3,
+	}, videos
+}
+
+func TestResolveVideoName(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       string
+		existing     []string
+		videoName    string
+		self         string
+		want         string
+		wantReplaced string
+		wantErr      error
+	}{
+		{"free name", domain.ConflictPolicyError, []string{"b"}, "a", "", "a", "", nil},
+		{"error on conflict", domain.ConflictPolicyError, []string{"a"}, "a", "", "", "", domain.ErrVideoAlreadyExist},
+		{"no conflict with itself", domain.ConflictPolicyError, []string{"a"}, "a", "a", "a", "", nil},
+		{"first suffix", domain.ConflictPolicySuffix, []string{"a"}, "a", "", "a (2)", "", nil},
+		{"next free suffix", domain.ConflictPolicySuffix, []string{"a", "a (2)"}, "a", "", "a (3)", "", nil},
+		{"suffixes used up", domain.ConflictPolicySuffix, []string{"a", "a (2)", "a (3)"}, "a", "", "", "", domain.ErrResolvingVideoName},
+		{"suffix free name", domain.ConflictPolicySuffix, nil, "a", "", "a", "", nil},
+		{"overwrite", domain.ConflictPolicyOverwrite, []string{"a"}, "a", "", "a", "a", nil},
+		{"overwrite free name", domain.ConflictPolicyOverwrite, nil, "a", "", "a", "", nil},
+		{"overwrite not itself", domain.ConflictPolicyOverwrite, []string{"a"}, "a", "a", "a", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ownerID, folderID := primitive.NewObjectID(), primitive.NewObjectID()
+			v, videos := newService(t, tt.policy, ownerID, folderID, tt.existing...)
+
+			got, replaced, err := v.resolveVideoName(context.Background(), ownerID, tt.videoName, folderID, videos[tt.self].ID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("got name %q, want %q", got, tt.want)
+			}
+
+			if replaced.ID != videos[tt.wantReplaced].ID {
+				t.Errorf("got replaced video %s, want %s", replaced.ID, videos[tt.wantReplaced].ID)
+			}
+
+			// resolving a name never changes the videos
+			for name, video := range videos {
+				if _, err := v.repo.GetByName(context.Background(), ownerID, name, folderID); err != nil {
+					t.Errorf("video %q (%s) is gone: %s", name, video.ID, err)
+				}
+			}
+		})
+	}
+}
+
+func TestReplace(t *testing.T) {
+	errSaving := errors.New("saving failed")
+
+	tests := []struct {
+		name        string
+		saveErr     error
+		wantDeleted bool
+	}{
+		{"deleted after the new video is saved", nil, true},
+		{"kept when the new video can't be saved", errSaving, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			ownerID, folderID := primitive.NewObjectID(), primitive.NewObjectID()
+			v, videos := newService(t, domain.ConflictPolicyOverwrite, ownerID, folderID, "a")
+
+			_, replaced, err := v.resolveVideoName(ctx, ownerID, "a", folderID, primitive.NilObjectID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = v.replace(ctx, replaced, func() error {
+				if _, err := v.repo.Lookup(ctx, videos["a"].ID); err != nil {
+					t.Errorf("replaced video deleted before saving: %s", err)
+				}
+
+				if _, err := v.repo.GetByName(ctx, ownerID, "a", folderID); !errors.Is(err, domain.ErrNoDocuments) {
+					t.Errorf("name not freed for the new video: %v", err)
+				}
+
+				if tt.saveErr != nil {
+					return tt.saveErr
+				}
+
+				_, err := v.intentsService.CreateVideo(ctx, domain.Video{OwnerID: ownerID, VideoName: "a", FolderID: folderID})
+				return err
+			})
+			if !errors.Is(err, tt.saveErr) {
+				t.Fatalf("got error %v, want %v", err, tt.saveErr)
+			}
+
+			old, err := v.repo.Lookup(ctx, videos["a"].ID)
+			if deleted := errors.Is(err, domain.ErrNoDocuments); deleted != tt.wantDeleted {
+				t.Fatalf("replaced video deleted: got %t, want %t", deleted, tt.wantDeleted)
+			}
+
+			if !tt.wantDeleted && old.VideoName != "a" {
+				t.Errorf("replaced video kept name %q, want %q", old.VideoName, "a")
+			}
+
+			if _, err := v.repo.GetByName(ctx, ownerID, "a", folderID); err != nil {
+				t.Errorf("no video named %q: %s", "a", err)
+			}
+		})
+	}
+}
+
+func TestSaveRemovesFilesWhenReplacingFails(t *testing.T) {
+	ctx := context.Background()
+	ownerID, folderID := primitive.NewObjectID(), primitive.NewObjectID()
+	v, _ := newService(t, domain.ConflictPolicyOverwrite, ownerID, folderID, "a")
+
+	preview := &fakePreview{previewPath: "ab/a.jpeg"}
+	v.repo = failingRename{v.repo.(*repository.VideosMemoryRepo)}
+	v.previewService = preview
+	v.videoDir = t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(v.videoDir, "new.mp4"), []byte("video"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := v.save(ctx, ownerID, "a", folderID, "new.mp4"); !errors.Is(err, domain.ErrResolvingVideoName) {
+		t.Fatalf("got error %v, want %v", err, domain.ErrResolvingVideoName)
+	}
+
+	if _, err := os.Stat(filepath.Join(v.videoDir, "new.mp4")); !os.IsNotExist(err) {
+		t.Errorf("video file kept: %v", err)
+	}
+
+	if len(preview.removed) != 1 || preview.removed[0] != preview.previewPath {
+		t.Errorf("removed previews %v, want %q", preview.removed, preview.previewPath)
+	}
+}
@@ -0,0 +1,98 @@
+// Package tracing sets up the OpenTelemetry tracer provider and wraps span
+// creation so that every stage of a request or job shows up in one trace.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"os"
+	"video-downloader-server/internal/domain"
+	"video-downloader-server/internal/version"
+)
+
+const (
+	NoneExporter   = "none"
+	StdoutExporter = "stdout"
+	OTLPExporter   = "otlp"
+
+	serviceName = "video-downloader-server"
+)
+
+var tracer = otel.Tracer(serviceName)
+
+// Setup installs the global tracer provider and W3C trace context propagator.
+// With the none exporter spans are not recorded at all. The returned function
+// flushes the pending spans and must be called on shutdown.
+func Setup(ctx context.Context, exporter, endpoint string, insecure bool, sampleRatio float64) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+
+	switch exporter {
+	case NoneExporter:
+		return func(context.Context) error { return nil }, nil
+	case StdoutExporter:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case OTLPExporter:
+		opts := []otlptracehttp.Option{}
+		if endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		if insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		spanExporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		err = fmt.Errorf("unknown exporter: %s", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w (exporter: %s): %s", domain.ErrCreatingSpanExporter, exporter, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName(serviceName),
+			semconv.ServiceVersion(version.Version),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span named name as a child of the span in ctx.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, opts...)
+}
+
+// End records *err on span, if any, and ends it. It is meant to be deferred
+// with a pointer to the named error result of the traced function.
+func End(span trace.Span, err *error) {
+	if err != nil && *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+
+	span.End()
+}
+
+// StartProcess starts a span around a run of an external executable.
+func StartProcess(ctx context.Context, operation, path string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "exec "+operation, trace.WithAttributes(
+		attribute.String("process.operation", operation),
+		attribute.String("process.executable.path", path),
+	))
+}
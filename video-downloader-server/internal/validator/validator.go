@@ -3,6 +3,7 @@ package validator
 import (
 	"github.com/go-playground/validator/v10"
 	"regexp"
+	"video-downloader-server/internal/domain"
 )
 
 func Init() *validator.Validate {
@@ -11,6 +12,7 @@ func Init() *validator.Validate {
 	validate.RegisterValidation("foldername", folderNameValidation)
 	validate.RegisterValidation("videoname", videoNameValidation)
 	validate.RegisterValidation("objectid", objectIDValidation)
+	validate.RegisterValidation("subtitlelang", subtitleLangValidation)
 
 	return validate
 }
@@ -37,3 +39,7 @@ func videoNameValidation(fl validator.FieldLevel) bool {
 func objectIDValidation(fl validator.FieldLevel) bool {
 	return !fl.Field().IsZero()
 }
+
+func subtitleLangValidation(fl validator.FieldLevel) bool {
+	return domain.ValidSubtitleLang(fl.Field().String())
+}
@@ -0,0 +1,32 @@
+// Package version holds build information set at link time:
+//
+//	go build -ldflags "-X video-downloader-server/internal/version.Version=v1.2.3 \
+//		-X video-downloader-server/internal/version.Commit=$(git rev-parse HEAD) \
+//		-X video-downloader-server/internal/version.BuildTime=$(date -u +%FT%TZ)" ./cmd
+package version
+
+import "runtime/debug"
+
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// GetCommit returns Commit or, when it was not set at link time, the VCS
+// revision stamped by the go tool.
+func GetCommit() string {
+	if Commit != "" {
+		return Commit
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+
+	return ""
+}